@@ -3,9 +3,12 @@ module github.com/fgiloux/kcp-operator-sdk
 go 1.19
 
 require (
+	github.com/fgiloux/kcp-operator-sdk/pkg v0.0.0
 	github.com/operator-framework/operator-sdk v1.23.0
 	github.com/sirupsen/logrus v1.9.0
+	github.com/spf13/afero v1.6.0
 	github.com/spf13/cobra v1.5.0
+	github.com/spf13/pflag v1.0.5
 	k8s.io/client-go v0.25.2
 	sigs.k8s.io/kubebuilder/v3 v3.7.0
 )
@@ -46,8 +49,6 @@ require (
 	github.com/monochromegane/go-gitignore v0.0.0-20200626010858-205db1a8cc00 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/spf13/afero v1.6.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/stretchr/testify v1.7.1 // indirect
 	github.com/thoas/go-funk v0.8.0 // indirect
 	github.com/weppos/publicsuffix-go v0.13.0 // indirect
@@ -81,3 +82,5 @@ require (
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.3 // indirect
 	sigs.k8s.io/yaml v1.3.0 // indirect
 )
+
+replace github.com/fgiloux/kcp-operator-sdk/pkg => ./pkg
@@ -0,0 +1,122 @@
+package auditreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/audit"
+)
+
+type auditReportCmd struct {
+	auditLog         string
+	output           string
+	failOnViolations bool
+	opts             audit.Options
+}
+
+// NewCmd returns the alpha audit-report command.
+func NewCmd() *cobra.Command {
+	c := &auditReportCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "audit-report",
+		Short: "Analyze the kcp audit log of an end-to-end test run",
+		Long: `Analyze the audit log written by kcp during "make test-e2e".
+
+The report lists, per workspace and per user agent, the number of requests by verb and resource.
+It also flags the writes the controller made outside of the workspaces it was reconciling
+and the objects the controller updated in a hot loop.
+`,
+		Example: `  # Analyze the audit log of the last end-to-end test run
+  kcp-operator-sdk alpha audit-report --audit-log .test/audit.log --primary-resource widgets.data.example.com
+`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// Violations are not usage errors.
+			cmd.SilenceUsage = true
+			return c.run(cmd.OutOrStdout())
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&c.auditLog, "audit-log", ".test/audit.log", "path to the audit log written by kcp")
+	fs.StringVarP(&c.output, "output", "o", "text", "output format, one of 'text', 'json'")
+	fs.BoolVar(&c.failOnViolations, "fail-on-violations", false,
+		"exit with an error if cross-tenant writes or hot loops are found")
+	fs.StringVar(&c.opts.ControllerUserAgent, "controller-user-agent", audit.DefaultControllerUserAgent,
+		"prefix of the user agent identifying the requests of the controller")
+	fs.StringSliceVar(&c.opts.PrimaryResources, "primary-resource", nil,
+		"resource reconciled by the controller, e.g. widgets.data.example.com; "+
+			"a workspace is considered reconciled when another user agent wrote one of these resources in it")
+	fs.StringSliceVar(&c.opts.Workspaces, "workspace", nil,
+		"additional workspace the controller is allowed to write to")
+	fs.IntVar(&c.opts.HotLoopThreshold, "hot-loop-threshold", audit.DefaultHotLoopThreshold,
+		"number of updates to a single object within --hot-loop-window above which the controller is hot looping")
+	fs.DurationVar(&c.opts.HotLoopWindow, "hot-loop-window", audit.DefaultHotLoopWindow,
+		"window used to count the updates to a single object")
+
+	return cmd
+}
+
+func (c *auditReportCmd) run(out io.Writer) error {
+	events, err := audit.ReadFile(c.auditLog)
+	if err != nil {
+		return err
+	}
+
+	report := audit.Analyze(events, c.opts)
+
+	switch c.output {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("error encoding the report: %w", err)
+		}
+	case "text":
+		if err := printReport(out, report); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", c.output)
+	}
+
+	if c.failOnViolations {
+		if err := report.NoCrossTenantWrites(); err != nil {
+			return err
+		}
+		if err := report.NoHotLoops(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printReport(out io.Writer, report *audit.Report) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tUSER AGENT\tVERB\tRESOURCE\tCOUNT")
+	for _, r := range report.Requests {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", r.Workspace, r.UserAgent, r.Verb, r.Resource, r.Count)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nReconciled workspaces: %v\n", report.ReconciledWorkspaces)
+	if err := report.NoCrossTenantWrites(); err != nil {
+		fmt.Fprintf(out, "\n%v\n", err)
+	} else {
+		fmt.Fprintln(out, "\nNo cross-tenant writes.")
+	}
+	if err := report.NoHotLoops(); err != nil {
+		fmt.Fprintf(out, "\n%v\n", err)
+	} else {
+		fmt.Fprintln(out, "No hot loops.")
+	}
+
+	return nil
+}
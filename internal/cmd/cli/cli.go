@@ -14,6 +14,7 @@ import (
 	"sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang"
 	declarativev1 "sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang/declarative/v1"
 
+	"github.com/fgiloux/kcp-operator-sdk/internal/cmd/alpha/auditreport"
 	"github.com/fgiloux/kcp-operator-sdk/internal/version"
 	// golangv3 "sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang/v3"
 	gov3 "github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3"
@@ -25,7 +26,9 @@ var (
 	// to this project binary may get added
 	// example: myExampleCommand.NewCmd(),
 	commands      = []*cobra.Command{}
-	alphaCommands = []*cobra.Command{}
+	alphaCommands = []*cobra.Command{
+		auditreport.NewCmd(),
+	}
 )
 
 func Run() error {
@@ -33,14 +36,19 @@ func Run() error {
 	return c.Run()
 }
 
-// GetPluginsCLI returns the plugins based CLI configured to be used in the new CLI binary
-func GetPluginsCLI() *cli.CLI {
-	// Bundle plugin which built the golang projects scaffold by the kcp plugin mimicking Kubebuilder go/v3
+// GetGoV3Bundle returns the bundle plugin which builds the golang projects scaffold by the kcp plugin mimicking Kubebuilder go/v3
+func GetGoV3Bundle() plugin.Bundle {
 	gov3Bundle, _ := plugin.NewBundle(golang.DefaultNameQualifier, plugin.Version{Number: 3},
 		kustomizev1.Plugin{},
 		gov3.Plugin{},
 		manifests.Plugin{},
 	)
+	return gov3Bundle
+}
+
+// GetPluginsCLI returns the plugins based CLI configured to be used in the new CLI binary
+func GetPluginsCLI() *cli.CLI {
+	gov3Bundle := GetGoV3Bundle()
 
 	c, err := cli.New(
 
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TestingT is the subset of testing.TB used by the assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// NoCrossTenantWrites returns an error listing the writes the controller made
+// outside of the workspaces it was reconciling.
+func (r *Report) NoCrossTenantWrites() error {
+	if len(r.CrossTenantWrites) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(r.CrossTenantWrites))
+	for i := range r.CrossTenantWrites {
+		e := &r.CrossTenantWrites[i]
+		lines = append(lines, fmt.Sprintf("%s %s (user agent %q)", e.Verb, e.RequestURI, e.UserAgent))
+	}
+	return fmt.Errorf("%d write(s) outside of the reconciled workspaces %v:\n%s",
+		len(lines), r.ReconciledWorkspaces, strings.Join(lines, "\n"))
+}
+
+// NoHotLoops returns an error listing the objects the controller updated too often.
+func (r *Report) NoHotLoops() error {
+	if len(r.HotLoops) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(r.HotLoops))
+	for _, h := range r.HotLoops {
+		lines = append(lines, fmt.Sprintf("%s updated %d times between %s and %s",
+			h.Object, h.Updates, h.Start.Format("15:04:05.000"), h.End.Format("15:04:05.000")))
+	}
+	return fmt.Errorf("%d object(s) updated in a hot loop:\n%s", len(lines), strings.Join(lines, "\n"))
+}
+
+// AssertNoCrossTenantWrites fails the test if the controller wrote outside of the workspaces it was reconciling.
+func AssertNoCrossTenantWrites(t TestingT, r *Report) {
+	t.Helper()
+	if err := r.NoCrossTenantWrites(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+// AssertNoHotLoops fails the test if the controller updated an object too often.
+func AssertNoHotLoops(t TestingT, r *Report) {
+	t.Helper()
+	if err := r.NoHotLoops(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,415 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// auditLog is a small audit log: a user creates a widget in root:org:ws1, the controller watches the widgets through
+// the virtual workspace, updates the status of the widget, whose two stages are logged, and creates a config map in
+// root:org:ws2, where no widget was created.
+const auditLog = `{"auditID":"1","stage":"ResponseComplete","requestURI":"/clusters/root:org:ws1/apis/data.example.com/v1alpha1/namespaces/default/widgets","verb":"create","user":{"username":"user"},"userAgent":"kubectl/v1.24.0","objectRef":{"resource":"widgets","namespace":"default","name":"w1","apiGroup":"data.example.com","apiVersion":"v1alpha1"},"responseStatus":{"code":201},"requestReceivedTimestamp":"2022-10-01T10:00:00Z","stageTimestamp":"2022-10-01T10:00:00Z"}
+{"auditID":"2","stage":"ResponseComplete","requestURI":"/services/apiexport/root:org/widgets/clusters/*/apis/data.example.com/v1alpha1/widgets?watch=true","verb":"watch","user":{"username":"controller"},"userAgent":"manager/v0.0.0","objectRef":{"resource":"widgets","apiGroup":"data.example.com","apiVersion":"v1alpha1"},"requestReceivedTimestamp":"2022-10-01T10:00:01Z","stageTimestamp":"2022-10-01T10:00:01Z"}
+{"auditID":"3","stage":"RequestReceived","requestURI":"/services/apiexport/root:org/widgets/clusters/root:org:ws1/apis/data.example.com/v1alpha1/namespaces/default/widgets/w1/status","verb":"update","user":{"username":"controller"},"userAgent":"manager/v0.0.0","objectRef":{"resource":"widgets","namespace":"default","name":"w1","apiGroup":"data.example.com","apiVersion":"v1alpha1","subresource":"status"},"requestReceivedTimestamp":"2022-10-01T10:00:02Z","stageTimestamp":"2022-10-01T10:00:02Z"}
+{"auditID":"3","stage":"ResponseComplete","requestURI":"/services/apiexport/root:org/widgets/clusters/root:org:ws1/apis/data.example.com/v1alpha1/namespaces/default/widgets/w1/status","verb":"update","user":{"username":"controller"},"userAgent":"manager/v0.0.0","objectRef":{"resource":"widgets","namespace":"default","name":"w1","apiGroup":"data.example.com","apiVersion":"v1alpha1","subresource":"status"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2022-10-01T10:00:02Z","stageTimestamp":"2022-10-01T10:00:02Z"}
+{"auditID":"4","stage":"ResponseComplete","requestURI":"/services/apiexport/root:org/widgets/clusters/root:org:ws2/api/v1/namespaces/default/configmaps","verb":"create","user":{"username":"controller"},"userAgent":"manager/v0.0.0","objectRef":{"resource":"configmaps","namespace":"default","name":"w1","apiVersion":"v1"},"responseStatus":{"code":201},"requestReceivedTimestamp":"2022-10-01T10:00:03Z","stageTimestamp":"2022-10-01T10:00:03Z"}
+`
+
+var start = time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC)
+
+// newEvent returns an event of the user agent against the widget w1 of the workspace, received after the offset.
+func newEvent(userAgent, verb, workspace string, offset time.Duration) Event {
+	return Event{
+		RequestURI: fmt.Sprintf("/clusters/%s/apis/data.example.com/v1alpha1/namespaces/default/widgets/w1", workspace),
+		Verb:       verb,
+		UserAgent:  userAgent,
+		ObjectRef: &ObjectReference{
+			Resource:  "widgets",
+			Namespace: "default",
+			Name:      "w1",
+			APIGroup:  "data.example.com",
+		},
+		RequestReceivedTimestamp: start.Add(offset),
+	}
+}
+
+// updates returns n updates of the controller to the widget w1 of the workspace, one every interval.
+func updates(workspace string, n int, interval time.Duration) []Event {
+	events := make([]Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, newEvent("manager/v0.0.0", "update", workspace, time.Duration(i)*interval))
+	}
+	return events
+}
+
+func TestWorkspace(t *testing.T) {
+	tests := []struct {
+		requestURI string
+		want       string
+	}{
+		{requestURI: "/clusters/root:org:ws/api/v1/configmaps", want: "root:org:ws"},
+		{requestURI: "/services/apiexport/root:org/widgets/clusters/*/apis/data.example.com/v1alpha1/widgets", want: WildcardWorkspace},
+		{requestURI: "/clusters/root:org:ws?watch=true", want: "root:org:ws"},
+		{requestURI: "/api/v1/configmaps", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.requestURI, func(t *testing.T) {
+			e := &Event{RequestURI: tt.requestURI}
+			if got := e.Workspace(); got != tt.want {
+				t.Errorf("Workspace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		wantIDs []string
+		wantErr string
+	}{
+		{
+			name:    "audit log",
+			log:     auditLog,
+			wantIDs: []string{"1", "2", "3", "4"},
+		},
+		{
+			name: "empty",
+			log:  "",
+		},
+		{
+			name:    "malformed line",
+			log:     `{"auditID":"1","verb":"get"}` + "\n" + "not json\n",
+			wantErr: "error decoding audit event 2",
+		},
+		{
+			name:    "truncated log",
+			log:     `{"auditID":"1","verb":"get"}` + "\n" + `{"auditID":"2","verb":"get"`,
+			wantErr: "error decoding audit event 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := Read(strings.NewReader(tt.log))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Read() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			var ids []string
+			for _, e := range events {
+				ids = append(ids, e.AuditID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("Read() audit IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte(auditLog), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	// The first stage of the status update is kept.
+	if len(events) != 4 || events[2].Stage != "RequestReceived" {
+		t.Errorf("ReadFile() = %+v, want 4 events with the RequestReceived stage of the update", events)
+	}
+
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "missing.log")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFile() of a missing file error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	events, err := Read(strings.NewReader(auditLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Analyze(events, Options{})
+
+	wantRequests := []RequestCount{
+		{Workspace: WildcardWorkspace, UserAgent: "manager/v0.0.0", Verb: "watch", Resource: "widgets.data.example.com", Count: 1},
+		{Workspace: "root:org:ws1", UserAgent: "kubectl/v1.24.0", Verb: "create", Resource: "widgets.data.example.com", Count: 1},
+		{Workspace: "root:org:ws1", UserAgent: "manager/v0.0.0", Verb: "update", Resource: "widgets.data.example.com/status", Count: 1},
+		{Workspace: "root:org:ws2", UserAgent: "manager/v0.0.0", Verb: "create", Resource: "configmaps", Count: 1},
+	}
+	if !reflect.DeepEqual(report.Requests, wantRequests) {
+		t.Errorf("Requests = %+v, want %+v", report.Requests, wantRequests)
+	}
+	if want := []string{"root:org:ws1"}; !reflect.DeepEqual(report.ReconciledWorkspaces, want) {
+		t.Errorf("ReconciledWorkspaces = %v, want %v", report.ReconciledWorkspaces, want)
+	}
+	if len(report.CrossTenantWrites) != 1 || report.CrossTenantWrites[0].AuditID != "4" {
+		t.Errorf("CrossTenantWrites = %+v, want the creation of the config map in root:org:ws2", report.CrossTenantWrites)
+	}
+	if len(report.HotLoops) != 0 {
+		t.Errorf("HotLoops = %+v, want none", report.HotLoops)
+	}
+}
+
+func TestAnalyzeCrossTenantWrites(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		opts   Options
+		want   []string
+	}{
+		{
+			name: "write in a reconciled workspace",
+			events: []Event{
+				newEvent("kubectl/v1.24.0", "create", "root:org:ws1", 0),
+				newEvent("manager/v0.0.0", "update", "root:org:ws1", time.Second),
+			},
+		},
+		{
+			name: "write in another workspace",
+			events: []Event{
+				newEvent("kubectl/v1.24.0", "create", "root:org:ws1", 0),
+				newEvent("manager/v0.0.0", "update", "root:org:ws2", time.Second),
+			},
+			want: []string{"root:org:ws2"},
+		},
+		{
+			name: "read in another workspace",
+			events: []Event{
+				newEvent("kubectl/v1.24.0", "create", "root:org:ws1", 0),
+				newEvent("manager/v0.0.0", "get", "root:org:ws2", time.Second),
+			},
+		},
+		{
+			name: "write in an allowed workspace",
+			events: []Event{
+				newEvent("kubectl/v1.24.0", "create", "root:org:ws1", 0),
+				newEvent("manager/v0.0.0", "update", "root:org:leases", time.Second),
+			},
+			opts: Options{Workspaces: []string{"root:org:leases"}},
+		},
+		{
+			name: "workspace with writes of other resources only",
+			events: []Event{
+				newEvent("kubectl/v1.24.0", "create", "root:org:ws1", 0),
+				newEvent("manager/v0.0.0", "update", "root:org:ws1", time.Second),
+			},
+			opts: Options{PrimaryResources: []string{"gadgets.data.example.com"}},
+			want: []string{"root:org:ws1"},
+		},
+		{
+			name: "custom user agent",
+			events: []Event{
+				newEvent("kubectl/v1.24.0", "create", "root:org:ws1", 0),
+				newEvent("widgets-controller/v0.0.0", "update", "root:org:ws2", time.Second),
+				newEvent("manager/v0.0.0", "update", "root:org:ws3", time.Second),
+			},
+			opts: Options{ControllerUserAgent: "widgets-controller/"},
+			want: []string{"root:org:ws2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Analyze(tt.events, tt.opts)
+			var got []string
+			for i := range report.CrossTenantWrites {
+				got = append(got, report.CrossTenantWrites[i].Workspace())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CrossTenantWrites in %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeHotLoops(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		opts   Options
+		want   []HotLoop
+	}{
+		{
+			name:   "updates below the threshold",
+			events: updates("root:org:ws1", DefaultHotLoopThreshold, time.Second/2),
+		},
+		{
+			name:   "updates spread over several windows",
+			events: updates("root:org:ws1", 3*DefaultHotLoopThreshold, 2*time.Second),
+		},
+		{
+			name:   "hot loop",
+			events: updates("root:org:ws1", DefaultHotLoopThreshold+1, time.Second/2),
+			want: []HotLoop{{
+				Object:  ObjectKey{Workspace: "root:org:ws1", Resource: "widgets.data.example.com", Namespace: "default", Name: "w1"},
+				Updates: DefaultHotLoopThreshold + 1,
+				Start:   start,
+				End:     start.Add(time.Duration(DefaultHotLoopThreshold) * time.Second / 2),
+			}},
+		},
+		{
+			name:   "custom threshold and window",
+			events: updates("root:org:ws1", 4, 2*time.Second),
+			opts:   Options{HotLoopThreshold: 2, HotLoopWindow: 4 * time.Second},
+			want: []HotLoop{{
+				Object:  ObjectKey{Workspace: "root:org:ws1", Resource: "widgets.data.example.com", Namespace: "default", Name: "w1"},
+				Updates: 3,
+				Start:   start,
+				End:     start.Add(4 * time.Second),
+			}},
+		},
+		{
+			name:   "updates of another user agent",
+			events: append(updates("root:org:ws1", 1, 0), newEvent("kubectl/v1.24.0", "update", "root:org:ws1", 0)),
+			opts:   Options{HotLoopThreshold: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Analyze(tt.events, tt.opts)
+			if !reflect.DeepEqual(report.HotLoops, tt.want) {
+				t.Errorf("HotLoops = %+v, want %+v", report.HotLoops, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindHotLoop(t *testing.T) {
+	at := func(seconds ...int) []time.Time {
+		timestamps := make([]time.Time, 0, len(seconds))
+		for _, s := range seconds {
+			timestamps = append(timestamps, start.Add(time.Duration(s)*time.Second))
+		}
+		return timestamps
+	}
+	tests := []struct {
+		name       string
+		timestamps []time.Time
+		want       HotLoop
+		wantFound  bool
+	}{
+		{
+			name: "no update",
+		},
+		{
+			name:       "at the threshold",
+			timestamps: at(0, 1, 2),
+			want:       HotLoop{Updates: 3, Start: start, End: start.Add(2 * time.Second)},
+		},
+		{
+			name:       "above the threshold",
+			timestamps: at(0, 1, 2, 3),
+			want:       HotLoop{Updates: 4, Start: start, End: start.Add(3 * time.Second)},
+			wantFound:  true,
+		},
+		{
+			name:       "unsorted",
+			timestamps: at(30, 3, 31, 0, 32, 33),
+			want:       HotLoop{Updates: 4, Start: start.Add(30 * time.Second), End: start.Add(33 * time.Second)},
+			wantFound:  true,
+		},
+		{
+			name:       "window bounds included",
+			timestamps: at(0, 5, 10, 20),
+			want:       HotLoop{Updates: 3, Start: start, End: start.Add(10 * time.Second)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := findHotLoop(tt.timestamps, 3, 10*time.Second)
+			if !reflect.DeepEqual(got, tt.want) || found != tt.wantFound {
+				t.Errorf("findHotLoop() = %+v, %t, want %+v, %t", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+// recordingT records the errors of the assertions.
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	opts := Options{Workspaces: []string{"root:org:ws1"}}
+	clean := Analyze(updates("root:org:ws1", 1, 0), opts)
+	crossTenant := Analyze(updates("root:org:ws2", 1, 0), opts)
+	hotLoop := Analyze(updates("root:org:ws1", DefaultHotLoopThreshold+1, 0), opts)
+
+	tests := []struct {
+		name   string
+		report *Report
+		assert func(TestingT, *Report)
+		check  func(*Report) error
+		want   string
+	}{
+		{
+			name:   "no cross tenant writes",
+			report: clean,
+			assert: AssertNoCrossTenantWrites,
+			check:  (*Report).NoCrossTenantWrites,
+		},
+		{
+			name:   "cross tenant writes",
+			report: crossTenant,
+			assert: AssertNoCrossTenantWrites,
+			check:  (*Report).NoCrossTenantWrites,
+			want: "1 write(s) outside of the reconciled workspaces [root:org:ws1]:\n" +
+				"update /clusters/root:org:ws2/apis/data.example.com/v1alpha1/namespaces/default/widgets/w1 (user agent \"manager/v0.0.0\")",
+		},
+		{
+			name:   "no hot loops",
+			report: clean,
+			assert: AssertNoHotLoops,
+			check:  (*Report).NoHotLoops,
+		},
+		{
+			name:   "hot loops",
+			report: hotLoop,
+			assert: AssertNoHotLoops,
+			check:  (*Report).NoHotLoops,
+			want: fmt.Sprintf("1 object(s) updated in a hot loop:\n"+
+				"root:org:ws1|widgets.data.example.com/default/w1 updated %d times between 10:00:00.000 and 10:00:00.000",
+				DefaultHotLoopThreshold+1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check(tt.report)
+			rt := &recordingT{}
+			tt.assert(rt, tt.report)
+			if tt.want == "" {
+				if err != nil || len(rt.errors) != 0 {
+					t.Errorf("error = %v, assertion errors = %q, want none", err, rt.errors)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+			if !reflect.DeepEqual(rt.errors, []string{tt.want}) {
+				t.Errorf("assertion errors = %q, want %q", rt.errors, tt.want)
+			}
+		})
+	}
+}
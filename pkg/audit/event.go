@@ -0,0 +1,134 @@
+// Package audit analyzes the audit log written by kcp during the end-to-end tests of a scaffolded project.
+//
+// The generated Makefile starts kcp with test/e2e/audit-policy.yaml and writes the log to
+// $(ARTIFACT_DIR)/audit.log. The functions of this package read that log, aggregate the requests
+// per workspace and user agent and flag the behaviours a cluster aware controller should not have.
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event is the subset of an audit.k8s.io/v1 Event that is needed for the analysis.
+type Event struct {
+	AuditID                  string            `json:"auditID"`
+	Stage                    string            `json:"stage"`
+	RequestURI               string            `json:"requestURI"`
+	Verb                     string            `json:"verb"`
+	User                     UserInfo          `json:"user"`
+	UserAgent                string            `json:"userAgent,omitempty"`
+	ObjectRef                *ObjectReference  `json:"objectRef,omitempty"`
+	ResponseStatus           *ResponseStatus   `json:"responseStatus,omitempty"`
+	RequestReceivedTimestamp time.Time         `json:"requestReceivedTimestamp"`
+	StageTimestamp           time.Time         `json:"stageTimestamp"`
+	Annotations              map[string]string `json:"annotations,omitempty"`
+}
+
+// UserInfo identifies the user that issued a request.
+type UserInfo struct {
+	Username string   `json:"username,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// ObjectReference identifies the object a request was made against.
+type ObjectReference struct {
+	Resource    string `json:"resource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+	APIGroup    string `json:"apiGroup,omitempty"`
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+}
+
+// ResponseStatus is the status returned for a request.
+type ResponseStatus struct {
+	Code int32 `json:"code,omitempty"`
+}
+
+// WildcardWorkspace is the workspace reported for requests spanning all logical clusters,
+// e.g. the list and watch requests a controller issues against an APIExport virtual workspace.
+const WildcardWorkspace = "*"
+
+// Workspace returns the logical cluster the request was made against.
+// It is extracted from the /clusters/<name> segment of the request URI, which is present
+// for requests sent to a workspace as well as for requests sent through a virtual workspace.
+// An empty string is returned when the request URI does not contain such a segment.
+func (e *Event) Workspace() string {
+	path, _, _ := strings.Cut(e.RequestURI, "?")
+	_, rest, found := strings.Cut(path, "/clusters/")
+	if !found {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	return name
+}
+
+// Resource returns the resource the request was made against, qualified by its group
+// and suffixed with the subresource if any, e.g. "widgets.data.example.com/status".
+func (e *Event) Resource() string {
+	if e.ObjectRef == nil {
+		return ""
+	}
+	resource := e.ObjectRef.Resource
+	if e.ObjectRef.APIGroup != "" {
+		resource += "." + e.ObjectRef.APIGroup
+	}
+	if e.ObjectRef.Subresource != "" {
+		resource += "/" + e.ObjectRef.Subresource
+	}
+	return resource
+}
+
+// IsWrite returns true if the request may have modified the object it was made against.
+func (e *Event) IsWrite() bool {
+	switch e.Verb {
+	case "create", "update", "patch", "delete", "deletecollection":
+		return true
+	}
+	return false
+}
+
+// ReadFile reads the audit events stored in the file at path.
+func ReadFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read reads audit events, one JSON document per line as written by the log backend of kcp.
+// Each audit ID is only returned once: when the policy records several stages of the same request
+// the first stage found in the log is kept.
+func Read(r io.Reader) ([]Event, error) {
+	var events []Event
+	seen := map[string]bool{}
+
+	decoder := json.NewDecoder(r)
+	for i := 1; ; i++ {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("error decoding audit event %d: %w", i, err)
+		}
+		if event.AuditID != "" {
+			if seen[event.AuditID] {
+				continue
+			}
+			seen[event.AuditID] = true
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultControllerUserAgent is the user agent prefix of the requests issued by the manager
+	// of a scaffolded project. controller-runtime sets the user agent to the name of the binary.
+	DefaultControllerUserAgent = "manager/"
+	// DefaultHotLoopThreshold is the number of updates to a single object within
+	// DefaultHotLoopWindow above which the controller is considered to be hot looping.
+	DefaultHotLoopThreshold = 10
+	// DefaultHotLoopWindow is the window used to count the updates to a single object.
+	DefaultHotLoopWindow = 10 * time.Second
+)
+
+// Options configures the analysis of the audit events.
+type Options struct {
+	// ControllerUserAgent is the prefix of the user agent identifying the requests of the controller.
+	// It defaults to DefaultControllerUserAgent.
+	ControllerUserAgent string
+	// PrimaryResources are the resources reconciled by the controller, e.g. "widgets.data.example.com".
+	// A workspace is considered reconciled by the controller when a user agent other than the controller
+	// wrote one of these resources in it. When empty any write from another user agent counts.
+	PrimaryResources []string
+	// Workspaces are additional workspaces the controller is allowed to write to,
+	// e.g. the workspace where it stores its leader election lease.
+	Workspaces []string
+	// HotLoopThreshold is the number of updates to a single object within HotLoopWindow
+	// above which the controller is considered to be hot looping. It defaults to DefaultHotLoopThreshold.
+	HotLoopThreshold int
+	// HotLoopWindow is the window used to count the updates to a single object.
+	// It defaults to DefaultHotLoopWindow.
+	HotLoopWindow time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.ControllerUserAgent == "" {
+		o.ControllerUserAgent = DefaultControllerUserAgent
+	}
+	if o.HotLoopThreshold == 0 {
+		o.HotLoopThreshold = DefaultHotLoopThreshold
+	}
+	if o.HotLoopWindow == 0 {
+		o.HotLoopWindow = DefaultHotLoopWindow
+	}
+}
+
+// RequestCount is the number of requests a user agent issued in a workspace for a verb and a resource.
+type RequestCount struct {
+	Workspace string `json:"workspace"`
+	UserAgent string `json:"userAgent"`
+	Verb      string `json:"verb"`
+	Resource  string `json:"resource"`
+	Count     int    `json:"count"`
+}
+
+// ObjectKey identifies an object within a workspace.
+type ObjectKey struct {
+	Workspace string `json:"workspace"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (k ObjectKey) String() string {
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s|%s/%s", k.Workspace, k.Resource, k.Name)
+	}
+	return fmt.Sprintf("%s|%s/%s/%s", k.Workspace, k.Resource, k.Namespace, k.Name)
+}
+
+// HotLoop reports an object the controller updated more than the configured threshold within the window.
+type HotLoop struct {
+	Object  ObjectKey `json:"object"`
+	Updates int       `json:"updates"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// Report is the result of the analysis of the audit events.
+type Report struct {
+	// Requests lists the request counts sorted by workspace, user agent, verb and resource.
+	Requests []RequestCount `json:"requests"`
+	// ReconciledWorkspaces lists the workspaces the controller was expected to write to.
+	ReconciledWorkspaces []string `json:"reconciledWorkspaces"`
+	// CrossTenantWrites lists the writes the controller made outside of ReconciledWorkspaces.
+	CrossTenantWrites []Event `json:"crossTenantWrites,omitempty"`
+	// HotLoops lists the objects the controller updated too often.
+	HotLoops []HotLoop `json:"hotLoops,omitempty"`
+}
+
+// Analyze aggregates the audit events and flags the writes and update loops of the controller
+// that do not match the expectations for a cluster aware controller.
+func Analyze(events []Event, opts Options) *Report {
+	opts.setDefaults()
+
+	isController := func(e *Event) bool {
+		return strings.HasPrefix(e.UserAgent, opts.ControllerUserAgent)
+	}
+	isPrimary := func(e *Event) bool {
+		if len(opts.PrimaryResources) == 0 {
+			return true
+		}
+		resource, _, _ := strings.Cut(e.Resource(), "/")
+		for _, r := range opts.PrimaryResources {
+			if r == resource || (e.ObjectRef != nil && r == e.ObjectRef.Resource) {
+				return true
+			}
+		}
+		return false
+	}
+
+	report := &Report{}
+
+	counts := map[RequestCount]int{}
+	reconciled := map[string]bool{}
+	for _, w := range opts.Workspaces {
+		reconciled[w] = true
+	}
+	for i := range events {
+		e := &events[i]
+		counts[RequestCount{Workspace: e.Workspace(), UserAgent: e.UserAgent, Verb: e.Verb, Resource: e.Resource()}]++
+		if !isController(e) && e.IsWrite() && isPrimary(e) && e.Workspace() != "" {
+			reconciled[e.Workspace()] = true
+		}
+	}
+
+	for key, count := range counts {
+		key.Count = count
+		report.Requests = append(report.Requests, key)
+	}
+	sort.Slice(report.Requests, func(i, j int) bool {
+		a, b := report.Requests[i], report.Requests[j]
+		if a.Workspace != b.Workspace {
+			return a.Workspace < b.Workspace
+		}
+		if a.UserAgent != b.UserAgent {
+			return a.UserAgent < b.UserAgent
+		}
+		if a.Verb != b.Verb {
+			return a.Verb < b.Verb
+		}
+		return a.Resource < b.Resource
+	})
+
+	for w := range reconciled {
+		report.ReconciledWorkspaces = append(report.ReconciledWorkspaces, w)
+	}
+	sort.Strings(report.ReconciledWorkspaces)
+
+	updates := map[ObjectKey][]time.Time{}
+	for i := range events {
+		e := &events[i]
+		if !isController(e) || !e.IsWrite() {
+			continue
+		}
+		if !reconciled[e.Workspace()] {
+			report.CrossTenantWrites = append(report.CrossTenantWrites, *e)
+		}
+		if (e.Verb == "update" || e.Verb == "patch") && e.ObjectRef != nil && e.ObjectRef.Name != "" {
+			key := ObjectKey{
+				Workspace: e.Workspace(),
+				Resource:  e.Resource(),
+				Namespace: e.ObjectRef.Namespace,
+				Name:      e.ObjectRef.Name,
+			}
+			updates[key] = append(updates[key], e.RequestReceivedTimestamp)
+		}
+	}
+
+	for key, timestamps := range updates {
+		if hotLoop, ok := findHotLoop(timestamps, opts.HotLoopThreshold, opts.HotLoopWindow); ok {
+			hotLoop.Object = key
+			report.HotLoops = append(report.HotLoops, hotLoop)
+		}
+	}
+	sort.Slice(report.HotLoops, func(i, j int) bool {
+		return report.HotLoops[i].Object.String() < report.HotLoops[j].Object.String()
+	})
+
+	return report
+}
+
+// findHotLoop returns the window with the largest number of updates if it exceeds the threshold.
+func findHotLoop(timestamps []time.Time, threshold int, window time.Duration) (HotLoop, bool) {
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+
+	var worst HotLoop
+	start := 0
+	for end := range timestamps {
+		for timestamps[end].Sub(timestamps[start]) > window {
+			start++
+		}
+		if n := end - start + 1; n > worst.Updates {
+			worst = HotLoop{Updates: n, Start: timestamps[start], End: timestamps[end]}
+		}
+	}
+
+	return worst, worst.Updates > threshold
+}
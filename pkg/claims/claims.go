@@ -0,0 +1,139 @@
+// Package claims checks that the tenants accepted the permission claims of the APIExport of the controller.
+//
+// The objects of the resources defined outside of the APIExport, e.g. the Secrets of a tenant, are reached through
+// the virtual workspace of the APIExport only when the APIBinding of the logical cluster accepts the permission
+// claim of their resource. Otherwise the requests fail with Forbidden errors. A Checker reads the APIBindings of the
+// logical clusters from the cache of the manager to tell which claims are accepted.
+//
+// The reconcilers call CheckCurrent before reaching the claimed resources. When a claim is not accepted, they report
+// it on the object with the NotAcceptedReason reason and requeue it after RecheckInterval, so that the reconciliation
+// resumes once the tenant accepts the claim.
+package claims
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// NotAcceptedReason is the reason of the conditions reporting that a permission claim is not accepted.
+	NotAcceptedReason = "PermissionClaimNotAccepted"
+	// RecheckInterval is the period after which the objects waiting for a permission claim to be accepted are
+	// reconciled again.
+	RecheckInterval = time.Minute
+)
+
+// NotAcceptedError is returned when a logical cluster has not accepted the permission claim of a resource.
+type NotAcceptedError struct {
+	// Cluster is the logical cluster.
+	Cluster logicalcluster.Name
+	// GroupResource is the claimed resource.
+	GroupResource schema.GroupResource
+	// Bound is whether the logical cluster has an APIBinding to the APIExport.
+	Bound bool
+}
+
+func (e *NotAcceptedError) Error() string {
+	if !e.Bound {
+		return fmt.Sprintf("logical cluster %s has no APIBinding to the APIExport", e.Cluster)
+	}
+	return fmt.Sprintf("logical cluster %s has not accepted the permission claim of %s", e.Cluster, e.GroupResource)
+}
+
+// IsNotAccepted returns whether err is, or wraps, a NotAcceptedError.
+func IsNotAccepted(err error) bool {
+	var notAccepted *NotAcceptedError
+	return errors.As(err, &notAccepted)
+}
+
+// Checker checks the permission claims accepted by the logical clusters.
+type Checker struct {
+	// reader reads the APIBindings of the logical clusters, it is nil when not connected to kcp.
+	reader client.Reader
+}
+
+// NewChecker returns a Checker reading the APIBindings from reader, the cache of a cluster aware manager. reader
+// is nil when not connected to kcp, all the claims are then accepted.
+func NewChecker(reader client.Reader) *Checker {
+	return &Checker{reader: reader}
+}
+
+// Check returns nil when the APIBinding of the logical cluster to the APIExport accepts the permission claims of
+// grs, or binds them, which are then resources of the APIExport. It returns a NotAcceptedError for the first one
+// that is not accepted otherwise.
+func (c *Checker) Check(ctx context.Context, cluster logicalcluster.Name, grs ...schema.GroupResource) error {
+	if c.reader == nil || len(grs) == 0 {
+		return nil
+	}
+	bindings := &apisv1alpha1.APIBindingList{}
+	if err := c.reader.List(logicalcluster.WithCluster(ctx, cluster), bindings); err != nil {
+		return fmt.Errorf("error listing the APIBindings of logical cluster %s: %w", cluster, err)
+	}
+	for _, gr := range grs {
+		if !accepted(bindings.Items, gr) {
+			return &NotAcceptedError{Cluster: cluster, GroupResource: gr, Bound: len(bindings.Items) != 0}
+		}
+	}
+	return nil
+}
+
+// accepted returns whether one of the APIBindings, which are the ones to the APIExport as seen through its virtual
+// workspace, binds gr or accepts its permission claim.
+func accepted(bindings []apisv1alpha1.APIBinding, gr schema.GroupResource) bool {
+	for i := range bindings {
+		for _, bound := range bindings[i].Status.BoundResources {
+			if bound.Group == gr.Group && bound.Resource == gr.Resource {
+				return true
+			}
+		}
+		for _, claim := range bindings[i].Spec.PermissionClaims {
+			if claim.Group == gr.Group && claim.Resource == gr.Resource && claim.State == apisv1alpha1.ClaimAccepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	defaultCheckerMu sync.RWMutex
+	defaultChecker   *Checker
+)
+
+// SetDefault sets the Checker used by Check. It is called by kcpmanager.NewManager.
+// All the claims are accepted when it is not set.
+func SetDefault(c *Checker) {
+	defaultCheckerMu.Lock()
+	defer defaultCheckerMu.Unlock()
+	defaultChecker = c
+}
+
+// Check checks the claims with the default Checker, see Checker.Check.
+func Check(ctx context.Context, cluster logicalcluster.Name, grs ...schema.GroupResource) error {
+	defaultCheckerMu.RLock()
+	c := defaultChecker
+	defaultCheckerMu.RUnlock()
+	if c == nil {
+		return nil
+	}
+	return c.Check(ctx, cluster, grs...)
+}
+
+// CheckCurrent checks the claims with the default Checker for the logical cluster of ctx, which
+// clusteraware.NewReconciler scopes to the logical cluster of the request. The claims are accepted when ctx has no
+// logical cluster, e.g. when not connected to kcp.
+func CheckCurrent(ctx context.Context, grs ...schema.GroupResource) error {
+	cluster, ok := logicalcluster.ClusterFromContext(ctx)
+	if !ok || cluster.Empty() {
+		return nil
+	}
+	return Check(ctx, cluster, grs...)
+}
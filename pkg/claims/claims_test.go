@@ -0,0 +1,97 @@
+package claims
+
+import (
+	"context"
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeReader serves the APIBindings of the logical clusters it is created with.
+type fakeReader struct {
+	client.Reader
+	bindings map[string][]apisv1alpha1.APIBinding
+}
+
+func (r *fakeReader) List(ctx context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	clusterName, _ := logicalcluster.ClusterFromContext(ctx)
+	list.(*apisv1alpha1.APIBindingList).Items = r.bindings[clusterName.String()]
+	return nil
+}
+
+func newBinding(claims ...apisv1alpha1.AcceptablePermissionClaim) apisv1alpha1.APIBinding {
+	binding := apisv1alpha1.APIBinding{}
+	binding.Spec.PermissionClaims = claims
+	binding.Status.BoundResources = []apisv1alpha1.BoundAPIResource{{Group: "cache.example.com", Resource: "widgets"}}
+	return binding
+}
+
+func newClaim(group, resource string, state apisv1alpha1.AcceptablePermissionClaimState) apisv1alpha1.AcceptablePermissionClaim {
+	claim := apisv1alpha1.AcceptablePermissionClaim{State: state}
+	claim.Group = group
+	claim.Resource = resource
+	return claim
+}
+
+func TestCheck(t *testing.T) {
+	c := NewChecker(&fakeReader{bindings: map[string][]apisv1alpha1.APIBinding{
+		"root:accepted": {newBinding(newClaim("", "secrets", apisv1alpha1.ClaimAccepted))},
+		"root:rejected": {newBinding(newClaim("", "secrets", apisv1alpha1.ClaimRejected))},
+	}})
+	secrets := schema.GroupResource{Resource: "secrets"}
+
+	tests := []struct {
+		name        string
+		cluster     string
+		gr          schema.GroupResource
+		notAccepted bool
+		bound       bool
+	}{
+		{name: "accepted claim", cluster: "root:accepted", gr: secrets},
+		{name: "bound resource", cluster: "root:rejected", gr: schema.GroupResource{Group: "cache.example.com", Resource: "widgets"}},
+		{name: "rejected claim", cluster: "root:rejected", gr: secrets, notAccepted: true, bound: true},
+		{name: "missing claim", cluster: "root:accepted", gr: schema.GroupResource{Resource: "configmaps"}, notAccepted: true, bound: true},
+		{name: "not bound", cluster: "root:other", gr: secrets, notAccepted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Check(context.Background(), logicalcluster.New(tt.cluster), tt.gr)
+			if IsNotAccepted(err) != tt.notAccepted {
+				t.Fatalf("expected not accepted to be %v, got %v", tt.notAccepted, err)
+			}
+			if tt.notAccepted && err.(*NotAcceptedError).Bound != tt.bound {
+				t.Errorf("expected bound to be %v, got %v", tt.bound, err)
+			}
+		})
+	}
+
+	// The claims are accepted when not connected to kcp.
+	if err := NewChecker(nil).Check(context.Background(), logicalcluster.New("root:other"), secrets); err != nil {
+		t.Errorf("expected the claims to be accepted without kcp, got %v", err)
+	}
+}
+
+func TestCheckCurrent(t *testing.T) {
+	SetDefault(NewChecker(&fakeReader{bindings: map[string][]apisv1alpha1.APIBinding{
+		"root:ws": {newBinding(newClaim("", "secrets", apisv1alpha1.ClaimAccepted))},
+	}}))
+	defer SetDefault(nil)
+	secrets := schema.GroupResource{Resource: "secrets"}
+	configMaps := schema.GroupResource{Resource: "configmaps"}
+
+	ctx := logicalcluster.WithCluster(context.Background(), logicalcluster.New("root:ws"))
+	if err := CheckCurrent(ctx, secrets); err != nil {
+		t.Errorf("expected the claim to be accepted, got %v", err)
+	}
+	err := CheckCurrent(ctx, secrets, configMaps)
+	if !IsNotAccepted(err) || err.(*NotAcceptedError).GroupResource != configMaps {
+		t.Errorf("expected the claim of the configmaps not to be accepted, got %v", err)
+	}
+	// The claims are accepted when the context has no logical cluster.
+	if err := CheckCurrent(context.Background(), configMaps); err != nil {
+		t.Errorf("expected the claims to be accepted without logical cluster, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package clusteraware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// EnqueueRequestForOwner returns a handler enqueuing a request for the controller owner of the objects, when it
+// has the type of ownerType, like the handler of the Owns method of the controller builder. The request is in
+// the logical cluster of the object: owner references do not cross workspaces.
+// The mapper tells whether the owner is namespaced.
+func EnqueueRequestForOwner(scheme *runtime.Scheme, mapper meta.RESTMapper, ownerType client.Object) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		ownerRef := metav1.GetControllerOf(obj)
+		if ownerRef == nil {
+			return nil
+		}
+		ownerGVK, err := apiutil.GVKForObject(ownerType, scheme)
+		if err != nil {
+			logf.Log.WithName("clusteraware").Error(err, "unable to get the kind of the owner", "type", fmt.Sprintf("%T", ownerType))
+			return nil
+		}
+		refGV, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+		if err != nil || refGV.Group != ownerGVK.Group || ownerRef.Kind != ownerGVK.Kind {
+			return nil
+		}
+
+		request := reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: ownerRef.Name},
+			ClusterName:    logicalcluster.From(obj).String(),
+		}
+		mapping, err := mapper.RESTMapping(ownerGVK.GroupKind(), ownerGVK.Version)
+		if err != nil {
+			logf.Log.WithName("clusteraware").Error(err, "unable to get the REST mapping of the owner", "kind", ownerGVK)
+			return nil
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameRoot {
+			request.Namespace = obj.GetNamespace()
+		}
+		return []reconcile.Request{request}
+	})
+}
+
+// SetControllerReference sets owner as the controller owner of controlled, like the function of the controllerutil
+// package, after checking that they are in the same logical cluster. The logical cluster of an object that has not
+// been created yet is the one of ctx, in which the clients of the manager create it.
+func SetControllerReference(ctx context.Context, owner, controlled metav1.Object, scheme *runtime.Scheme) error {
+	ownerCluster := logicalcluster.From(owner)
+	controlledCluster := logicalcluster.From(controlled)
+	if controlledCluster.Empty() {
+		controlledCluster, _ = logicalcluster.ClusterFromContext(ctx)
+	}
+	if !ownerCluster.Empty() && !controlledCluster.Empty() && ownerCluster != controlledCluster {
+		return fmt.Errorf("cross-workspace owner references are not allowed: owner %s/%s is in cluster %s, not in cluster %s",
+			owner.GetNamespace(), owner.GetName(), ownerCluster, controlledCluster)
+	}
+	return controllerutil.SetControllerReference(owner, controlled, scheme)
+}
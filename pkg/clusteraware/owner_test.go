@@ -0,0 +1,77 @@
+package clusteraware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newObjectMeta(clusterName, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   "default",
+		UID:         types.UID(clusterName + "/" + name),
+		Annotations: map[string]string{logicalcluster.AnnotationKey: clusterName},
+	}
+}
+
+func TestEnqueueRequestForOwner(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	h := EnqueueRequestForOwner(scheme.Scheme, mapper, &corev1.ConfigMap{})
+
+	owner := &corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:ws", "widget")}
+	owned := &corev1.Secret{ObjectMeta: newObjectMeta("root:org:ws", "widget-credentials")}
+	if err := SetControllerReference(context.Background(), owner, owned, scheme.Scheme); err != nil {
+		t.Fatalf("unable to set the owner reference: %v", err)
+	}
+	notOwned := &corev1.Secret{ObjectMeta: newObjectMeta("root:org:ws", "other")}
+
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	h.Create(event.CreateEvent{Object: notOwned}, q)
+	h.Create(event.CreateEvent{Object: owned}, q)
+	if q.Len() != 1 {
+		t.Fatalf("expected a request for the owner only, got %d requests", q.Len())
+	}
+	item, _ := q.Get()
+	want := reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "widget"},
+		ClusterName:    "root:org:ws",
+	}
+	if item != want {
+		t.Errorf("expected request %v, got %v", want, item)
+	}
+}
+
+func TestSetControllerReference(t *testing.T) {
+	owner := &corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:ws", "widget")}
+
+	// An object that is not created yet is in the logical cluster of the context.
+	ctx := logicalcluster.WithCluster(context.Background(), logicalcluster.New("root:org:ws"))
+	owned := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "widget-credentials", Namespace: "default"}}
+	if err := SetControllerReference(ctx, owner, owned, scheme.Scheme); err != nil {
+		t.Fatalf("unable to set the owner reference: %v", err)
+	}
+	if ref := metav1.GetControllerOf(owned); ref == nil || ref.Name != "widget" {
+		t.Errorf("expected the owner reference to be set, got %v", owned.OwnerReferences)
+	}
+
+	ctx = logicalcluster.WithCluster(context.Background(), logicalcluster.New("root:org:other"))
+	owned = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "widget-credentials", Namespace: "default"}}
+	if err := SetControllerReference(ctx, owner, owned, scheme.Scheme); err == nil {
+		t.Errorf("expected an error for an owner in another logical cluster")
+	}
+	if len(owned.OwnerReferences) != 0 {
+		t.Errorf("expected no owner reference, got %v", owned.OwnerReferences)
+	}
+}
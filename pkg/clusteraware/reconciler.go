@@ -0,0 +1,73 @@
+// Package clusteraware provides the middleware that makes a reconciler safe to run against
+// the objects of many logical clusters, and the helpers to own objects within a logical cluster.
+package clusteraware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/workspaces"
+)
+
+// Error records the logical cluster of a failed reconciliation.
+type Error struct {
+	ClusterName logicalcluster.Name
+	Err         error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cluster %s: %v", e.ClusterName, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ClusterFromError returns the logical cluster recorded in err by the reconciler middleware.
+func ClusterFromError(err error) (logicalcluster.Name, bool) {
+	var clusterErr *Error
+	if errors.As(err, &clusterErr) {
+		return clusterErr.ClusterName, true
+	}
+	return logicalcluster.Name{}, false
+}
+
+// NewReconciler wraps r so that, for each request:
+//   - the context carries the logical cluster of the request, which the clients of the manager use
+//     to scope their requests, and a logger with the clusterName key and, when it differs from the name, the
+//     workspace key with the path of the workspace, see workspaces.Path. With kcp v0.9 the name of a logical
+//     cluster is the path of its workspace, e.g. root:org:team;
+//   - a panic is recovered and returned as an error, so that the request is retried with a backoff
+//     rather than stopping the reconciliation of all the other logical clusters;
+//   - the returned errors record the logical cluster, see ClusterFromError.
+func NewReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (result reconcile.Result, err error) {
+		clusterName := logicalcluster.New(req.ClusterName)
+		logger := log.FromContext(ctx).WithValues("clusterName", req.ClusterName)
+		if path, err := workspaces.Path(ctx, clusterName); err != nil {
+			logger.Error(err, "Unable to resolve the workspace of the logical cluster")
+		} else if path != clusterName {
+			logger = logger.WithValues("workspace", path.String())
+		}
+		ctx = log.IntoContext(logicalcluster.WithCluster(ctx, clusterName), logger)
+
+		defer func() {
+			if p := recover(); p != nil {
+				err = &Error{ClusterName: clusterName, Err: fmt.Errorf("panic: %v", p)}
+				logger.Error(err, "Observed a panic in reconciler", "stacktrace", string(debug.Stack()))
+			}
+		}()
+
+		result, err = r.Reconcile(ctx, req)
+		if err != nil {
+			err = &Error{ClusterName: clusterName, Err: err}
+		}
+		return result, err
+	})
+}
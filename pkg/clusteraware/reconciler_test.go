@@ -0,0 +1,114 @@
+package clusteraware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/workspaces"
+)
+
+var request = reconcile.Request{
+	NamespacedName: types.NamespacedName{Namespace: "default", Name: "widget"},
+	ClusterName:    "root:org:tenant",
+}
+
+func TestReconcilerContext(t *testing.T) {
+	var got logicalcluster.Name
+	r := NewReconciler(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		got, _ = logicalcluster.ClusterFromContext(ctx)
+		return reconcile.Result{}, nil
+	}))
+
+	if _, err := r.Reconcile(context.Background(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.String() != request.ClusterName {
+		t.Errorf("expected the context to carry cluster %s, got %q", request.ClusterName, got)
+	}
+}
+
+func TestReconcilerError(t *testing.T) {
+	errFailed := errors.New("failed")
+	r := NewReconciler(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		return reconcile.Result{}, errFailed
+	}))
+
+	_, err := r.Reconcile(context.Background(), request)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected the error of the reconciler to be wrapped, got %v", err)
+	}
+	if clusterName, ok := ClusterFromError(err); !ok || clusterName.String() != request.ClusterName {
+		t.Errorf("expected the error to record cluster %s, got %q", request.ClusterName, clusterName)
+	}
+}
+
+func TestReconcilerPanic(t *testing.T) {
+	r := NewReconciler(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		panic("boom")
+	}))
+
+	_, err := r.Reconcile(context.Background(), request)
+	if err == nil {
+		t.Fatal("expected the panic to be returned as an error")
+	}
+	if clusterName, ok := ClusterFromError(err); !ok || clusterName.String() != request.ClusterName {
+		t.Errorf("expected the error to record cluster %s, got %q", request.ClusterName, clusterName)
+	}
+}
+
+func TestReconcilerLogger(t *testing.T) {
+	defer workspaces.SetDefault(nil)
+
+	tests := []struct {
+		name   string
+		lookup workspaces.Lookup
+		want   string
+	}{
+		{
+			name:   "name of the logical cluster is the path",
+			lookup: workspaces.Identity,
+			want:   `"level"=0 "msg"="reconciling" "clusterName"="root:org:tenant"`,
+		},
+		{
+			name: "opaque name of the logical cluster",
+			lookup: func(context.Context, logicalcluster.Name) (logicalcluster.Name, error) {
+				return logicalcluster.New("root:org:team"), nil
+			},
+			want: `"level"=0 "msg"="reconciling" "clusterName"="root:org:tenant" "workspace"="root:org:team"`,
+		},
+		{
+			name: "lookup error",
+			lookup: func(context.Context, logicalcluster.Name) (logicalcluster.Name, error) {
+				return logicalcluster.Name{}, errors.New("forbidden")
+			},
+			want: `"level"=0 "msg"="reconciling" "clusterName"="root:org:tenant"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaces.SetDefault(tt.lookup)
+
+			var got string
+			logger := funcr.New(func(prefix, args string) { got = args }, funcr.Options{})
+			r := NewReconciler(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+				logr.FromContextOrDiscard(ctx).Info("reconciling")
+				return reconcile.Result{}, nil
+			}))
+
+			if _, err := r.Reconcile(log.IntoContext(context.Background(), logger), request); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected the log line %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package clustermetrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClusterCounter is a prometheus.Collector of counters per logical cluster, bounded as the metrics of the
+// reconciliations: the TopClusters logical clusters with the highest counts get their own cluster label and the
+// other ones are aggregated under the OtherClusters label, with the options set by Register. The counts since the
+// previous scrape are added to the series of the label of their logical cluster at that time, so that the series
+// are monotonic.
+type ClusterCounter struct {
+	desc *prometheus.Desc
+	now  func() time.Time
+	// options returns the number of top logical clusters and the retention of their statistics.
+	options func() (int, time.Duration)
+
+	mu sync.Mutex
+	// clusters are the counts of the logical clusters counted within the ClusterTTL.
+	clusters map[string]*clusterCounts
+	// series are the exposed counts per cluster label.
+	series map[string]*counterSeries
+	// labelValues are the values of the variable labels, by key of the counts.
+	labelValues map[string][]string
+}
+
+// clusterCounts are the counts of a logical cluster.
+type clusterCounts struct {
+	// pending are the counts since the previous collection, by key of the label values.
+	pending map[string]uint64
+	// total is the count since the logical cluster was first seen, to rank it.
+	total    uint64
+	lastSeen time.Time
+}
+
+// counterSeries are the counts exposed under a cluster label, which only increase.
+type counterSeries struct {
+	counts      map[string]uint64
+	lastUpdated time.Time
+}
+
+// NewClusterCounter returns a ClusterCounter of the metric name with the variable labels labelNames, to which the
+// cluster label is added.
+func NewClusterCounter(name, help string, labelNames []string) *ClusterCounter {
+	return &ClusterCounter{
+		desc:        prometheus.NewDesc(name, help, append(append([]string{}, labelNames...), "cluster"), nil),
+		now:         time.Now,
+		options:     defaultCollector.options,
+		clusters:    map[string]*clusterCounts{},
+		series:      map[string]*counterSeries{},
+		labelValues: map[string][]string{},
+	}
+}
+
+// Inc increments the counter of the logical cluster with the values of the variable labels.
+func (c *ClusterCounter) Inc(clusterName string, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.labelValues[key]; !ok {
+		c.labelValues[key] = append([]string{}, labelValues...)
+	}
+	s, ok := c.clusters[clusterName]
+	if !ok {
+		s = &clusterCounts{pending: map[string]uint64{}}
+		c.clusters[clusterName] = s
+	}
+	s.pending[key]++
+	s.total++
+	s.lastSeen = c.now()
+}
+
+// Describe implements prometheus.Collector.
+func (c *ClusterCounter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector. It adds the counts since the previous collection to the series of the
+// label of their logical cluster and drops the counts that expired.
+func (c *ClusterCounter) Collect(ch chan<- prometheus.Metric) {
+	n, clusterTTL := c.options()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	counts := make(map[string]uint64, len(c.clusters))
+	expired := make(map[string]bool)
+	for name, s := range c.clusters {
+		counts[name] = s.total
+		if now.Sub(s.lastSeen) > clusterTTL {
+			expired[name] = true
+		}
+	}
+	top := topClusters(counts, expired, n)
+
+	for name, s := range c.clusters {
+		label := OtherClusters
+		if top[name] {
+			label = name
+		}
+		exposed, ok := c.series[label]
+		if !ok {
+			exposed = &counterSeries{counts: map[string]uint64{}, lastUpdated: now}
+			c.series[label] = exposed
+		}
+		if len(s.pending) > 0 {
+			for key, count := range s.pending {
+				exposed.counts[key] += count
+			}
+			exposed.lastUpdated = now
+			s.pending = map[string]uint64{}
+		}
+		if expired[name] {
+			delete(c.clusters, name)
+		}
+	}
+
+	for label, exposed := range c.series {
+		if label != OtherClusters && !top[label] && now.Sub(exposed.lastUpdated) > clusterTTL {
+			delete(c.series, label)
+			continue
+		}
+		for key, count := range exposed.counts {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(count),
+				append(append([]string{}, c.labelValues[key]...), label)...)
+		}
+	}
+}
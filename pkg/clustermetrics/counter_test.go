@@ -0,0 +1,54 @@
+package clustermetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClusterCounter(t *testing.T) {
+	start := time.Now()
+	now := start
+	c := NewClusterCounter("kcp_test_changes_total", "Test changes.", []string{"verb"})
+	c.now = func() time.Time { return now }
+	c.options = func() (int, time.Duration) { return 1, time.Minute }
+	header := `
+# HELP kcp_test_changes_total Test changes.
+# TYPE kcp_test_changes_total counter
+`
+
+	c.Inc("root:a", "create")
+	c.Inc("root:a", "update")
+	c.Inc("root:b", "create")
+	want := header + `kcp_test_changes_total{cluster="other",verb="create"} 1
+kcp_test_changes_total{cluster="root:a",verb="create"} 1
+kcp_test_changes_total{cluster="root:a",verb="update"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// root:b overtakes root:a, whose series stops increasing. The series are dropped once they have not increased
+	// within the retention.
+	now = start.Add(45 * time.Second)
+	for i := 0; i < 3; i++ {
+		c.Inc("root:b", "create")
+	}
+	c.Inc("root:a", "create")
+	want = header + `kcp_test_changes_total{cluster="other",verb="create"} 2
+kcp_test_changes_total{cluster="root:a",verb="create"} 1
+kcp_test_changes_total{cluster="root:a",verb="update"} 1
+kcp_test_changes_total{cluster="root:b",verb="create"} 3
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+	now = start.Add(2 * time.Minute)
+	want = header + `kcp_test_changes_total{cluster="other",verb="create"} 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
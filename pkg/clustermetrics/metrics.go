@@ -0,0 +1,416 @@
+// Package clustermetrics records the reconciliations of the controllers per logical cluster.
+//
+// A cluster aware controller reconciles the objects of an unbounded number of logical clusters and labelling
+// its metrics with the cluster name would create an unbounded number of series. The collector keeps the
+// statistics of the logical clusters reconciled within the ClusterTTL in memory and, at scrape time, exposes the
+// TopClusters logical clusters with the most reconciliations of each controller under their own label. The
+// statistics of the other logical clusters are aggregated under the OtherClusters label.
+//
+// The reconciliations completed since the previous scrape are added to the series of the label of their logical
+// cluster at that time, so that every series is monotonic as the ranking changes over time: the series of a
+// logical cluster leaving the top ones stops increasing while the other series goes on from where it was. The
+// series of a logical cluster outside of the top ones is dropped when it has not increased within the ClusterTTL.
+//
+// ClusterCounter bounds the other counters per logical cluster, e.g. the changes of the dry-run mode, the same way.
+//
+// The requests queued per logical cluster are exposed for the controllers whose workqueue is observed with
+// ObserveQueue, e.g. a clusterratelimit.Queue, under the same labels: the depth of the logical clusters outside of
+// the top ones is aggregated under the OtherClusters label.
+package clustermetrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// OtherClusters is the cluster label of the aggregated statistics of the logical clusters outside of the top ones.
+	OtherClusters = "other"
+	// DefaultTopClusters is the default number of logical clusters exposed under their own label per controller.
+	DefaultTopClusters = 10
+	// DefaultClusterTTL is the default time after which the statistics of a logical cluster that is not reconciled
+	// any more are dropped.
+	DefaultClusterTTL = time.Hour
+)
+
+// The results of a reconciliation, in the order of their counters.
+const (
+	resultSuccess = iota
+	resultError
+	resultRequeue
+	resultRequeueAfter
+	resultCount
+)
+
+var resultLabels = [resultCount]string{"success", "error", "requeue", "requeue_after"}
+
+// durationBuckets are the buckets of the reconcile_time_seconds histograms of controller-runtime.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.15, 0.2, 0.25, 0.3, 0.35, 0.4, 0.45, 0.5, 0.6, 0.7,
+	0.8, 0.9, 1.0, 1.25, 1.5, 1.75, 2.0, 2.5, 3.0, 3.5, 4.0, 4.5, 5, 6, 7, 8, 9, 10, 15, 20, 25, 30, 40, 50, 60}
+
+var (
+	reconcileTotalDesc = prometheus.NewDesc("kcp_controller_reconcile_total",
+		"Total number of reconciliations per controller and logical cluster.",
+		[]string{"controller", "cluster", "result"}, nil)
+	reconcileErrorsDesc = prometheus.NewDesc("kcp_controller_reconcile_errors_total",
+		"Total number of reconciliation errors per controller and logical cluster.",
+		[]string{"controller", "cluster"}, nil)
+	reconcileTimeDesc = prometheus.NewDesc("kcp_controller_reconcile_time_seconds",
+		"Length of time per reconciliation per controller and logical cluster.",
+		[]string{"controller", "cluster"}, nil)
+	activeWorkersDesc = prometheus.NewDesc("kcp_controller_active_workers",
+		"Number of workers currently reconciling the objects of a logical cluster per controller.",
+		[]string{"controller", "cluster"}, nil)
+	workqueueDepthDesc = prometheus.NewDesc("kcp_controller_workqueue_depth",
+		"Number of requests queued per controller and logical cluster.",
+		[]string{"controller", "cluster"}, nil)
+	clustersDesc = prometheus.NewDesc("kcp_controller_clusters",
+		"Number of logical clusters reconciled per controller within the retention of the statistics.",
+		[]string{"controller"}, nil)
+)
+
+// Options configures a Collector.
+type Options struct {
+	// TopClusters is the number of logical clusters exposed under their own label per controller.
+	// It defaults to DefaultTopClusters.
+	TopClusters int
+	// ClusterTTL is the time after which the statistics of a logical cluster that is not reconciled any more are
+	// dropped, as well as its series when it is outside of the top ones. It defaults to DefaultClusterTTL.
+	ClusterTTL time.Duration
+}
+
+// Collector is a prometheus.Collector of the reconciliations per logical cluster.
+type Collector struct {
+	mu          sync.Mutex
+	topClusters int
+	clusterTTL  time.Duration
+	now         func() time.Time
+	// controllers are the statistics per controller name.
+	controllers map[string]*controllerStats
+}
+
+// NewCollector returns a Collector without statistics.
+func NewCollector(opts Options) *Collector {
+	c := &Collector{now: time.Now, controllers: map[string]*controllerStats{}}
+	c.setOptions(opts)
+	return c
+}
+
+func (c *Collector) options() (int, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.topClusters, c.clusterTTL
+}
+
+func (c *Collector) setOptions(opts Options) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.topClusters = opts.TopClusters
+	if c.topClusters <= 0 {
+		c.topClusters = DefaultTopClusters
+	}
+	c.clusterTTL = opts.ClusterTTL
+	if c.clusterTTL <= 0 {
+		c.clusterTTL = DefaultClusterTTL
+	}
+}
+
+// defaultCollector is the collector of NewReconciler.
+var defaultCollector = NewCollector(Options{})
+
+// Register configures the collector recording the reconciliations of the reconcilers returned by NewReconciler
+// and registers it, usually with the metrics.Registry of controller-runtime.
+func Register(registerer prometheus.Registerer, opts Options) error {
+	defaultCollector.setOptions(opts)
+	return registerer.Register(defaultCollector)
+}
+
+// Queue is a workqueue reporting the number of queued requests per logical cluster.
+type Queue interface {
+	Depths() map[string]int
+}
+
+// ObserveQueue exposes the depth per logical cluster of q, the workqueue of the controller, with the collector of
+// Register. It replaces the workqueue previously observed for the controller.
+func ObserveQueue(controllerName string, q Queue) {
+	defaultCollector.ObserveQueue(controllerName, q)
+}
+
+// ObserveQueue exposes the depth per logical cluster of q, the workqueue of the controller.
+func (c *Collector) ObserveQueue(controllerName string, q Queue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.controller(controllerName).queue = q
+}
+
+// NewReconciler wraps r so that its reconciliations are recorded by the collector of Register.
+// It is meant to wrap the reconciler returned by clusteraware.NewReconciler, which recovers from panics.
+func NewReconciler(controllerName string, r reconcile.Reconciler) reconcile.Reconciler {
+	return defaultCollector.NewReconciler(controllerName, r)
+}
+
+// NewReconciler wraps r so that its reconciliations are recorded by c.
+func (c *Collector) NewReconciler(controllerName string, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (result reconcile.Result, err error) {
+		c.started(controllerName, req.ClusterName)
+		start := time.Now()
+		defer func() {
+			c.done(controllerName, req.ClusterName, time.Since(start), result, err)
+		}()
+		return r.Reconcile(ctx, req)
+	})
+}
+
+func (c *Collector) started(controllerName, clusterName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.cluster(controllerName, clusterName)
+	s.active++
+	s.lastSeen = c.now()
+}
+
+func (c *Collector) done(controllerName, clusterName string, duration time.Duration, result reconcile.Result, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.cluster(controllerName, clusterName)
+	s.active--
+	s.lastSeen = c.now()
+	s.reconciliations++
+	switch {
+	case err != nil:
+		s.pending.results[resultError]++
+	case result.RequeueAfter > 0:
+		s.pending.results[resultRequeueAfter]++
+	case result.Requeue:
+		s.pending.results[resultRequeue]++
+	default:
+		s.pending.results[resultSuccess]++
+	}
+	s.pending.observe(duration.Seconds())
+}
+
+func (c *Collector) controller(controllerName string) *controllerStats {
+	controller, ok := c.controllers[controllerName]
+	if !ok {
+		controller = &controllerStats{clusters: map[string]*clusterStats{}, series: map[string]*series{}}
+		c.controllers[controllerName] = controller
+	}
+	return controller
+}
+
+func (c *Collector) cluster(controllerName, clusterName string) *clusterStats {
+	controller := c.controller(controllerName)
+	s, ok := controller.clusters[clusterName]
+	if !ok {
+		s = &clusterStats{pending: newStats()}
+		controller.clusters[clusterName] = s
+	}
+	return s
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- reconcileTotalDesc
+	ch <- reconcileErrorsDesc
+	ch <- reconcileTimeDesc
+	ch <- activeWorkersDesc
+	ch <- workqueueDepthDesc
+	ch <- clustersDesc
+}
+
+// Collect implements prometheus.Collector. It adds the reconciliations completed since the previous collection
+// to the series of the label of their logical cluster and drops the statistics that expired.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for controllerName, controller := range c.controllers {
+		active, top := c.update(controller, now)
+		depths := queueDepths(controller, top)
+		ch <- prometheus.MustNewConstMetric(clustersDesc, prometheus.GaugeValue,
+			float64(len(controller.clusters)), controllerName)
+		for label, s := range controller.series {
+			for i, result := range resultLabels {
+				ch <- prometheus.MustNewConstMetric(reconcileTotalDesc, prometheus.CounterValue,
+					float64(s.results[i]), controllerName, label, result)
+			}
+			ch <- prometheus.MustNewConstMetric(reconcileErrorsDesc, prometheus.CounterValue,
+				float64(s.results[resultError]), controllerName, label)
+			ch <- prometheus.MustNewConstMetric(activeWorkersDesc, prometheus.GaugeValue,
+				float64(active[label]), controllerName, label)
+			if controller.queue != nil {
+				ch <- prometheus.MustNewConstMetric(workqueueDepthDesc, prometheus.GaugeValue,
+					float64(depths[label]), controllerName, label)
+			}
+			ch <- prometheus.MustNewConstHistogram(reconcileTimeDesc, s.count, s.sum, s.cumulativeBuckets(),
+				controllerName, label)
+		}
+	}
+}
+
+// update adds the pending statistics of the logical clusters of the controller to the series of their label,
+// the top logical clusters ranked by number of reconciliations under their own and the other ones under
+// OtherClusters, and drops the expired logical clusters and series. It returns the active workers per label and
+// the top logical clusters.
+func (c *Collector) update(controller *controllerStats, now time.Time) (map[string]int, map[string]bool) {
+	counts := make(map[string]uint64, len(controller.clusters))
+	expired := make(map[string]bool)
+	for name, s := range controller.clusters {
+		counts[name] = s.reconciliations
+		if s.active == 0 && now.Sub(s.lastSeen) > c.clusterTTL {
+			expired[name] = true
+		}
+	}
+	top := topClusters(counts, expired, c.topClusters)
+
+	active := make(map[string]int, c.topClusters+1)
+	for name, s := range controller.clusters {
+		label := OtherClusters
+		if top[name] {
+			label = name
+		}
+		active[label] += s.active
+
+		exposed, ok := controller.series[label]
+		if !ok {
+			exposed = &series{stats: newStats(), lastUpdated: now}
+			controller.series[label] = exposed
+		}
+		if s.pending.count > 0 {
+			exposed.add(s.pending)
+			exposed.lastUpdated = now
+			s.pending = newStats()
+		}
+
+		if expired[name] {
+			delete(controller.clusters, name)
+		}
+	}
+
+	for label, exposed := range controller.series {
+		if label != OtherClusters && !top[label] && now.Sub(exposed.lastUpdated) > c.clusterTTL {
+			delete(controller.series, label)
+		}
+	}
+
+	return active, top
+}
+
+// topClusters returns the n logical clusters with the highest counts that have not expired, the ties being broken
+// by name.
+func topClusters(counts map[string]uint64, expired map[string]bool, n int) map[string]bool {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		if !expired[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	top := make(map[string]bool, len(names))
+	for _, name := range names {
+		top[name] = true
+	}
+	return top
+}
+
+// queueDepths returns the depth of the workqueue of the controller per label, the top logical clusters under their
+// own and the other ones under OtherClusters, whose series is added when they have queued requests.
+func queueDepths(controller *controllerStats, top map[string]bool) map[string]int {
+	if controller.queue == nil {
+		return nil
+	}
+	depths := map[string]int{}
+	for clusterName, depth := range controller.queue.Depths() {
+		label := OtherClusters
+		if top[clusterName] {
+			label = clusterName
+		}
+		depths[label] += depth
+	}
+	if _, ok := controller.series[OtherClusters]; !ok && depths[OtherClusters] > 0 {
+		controller.series[OtherClusters] = &series{stats: newStats()}
+	}
+	return depths
+}
+
+// controllerStats are the statistics of the reconciliations of a controller.
+type controllerStats struct {
+	// clusters are the statistics of the logical clusters reconciled within the ClusterTTL.
+	clusters map[string]*clusterStats
+	// series are the exposed statistics per cluster label, which only increase.
+	series map[string]*series
+	// queue is the observed workqueue of the controller, nil when not observed.
+	queue Queue
+}
+
+// clusterStats are the statistics of the reconciliations of a logical cluster by a controller.
+type clusterStats struct {
+	// pending are the statistics of the reconciliations completed since the previous collection.
+	pending *stats
+	// reconciliations is the number of reconciliations since the logical cluster was first seen, to rank it.
+	reconciliations uint64
+	active          int
+	lastSeen        time.Time
+}
+
+// series are the statistics exposed under a cluster label.
+type series struct {
+	*stats
+	lastUpdated time.Time
+}
+
+// stats are the statistics of reconciliations.
+type stats struct {
+	results [resultCount]uint64
+	// buckets are the number of observations per bucket of durationBuckets, they are not cumulative.
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func newStats() *stats {
+	return &stats{buckets: make([]uint64, len(durationBuckets))}
+}
+
+func (s *stats) observe(seconds float64) {
+	if i := sort.SearchFloat64s(durationBuckets, seconds); i < len(durationBuckets) {
+		s.buckets[i]++
+	}
+	s.count++
+	s.sum += seconds
+}
+
+func (s *stats) add(o *stats) {
+	for i := range s.results {
+		s.results[i] += o.results[i]
+	}
+	for i := range s.buckets {
+		s.buckets[i] += o.buckets[i]
+	}
+	s.count += o.count
+	s.sum += o.sum
+}
+
+func (s *stats) cumulativeBuckets() map[float64]uint64 {
+	buckets := make(map[float64]uint64, len(durationBuckets))
+	var cumulative uint64
+	for i, upperBound := range durationBuckets {
+		cumulative += s.buckets[i]
+		buckets[upperBound] = cumulative
+	}
+	return buckets
+}
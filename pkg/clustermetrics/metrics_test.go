@@ -0,0 +1,234 @@
+package clustermetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileClusters runs r against a request of each cluster.
+func reconcileClusters(t *testing.T, r reconcile.Reconciler, clusterNames ...string) {
+	t.Helper()
+	for _, clusterName := range clusterNames {
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "widget"}, ClusterName: clusterName}
+		_, _ = r.Reconcile(context.Background(), req)
+	}
+}
+
+func TestReconcilerResults(t *testing.T) {
+	c := NewCollector(Options{})
+	results := map[string]func() (reconcile.Result, error){
+		"root:ok":      func() (reconcile.Result, error) { return reconcile.Result{}, nil },
+		"root:failed":  func() (reconcile.Result, error) { return reconcile.Result{}, errors.New("failed") },
+		"root:requeue": func() (reconcile.Result, error) { return reconcile.Result{Requeue: true}, nil },
+	}
+	r := c.NewReconciler("widget", reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		return results[req.ClusterName]()
+	}))
+	reconcileClusters(t, r, "root:ok", "root:ok", "root:failed", "root:requeue")
+
+	want := `
+# HELP kcp_controller_reconcile_errors_total Total number of reconciliation errors per controller and logical cluster.
+# TYPE kcp_controller_reconcile_errors_total counter
+kcp_controller_reconcile_errors_total{cluster="root:failed",controller="widget"} 1
+kcp_controller_reconcile_errors_total{cluster="root:ok",controller="widget"} 0
+kcp_controller_reconcile_errors_total{cluster="root:requeue",controller="widget"} 0
+# HELP kcp_controller_reconcile_total Total number of reconciliations per controller and logical cluster.
+# TYPE kcp_controller_reconcile_total counter
+kcp_controller_reconcile_total{cluster="root:failed",controller="widget",result="error"} 1
+kcp_controller_reconcile_total{cluster="root:failed",controller="widget",result="requeue"} 0
+kcp_controller_reconcile_total{cluster="root:failed",controller="widget",result="requeue_after"} 0
+kcp_controller_reconcile_total{cluster="root:failed",controller="widget",result="success"} 0
+kcp_controller_reconcile_total{cluster="root:ok",controller="widget",result="error"} 0
+kcp_controller_reconcile_total{cluster="root:ok",controller="widget",result="requeue"} 0
+kcp_controller_reconcile_total{cluster="root:ok",controller="widget",result="requeue_after"} 0
+kcp_controller_reconcile_total{cluster="root:ok",controller="widget",result="success"} 2
+kcp_controller_reconcile_total{cluster="root:requeue",controller="widget",result="error"} 0
+kcp_controller_reconcile_total{cluster="root:requeue",controller="widget",result="requeue"} 1
+kcp_controller_reconcile_total{cluster="root:requeue",controller="widget",result="requeue_after"} 0
+kcp_controller_reconcile_total{cluster="root:requeue",controller="widget",result="success"} 0
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"kcp_controller_reconcile_total", "kcp_controller_reconcile_errors_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReconcilerActiveWorkers(t *testing.T) {
+	c := NewCollector(Options{})
+	want := `
+# HELP kcp_controller_active_workers Number of workers currently reconciling the objects of a logical cluster per controller.
+# TYPE kcp_controller_active_workers gauge
+kcp_controller_active_workers{cluster="root:tenant",controller="widget"} %d
+`
+	r := c.NewReconciler("widget", reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		return reconcile.Result{}, testutil.CollectAndCompare(c, strings.NewReader(fmt.Sprintf(want, 1)), "kcp_controller_active_workers")
+	}))
+
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{ClusterName: "root:tenant"}); err != nil {
+		t.Errorf("during the reconciliation: %v", err)
+	}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(fmt.Sprintf(want, 0)), "kcp_controller_active_workers"); err != nil {
+		t.Errorf("after the reconciliation: %v", err)
+	}
+}
+
+func TestCollectorTopClusters(t *testing.T) {
+	c := NewCollector(Options{TopClusters: 2})
+	r := c.NewReconciler("widget", reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		return reconcile.Result{}, nil
+	}))
+	reconcileClusters(t, r, "root:a", "root:a", "root:a", "root:b", "root:b", "root:c", "root:d")
+
+	want := `
+# HELP kcp_controller_clusters Number of logical clusters reconciled per controller within the retention of the statistics.
+# TYPE kcp_controller_clusters gauge
+kcp_controller_clusters{controller="widget"} 4
+# HELP kcp_controller_reconcile_errors_total Total number of reconciliation errors per controller and logical cluster.
+# TYPE kcp_controller_reconcile_errors_total counter
+kcp_controller_reconcile_errors_total{cluster="other",controller="widget"} 0
+kcp_controller_reconcile_errors_total{cluster="root:a",controller="widget"} 0
+kcp_controller_reconcile_errors_total{cluster="root:b",controller="widget"} 0
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"kcp_controller_clusters", "kcp_controller_reconcile_errors_total")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// The histogram of the other clusters aggregates their reconciliations.
+	if n := testutil.CollectAndCount(c, "kcp_controller_reconcile_time_seconds"); n != 3 {
+		t.Errorf("expected 3 reconcile time histograms, got %d", n)
+	}
+}
+
+// reconcileTotal returns the expected reconcile_total series of the cluster label for successful reconciliations.
+func reconcileTotal(clusterName string, successes int) string {
+	return fmt.Sprintf(`kcp_controller_reconcile_total{cluster=%[1]q,controller="widget",result="error"} 0
+kcp_controller_reconcile_total{cluster=%[1]q,controller="widget",result="requeue"} 0
+kcp_controller_reconcile_total{cluster=%[1]q,controller="widget",result="requeue_after"} 0
+kcp_controller_reconcile_total{cluster=%[1]q,controller="widget",result="success"} %[2]d
+`, clusterName, successes)
+}
+
+// reconcileErrors returns the expected reconcile_errors_total series of the cluster labels without errors.
+func reconcileErrors(clusterNames ...string) string {
+	var series string
+	for _, clusterName := range clusterNames {
+		series += fmt.Sprintf("kcp_controller_reconcile_errors_total{cluster=%q,controller=\"widget\"} 0\n", clusterName)
+	}
+	return series
+}
+
+func TestCollectorMonotonicSeries(t *testing.T) {
+	c := NewCollector(Options{TopClusters: 1})
+	r := c.NewReconciler("widget", reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		return reconcile.Result{}, nil
+	}))
+	header := `
+# HELP kcp_controller_reconcile_total Total number of reconciliations per controller and logical cluster.
+# TYPE kcp_controller_reconcile_total counter
+`
+
+	reconcileClusters(t, r, "root:a", "root:a", "root:b")
+	want := header + reconcileTotal(OtherClusters, 1) + reconcileTotal("root:a", 2)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "kcp_controller_reconcile_total"); err != nil {
+		t.Error(err)
+	}
+
+	// root:b overtakes root:a: the next reconciliations of root:a are added to the other series and its own
+	// series stops increasing rather than moving to the other series.
+	reconcileClusters(t, r, "root:b", "root:b", "root:b", "root:a")
+	want = header + reconcileTotal(OtherClusters, 2) + reconcileTotal("root:a", 2) + reconcileTotal("root:b", 3)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "kcp_controller_reconcile_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectorClusterTTL(t *testing.T) {
+	start := time.Now()
+	now := start
+	c := NewCollector(Options{TopClusters: 1, ClusterTTL: time.Minute})
+	c.now = func() time.Time { return now }
+	r := c.NewReconciler("widget", reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		return reconcile.Result{}, nil
+	}))
+	header := `
+# HELP kcp_controller_clusters Number of logical clusters reconciled per controller within the retention of the statistics.
+# TYPE kcp_controller_clusters gauge
+kcp_controller_clusters{controller="widget"} %d
+# HELP kcp_controller_reconcile_errors_total Total number of reconciliation errors per controller and logical cluster.
+# TYPE kcp_controller_reconcile_errors_total counter
+`
+	reconcileClusters(t, r, "root:a", "root:a", "root:b")
+
+	tests := []struct {
+		name       string
+		after      time.Duration
+		reconcile  []string
+		clusters   int
+		wantSeries []string
+	}{
+		{name: "reconciled", clusters: 2, wantSeries: []string{OtherClusters, "root:a"}},
+		{
+			name:       "series kept outside of the top clusters",
+			after:      45 * time.Second,
+			reconcile:  []string{"root:b", "root:b"},
+			clusters:   2,
+			wantSeries: []string{OtherClusters, "root:a", "root:b"},
+		},
+		{name: "cluster and series expired", after: 90 * time.Second, clusters: 1, wantSeries: []string{OtherClusters, "root:b"}},
+		{name: "all expired", after: 150 * time.Second, clusters: 0, wantSeries: []string{OtherClusters}},
+	}
+	for _, tt := range tests {
+		now = start.Add(tt.after)
+		reconcileClusters(t, r, tt.reconcile...)
+		want := fmt.Sprintf(header, tt.clusters) + reconcileErrors(tt.wantSeries...)
+		err := testutil.CollectAndCompare(c, strings.NewReader(want),
+			"kcp_controller_clusters", "kcp_controller_reconcile_errors_total")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+// fakeQueue is a Queue with fixed depths.
+type fakeQueue map[string]int
+
+func (q fakeQueue) Depths() map[string]int {
+	return q
+}
+
+func TestCollectorWorkqueueDepth(t *testing.T) {
+	c := NewCollector(Options{TopClusters: 1})
+	r := c.NewReconciler("widget", reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		return reconcile.Result{}, nil
+	}))
+	reconcileClusters(t, r, "root:a", "root:a", "root:b")
+
+	// The depth of the clusters outside of the top ones, including the ones not reconciled yet, is aggregated.
+	c.ObserveQueue("widget", fakeQueue{"root:a": 3, "root:b": 2, "root:c": 5})
+	want := `
+# HELP kcp_controller_workqueue_depth Number of requests queued per controller and logical cluster.
+# TYPE kcp_controller_workqueue_depth gauge
+kcp_controller_workqueue_depth{cluster="other",controller="widget"} 7
+kcp_controller_workqueue_depth{cluster="root:a",controller="widget"} 3
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "kcp_controller_workqueue_depth"); err != nil {
+		t.Error(err)
+	}
+
+	// The depth is only exposed for the controllers whose workqueue is observed.
+	reconcileClusters(t, c.NewReconciler("gadget", r), "root:a")
+	if n := testutil.CollectAndCount(c, "kcp_controller_workqueue_depth"); n != 2 {
+		t.Errorf("expected the depth of the observed workqueue only, got %d series", n)
+	}
+}
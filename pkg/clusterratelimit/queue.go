@@ -0,0 +1,240 @@
+package clusterratelimit
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+)
+
+// Queue is a workqueue.RateLimitingInterface with a sub-queue per logical cluster. Get takes the requests of the
+// logical clusters in turn, so that a logical cluster with many requests does not delay the requests of the other
+// ones. As with the workqueue of client-go, a request is only queued once and is not handed out again while it is
+// processed.
+type Queue struct {
+	rateLimiter workqueue.RateLimiter
+	// delaying holds the requests added with AddAfter until they are due.
+	delaying workqueue.DelayingInterface
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queues are the queued requests per logical cluster.
+	queues map[string][]interface{}
+	// clusters are the logical clusters with queued requests, in the order of their turn.
+	clusters []string
+	length   int
+	// dirty are the requests to process, queued or processed.
+	dirty map[interface{}]bool
+	// processing are the requests handed out by Get and not done yet.
+	processing   map[interface{}]bool
+	shuttingDown bool
+	drain        bool
+}
+
+var _ workqueue.RateLimitingInterface = &Queue{}
+
+// NewQueue returns a started Queue delaying the requests requeued after a failure with rateLimiter.
+func NewQueue(rateLimiter workqueue.RateLimiter) *Queue {
+	q := &Queue{
+		rateLimiter: rateLimiter,
+		delaying:    workqueue.NewDelayingQueue(),
+		queues:      map[string][]interface{}{},
+		dirty:       map[interface{}]bool{},
+		processing:  map[interface{}]bool{},
+	}
+	q.cond = sync.NewCond(&q.mu)
+	go q.forwardDelayed()
+	return q
+}
+
+// forwardDelayed adds the delayed requests to the queue once they are due.
+func (q *Queue) forwardDelayed() {
+	for {
+		item, shutdown := q.delaying.Get()
+		if shutdown {
+			return
+		}
+		q.Add(item)
+		q.delaying.Done(item)
+	}
+}
+
+// clusterOf returns the logical cluster of the queued item, which is a reconcile.Request for the controllers.
+func clusterOf(item interface{}) string {
+	if req, ok := item.(reconcile.Request); ok {
+		return req.ClusterName
+	}
+	return ""
+}
+
+// Add queues item unless it is already queued. It is queued once it is done when it is being processed.
+func (q *Queue) Add(item interface{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shuttingDown || q.dirty[item] {
+		return
+	}
+	q.dirty[item] = true
+	if q.processing[item] {
+		return
+	}
+	q.push(item)
+	q.cond.Signal()
+}
+
+// push appends item to the sub-queue of its logical cluster, which gets a turn if it was empty.
+func (q *Queue) push(item interface{}) {
+	clusterName := clusterOf(item)
+	if len(q.queues[clusterName]) == 0 {
+		q.clusters = append(q.clusters, clusterName)
+	}
+	q.queues[clusterName] = append(q.queues[clusterName], item)
+	q.length++
+}
+
+// Len returns the number of queued requests.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.length
+}
+
+// Depths returns the number of queued requests per logical cluster.
+func (q *Queue) Depths() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depths := make(map[string]int, len(q.queues))
+	for clusterName, items := range q.queues {
+		depths[clusterName] = len(items)
+	}
+	return depths
+}
+
+// Get blocks until a request is queued and returns the first request of the logical cluster whose turn it is,
+// which then goes to the end of the turns when it has other requests. It returns shutdown once the queue is shut
+// down and empty.
+func (q *Queue) Get() (item interface{}, shutdown bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.clusters) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.clusters) == 0 {
+		return nil, true
+	}
+
+	clusterName := q.clusters[0]
+	q.clusters = q.clusters[1:]
+	items := q.queues[clusterName]
+	item, items = items[0], items[1:]
+	if len(items) == 0 {
+		delete(q.queues, clusterName)
+	} else {
+		q.queues[clusterName] = items
+		q.clusters = append(q.clusters, clusterName)
+	}
+	q.length--
+
+	q.processing[item] = true
+	delete(q.dirty, item)
+	return item, false
+}
+
+// Done marks item as processed. It is queued again when it was added while it was processed.
+func (q *Queue) Done(item interface{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.processing, item)
+	if q.dirty[item] {
+		q.push(item)
+		q.cond.Signal()
+	}
+	if q.drain && len(q.processing) == 0 {
+		q.cond.Broadcast()
+	}
+}
+
+// ShutDown makes Get return shutdown once the queue is empty and ignores the requests added after it.
+func (q *Queue) ShutDown() {
+	q.delaying.ShutDown()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+// ShutDownWithDrain shuts the queue down and waits for the requests being processed to be done.
+func (q *Queue) ShutDownWithDrain() {
+	q.delaying.ShutDown()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shuttingDown = true
+	q.drain = true
+	q.cond.Broadcast()
+	for len(q.processing) > 0 {
+		q.cond.Wait()
+	}
+}
+
+// ShuttingDown returns whether the queue is shut down.
+func (q *Queue) ShuttingDown() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.shuttingDown
+}
+
+// AddAfter adds item once the duration has passed.
+func (q *Queue) AddAfter(item interface{}, duration time.Duration) {
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	q.delaying.AddAfter(item, duration)
+}
+
+// AddRateLimited adds item once the rate limiter says it is ok.
+func (q *Queue) AddRateLimited(item interface{}) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+// Forget stops the rate limiter from tracking the failures of item.
+func (q *Queue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
+
+// NumRequeues returns the number of failures of item tracked by the rate limiter.
+func (q *Queue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+// SetQueue makes c, a controller returned by the builder or by controller.New that is not started yet, use a Queue
+// with the rate limiter of the Limiter instead of the workqueue shared by all the logical clusters. The options of
+// the controllers of controller-runtime have no field for the workqueue: the constructor of the workqueue of the
+// controller is replaced, it fails when c does not have one. The depth of the Queue per logical cluster is
+// exposed by clustermetrics under the name of the controller.
+func (l *Limiter) SetQueue(c controller.Controller, controllerName string) error {
+	makeQueue := func() workqueue.RateLimitingInterface {
+		q := NewQueue(l.RateLimiter())
+		clustermetrics.ObserveQueue(controllerName, q)
+		return q
+	}
+	v := reflect.ValueOf(c)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("unable to replace the workqueue of the controller: unsupported type %T", c)
+	}
+	field := v.FieldByName("MakeQueue")
+	if !field.IsValid() || !field.CanSet() || field.Type() != reflect.TypeOf(makeQueue) {
+		return fmt.Errorf("unable to replace the workqueue of the controller: %T has no MakeQueue field", c)
+	}
+	field.Set(reflect.ValueOf(makeQueue))
+	return nil
+}
@@ -0,0 +1,180 @@
+package clusterratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcptest"
+)
+
+func TestQueueRoundRobin(t *testing.T) {
+	q := NewQueue(workqueue.DefaultItemBasedRateLimiter())
+	defer q.ShutDown()
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		q.Add(request("root:noisy", name))
+	}
+	q.Add(request("root:quiet", "a"))
+	q.Add(request("root:other", "a"))
+	q.Add(request("root:other", "b"))
+	if q.Len() != 7 {
+		t.Fatalf("expected 7 queued requests, got %d", q.Len())
+	}
+	if depths := q.Depths(); depths["root:noisy"] != 4 || depths["root:quiet"] != 1 || depths["root:other"] != 2 {
+		t.Fatalf("expected the depths of the sub-queues, got %v", depths)
+	}
+
+	var got []reconcile.Request
+	for q.Len() > 0 {
+		item, _ := q.Get()
+		got = append(got, item.(reconcile.Request))
+		q.Done(item)
+	}
+	want := []reconcile.Request{
+		request("root:noisy", "a"), request("root:quiet", "a"), request("root:other", "a"),
+		request("root:noisy", "b"), request("root:other", "b"),
+		request("root:noisy", "c"),
+		request("root:noisy", "d"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected the logical clusters to take turns %v, got %v", want, got)
+		}
+	}
+}
+
+func TestQueueDeduplicates(t *testing.T) {
+	q := NewQueue(workqueue.DefaultItemBasedRateLimiter())
+	defer q.ShutDown()
+
+	req := request("root:tenant", "a")
+	q.Add(req)
+	q.Add(req)
+	if q.Len() != 1 {
+		t.Fatalf("expected the request to be queued once, got %d", q.Len())
+	}
+
+	// A request added while it is processed is queued once it is done.
+	item, _ := q.Get()
+	q.Add(req)
+	if q.Len() != 0 {
+		t.Fatalf("expected the request not to be queued while processed, got %d", q.Len())
+	}
+	q.Done(item)
+	if q.Len() != 1 {
+		t.Fatalf("expected the request to be queued again once done, got %d", q.Len())
+	}
+}
+
+func TestQueueAddAfter(t *testing.T) {
+	q := NewQueue(workqueue.DefaultItemBasedRateLimiter())
+	defer q.ShutDown()
+
+	req := request("root:tenant", "a")
+	q.AddAfter(req, 10*time.Millisecond)
+	if q.Len() != 0 {
+		t.Fatalf("expected the request to be delayed, got %d queued", q.Len())
+	}
+	item, _ := q.Get()
+	if item != req {
+		t.Errorf("expected %v, got %v", req, item)
+	}
+	q.Done(item)
+}
+
+func TestQueueShutDown(t *testing.T) {
+	q := NewQueue(workqueue.DefaultItemBasedRateLimiter())
+	q.Add(request("root:tenant", "a"))
+	item, _ := q.Get()
+
+	drained := make(chan struct{})
+	go func() {
+		q.ShutDownWithDrain()
+		close(drained)
+	}()
+	if _, shutdown := q.Get(); !shutdown {
+		t.Fatalf("expected Get to return shutdown")
+	}
+	q.Add(request("root:tenant", "b"))
+	if q.Len() != 0 {
+		t.Errorf("expected the requests added after the shutdown to be ignored")
+	}
+	select {
+	case <-drained:
+		t.Fatalf("expected the shutdown to wait for the request being processed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	q.Done(item)
+	<-drained
+}
+
+func TestSetQueue(t *testing.T) {
+	s := kcptest.NewServer(t, kcptest.Options{WithoutKCPAPIs: true})
+	mgr, err := manager.New(s.RestConfig(), manager.Options{MetricsBindAddress: "0"})
+	if err != nil {
+		t.Fatalf("unable to create the manager: %v", err)
+	}
+
+	reconciled := make(chan reconcile.Request, 1)
+	c, err := controller.NewUnmanaged("test", mgr, controller.Options{
+		Reconciler: reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+			reconciled <- req
+			return reconcile.Result{}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("unable to create the controller: %v", err)
+	}
+	if err := NewLimiter().SetQueue(c, "test"); err != nil {
+		t.Fatalf("unable to set the queue of the controller: %v", err)
+	}
+
+	// The requests reach the reconciler through the workqueue of the controller, which is a Queue.
+	req := request("root:tenant", "a")
+	src := queueSource(func(q workqueue.RateLimitingInterface) {
+		if _, ok := q.(*Queue); !ok {
+			t.Errorf("expected the controller to use a Queue, got %T", q)
+		}
+		q.Add(req)
+	})
+	if err := c.Watch(src, &handler.EnqueueRequestForObject{}); err != nil {
+		t.Fatalf("unable to watch: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = c.Start(ctx)
+	}()
+	select {
+	case got := <-reconciled:
+		if got != req {
+			t.Errorf("expected %v to be reconciled, got %v", req, got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected the request to be reconciled")
+	}
+
+	if err := NewLimiter().SetQueue(nil, "test"); err == nil {
+		t.Errorf("expected an error for a controller without workqueue")
+	}
+}
+
+// queueSource is a source.Source calling the function with the workqueue of the controller.
+type queueSource func(q workqueue.RateLimitingInterface)
+
+func (s queueSource) Start(_ context.Context, _ handler.EventHandler, q workqueue.RateLimitingInterface,
+	_ ...predicate.Predicate) error {
+	s(q)
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package clusterratelimit limits the rate of the reconciliations of a controller per logical cluster.
+//
+// A cluster aware controller reconciles the objects of all the logical clusters. The Limiter admits the requests
+// at the start of their reconciliation: each logical cluster has its own token bucket and the requests of a
+// logical cluster without tokens are requeued, without being reconciled, at the time of the next token of the
+// bucket. A throttled logical cluster only holds a worker for the time of the admission and its
+// requests are spread at its rate rather than reconciled in a burst.
+//
+// The workqueue of a controller is replaced by a Queue, see Limiter.SetQueue, which has a sub-queue per logical
+// cluster and hands out the requests of the logical clusters in turn: the requests of the other logical clusters do
+// not wait behind the backlog of a logical cluster, e.g. after it has created many objects at once, and the workers
+// are shared fairly between the logical clusters with requests.
+//
+// The requests requeued after a failure are delayed by the per item exponential backoff of RateLimiter. It
+// replaces the default rate limiter of controller-runtime, whose overall token bucket is shared by all the
+// logical clusters, the rate of a logical cluster being limited at admission.
+package clusterratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// DefaultQPS is the default rate of the reconciliations of a logical cluster per controller.
+	DefaultQPS = 10
+	// DefaultBurst is the default number of reconciliations of a logical cluster admitted at once per controller.
+	DefaultBurst = 100
+
+	// The delays of the per item exponential backoff, which are the ones of controller-runtime.
+	baseDelay = 5 * time.Millisecond
+	maxDelay  = 1000 * time.Second
+
+	// sweepInterval is the minimum interval between two removals of the unused buckets.
+	sweepInterval = time.Minute
+)
+
+// Options configures a Limiter.
+type Options struct {
+	// QPS is the rate of the reconciliations of a logical cluster. It defaults to DefaultQPS.
+	QPS float64
+	// Burst is the number of reconciliations of a logical cluster admitted at once. It defaults to DefaultBurst.
+	Burst int
+}
+
+func (o Options) withDefaults() Options {
+	if o.QPS <= 0 {
+		o.QPS = DefaultQPS
+	}
+	if o.Burst <= 0 {
+		o.Burst = DefaultBurst
+	}
+	return o
+}
+
+var (
+	defaultOptionsMu sync.Mutex
+	defaultOptions   = Options{}.withDefaults()
+)
+
+// SetDefaultOptions sets the options of the limiters returned by NewLimiter.
+// It is meant to be called by main before the controllers are set up.
+func SetDefaultOptions(opts Options) {
+	defaultOptionsMu.Lock()
+	defer defaultOptionsMu.Unlock()
+	defaultOptions = opts.withDefaults()
+}
+
+// Limiter admits the reconciliations of a controller at the rate of the token bucket of their logical cluster.
+// A controller has its own Limiter.
+type Limiter struct {
+	opts Options
+	now  func() time.Time
+
+	mu sync.Mutex
+	// buckets are the token buckets per logical cluster.
+	buckets map[string]*bucket
+	// deferred are the times at which the requeued requests are admitted.
+	deferred  map[reconcile.Request]time.Time
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	lastUse time.Time
+}
+
+// NewLimiter returns a Limiter with the options set by SetDefaultOptions.
+func NewLimiter() *Limiter {
+	defaultOptionsMu.Lock()
+	defer defaultOptionsMu.Unlock()
+	return NewLimiterWithOptions(defaultOptions)
+}
+
+// NewLimiterWithOptions returns a Limiter with opts.
+func NewLimiterWithOptions(opts Options) *Limiter {
+	return &Limiter{
+		opts:     opts.withDefaults(),
+		now:      time.Now,
+		buckets:  map[string]*bucket{},
+		deferred: map[reconcile.Request]time.Time{},
+	}
+}
+
+// RateLimiter returns the rate limiter of the requests requeued after a failure, to be set in the
+// options of the controller. It only applies the per item exponential backoff.
+func (l *Limiter) RateLimiter() workqueue.RateLimiter {
+	return workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay)
+}
+
+// NewReconciler wraps r so that the requests are only reconciled when the token bucket of their logical
+// cluster has a token. The other requests are requeued at the time of the next token of the bucket.
+func (l *Limiter) NewReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		if delay := l.admit(req); delay > 0 {
+			return reconcile.Result{RequeueAfter: delay}, nil
+		}
+		return r.Reconcile(ctx, req)
+	})
+}
+
+// admit returns how long req has to wait before being reconciled. A request that has to wait holds the
+// token it waits for so that it is admitted when it comes back, and is not charged a second token.
+func (l *Limiter) admit(req reconcile.Request) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	if at, ok := l.deferred[req]; ok {
+		if now.Before(at) {
+			return at.Sub(now)
+		}
+		delete(l.deferred, req)
+		return 0
+	}
+
+	b, ok := l.buckets[req.ClusterName]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(l.opts.QPS), l.opts.Burst)}
+		l.buckets[req.ClusterName] = b
+	}
+	// The bucket is in use until the time of the token reserved for the request.
+	delay := b.limiter.ReserveN(now, 1).DelayFrom(now)
+	b.lastUse = now.Add(delay)
+	if delay > 0 {
+		l.deferred[req] = now.Add(delay)
+	}
+	return delay
+}
+
+// sweep removes the buckets that have been refilled since their last use, which are the same as new ones,
+// and the deferred requests that have not come back.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(float64(l.opts.Burst) / l.opts.QPS * float64(time.Second))
+	for clusterName, b := range l.buckets {
+		if now.Sub(b.lastUse) > refill {
+			delete(l.buckets, clusterName)
+		}
+	}
+	for req, at := range l.deferred {
+		if now.Sub(at) > sweepInterval {
+			delete(l.deferred, req)
+		}
+	}
+}
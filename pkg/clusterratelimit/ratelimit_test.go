@@ -0,0 +1,130 @@
+package clusterratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newTestLimiter returns a limiter with a fake clock and the reconciler counting the reconciliations per
+// logical cluster.
+func newTestLimiter(opts Options) (*Limiter, *time.Time, reconcile.Reconciler, map[string]int) {
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiterWithOptions(opts)
+	l.now = func() time.Time { return now }
+
+	reconciled := map[string]int{}
+	r := l.NewReconciler(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		reconciled[req.ClusterName]++
+		return reconcile.Result{}, nil
+	}))
+	return l, &now, r, reconciled
+}
+
+func request(clusterName, name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}, ClusterName: clusterName}
+}
+
+func TestThrottledClusterDoesNotDelayOthers(t *testing.T) {
+	_, _, r, reconciled := newTestLimiter(Options{QPS: 1, Burst: 2})
+
+	var delays []time.Duration
+	for _, name := range []string{"a", "b", "c", "d"} {
+		result, err := r.Reconcile(context.Background(), request("root:noisy", name))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		delays = append(delays, result.RequeueAfter)
+	}
+	if want := []time.Duration{0, 0, time.Second, 2 * time.Second}; !equal(delays, want) {
+		t.Errorf("expected the requests of the throttled cluster to be requeued after %v, got %v", want, delays)
+	}
+
+	result, err := r.Reconcile(context.Background(), request("root:quiet", "a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("expected the request of another cluster to be reconciled, got requeued after %v", result.RequeueAfter)
+	}
+	if reconciled["root:noisy"] != 2 || reconciled["root:quiet"] != 1 {
+		t.Errorf("expected 2 reconciliations of the throttled cluster and 1 of the other one, got %v", reconciled)
+	}
+}
+
+func TestDeferredRequestIsAdmittedWhenItComesBack(t *testing.T) {
+	_, now, r, reconciled := newTestLimiter(Options{QPS: 1, Burst: 1})
+
+	if _, err := r.Reconcile(context.Background(), request("root:tenant", "a")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, _ := r.Reconcile(context.Background(), request("root:tenant", "b"))
+	if result.RequeueAfter != time.Second {
+		t.Fatalf("expected the request to be requeued after 1s, got %v", result.RequeueAfter)
+	}
+
+	// The request coming back early, after an event, waits for the rest of its delay.
+	*now = now.Add(500 * time.Millisecond)
+	if result, _ := r.Reconcile(context.Background(), request("root:tenant", "b")); result.RequeueAfter != 500*time.Millisecond {
+		t.Errorf("expected the request to wait for the rest of its delay, got %v", result.RequeueAfter)
+	}
+
+	// The request coming back on time is admitted with the token it has reserved.
+	*now = now.Add(500 * time.Millisecond)
+	if result, _ := r.Reconcile(context.Background(), request("root:tenant", "b")); result.RequeueAfter != 0 {
+		t.Errorf("expected the request to be reconciled, got requeued after %v", result.RequeueAfter)
+	}
+	if reconciled["root:tenant"] != 2 {
+		t.Errorf("expected 2 reconciliations, got %d", reconciled["root:tenant"])
+	}
+
+	// The bucket is empty again, the token having been consumed by the deferred request.
+	if result, _ := r.Reconcile(context.Background(), request("root:tenant", "c")); result.RequeueAfter != time.Second {
+		t.Errorf("expected the request to be requeued after 1s, got %v", result.RequeueAfter)
+	}
+}
+
+func TestSweepRemovesRefilledBuckets(t *testing.T) {
+	l, now, r, _ := newTestLimiter(Options{QPS: 1, Burst: 10})
+
+	if _, err := r.Reconcile(context.Background(), request("root:tenant", "a")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	*now = now.Add(2 * sweepInterval)
+	if _, err := r.Reconcile(context.Background(), request("root:other", "a")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := l.buckets["root:tenant"]; ok {
+		t.Errorf("expected the refilled bucket to be removed")
+	}
+	if _, ok := l.buckets["root:other"]; !ok {
+		t.Errorf("expected the bucket in use to be kept")
+	}
+}
+
+func TestNewLimiterDefaultOptions(t *testing.T) {
+	t.Cleanup(func() { SetDefaultOptions(Options{}) })
+
+	if got := NewLimiter().opts; got != (Options{QPS: DefaultQPS, Burst: DefaultBurst}) {
+		t.Errorf("expected the default options, got %+v", got)
+	}
+	SetDefaultOptions(Options{QPS: 5})
+	if got := NewLimiter().opts; got != (Options{QPS: 5, Burst: DefaultBurst}) {
+		t.Errorf("expected the options set by SetDefaultOptions, got %+v", got)
+	}
+}
+
+func equal(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,5 @@
+// Package v1alpha1 contains the kcp specific component configuration of the managers scaffolded by
+// kcp-operator-sdk. KCPConfig is embedded inline in the ProjectConfig type scaffolded with --component-config,
+// along the configuration of controller-runtime, and is validated with it.
+// +kubebuilder:object:generate=true
+package v1alpha1
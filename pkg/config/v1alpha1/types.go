@@ -0,0 +1,125 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcpmanager"
+)
+
+// KCPConfig is the kcp specific configuration of a manager scaffolded by kcp-operator-sdk. It is embedded inline
+// in the component configuration of the project, which extends the one of controller-runtime.
+type KCPConfig struct {
+	// Mode selects whether the manager is cluster aware: auto when the server serves the apis.kcp.dev group,
+	// kcp to require a kcp server or kubernetes. It defaults to auto.
+	// +optional
+	Mode kcpmanager.Mode `json:"mode,omitempty"`
+
+	// APIExportName is the name of the APIExport whose virtual workspace the manager watches when connected to
+	// kcp. It defaults to the only APIExport of the workspace of the kubeconfig.
+	// +optional
+	APIExportName string `json:"apiExportName,omitempty"`
+
+	// LeaderElectionWorkspace is the path of the workspace of the leader election Lease, e.g. root:org:ws,
+	// when connected to kcp. It defaults to the workspace of the kubeconfig. The namespace and the name of
+	// the Lease are set in the leaderElection section.
+	// +optional
+	LeaderElectionWorkspace string `json:"leaderElectionWorkspace,omitempty"`
+
+	// DryRun turns the writes of the controllers into server-side dry-run requests, the changes are logged
+	// and counted per logical cluster.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Recording configures the recording of the objects read and written by the controllers.
+	// +optional
+	Recording RecordingConfig `json:"recording,omitempty"`
+
+	// Tracing configures the export of the traces, when the project is scaffolded with tracing.
+	// +optional
+	Tracing TracingConfig `json:"tracing,omitempty"`
+
+	// ClusterRateLimit configures the rate of the reconciliations of each logical cluster.
+	// +optional
+	ClusterRateLimit ClusterRateLimitConfig `json:"clusterRateLimit,omitempty"`
+
+	// Sharding configures the partitioning of the logical clusters between the replicas.
+	// +optional
+	Sharding ShardingConfig `json:"sharding,omitempty"`
+
+	// Tenants selects the logical clusters reconciled.
+	// +optional
+	Tenants TenantsConfig `json:"tenants,omitempty"`
+}
+
+// TracingConfig configures the export of the traces to an OpenTelemetry collector.
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP gRPC collector. Tracing is disabled when empty.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Insecure disables the transport security towards the collector.
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// ClusterRateLimitConfig configures the token buckets limiting the rate of the reconciliations of each logical
+// cluster.
+type ClusterRateLimitConfig struct {
+	// QPS is the rate of the reconciliations of a logical cluster per controller.
+	// +optional
+	QPS float32 `json:"qps,omitempty"`
+
+	// Burst is the number of reconciliations of a logical cluster admitted at once per controller.
+	// +optional
+	Burst int `json:"burst,omitempty"`
+}
+
+// ShardingConfig configures the partitioning of the logical clusters between the replicas. The Leases of the
+// replicas are stored with the ones of the leader election, which is disabled when sharding is enabled.
+type ShardingConfig struct {
+	// Enabled partitions the logical clusters between the replicas rather than electing a leader.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// LeaseDuration is the duration after which the Lease of a replica that has not renewed it expires.
+	// +optional
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+
+	// RenewPeriod is the period of the renewal of the Lease of a replica.
+	// +optional
+	RenewPeriod metav1.Duration `json:"renewPeriod,omitempty"`
+}
+
+// TenantsConfig selects the logical clusters reconciled, e.g. to roll out a new version of the controller to
+// some tenants first. A tenant also pauses the reconciliation of its objects with the tenants.kcp.io/paused
+// annotation on its APIBinding.
+type TenantsConfig struct {
+	// Allow are the names of the logical clusters reconciled. All the logical clusters are reconciled when empty.
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny are the names of the logical clusters that are not reconciled.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+
+	// Selector is the label selector of the APIBindings of the logical clusters reconciled, when connected to kcp.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+}
+
+// RecordingConfig configures the recording of the objects read and written by the controllers for some logical
+// clusters, which can be replayed offline. The data of the secrets is redacted.
+type RecordingConfig struct {
+	// Clusters are the names of the logical clusters recorded. Nothing is recorded when empty.
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// Dir is the directory the recordings are written to.
+	// +optional
+	Dir string `json:"dir,omitempty"`
+
+	// MaxEntries is the number of objects and writes recorded per logical cluster, after which the recording
+	// stops. It defaults to 10000.
+	// +optional
+	MaxEntries int `json:"maxEntries,omitempty"`
+}
@@ -0,0 +1,182 @@
+package v1alpha1
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+// projectConfig is the component configuration of a project, as scaffolded with --component-config.
+type projectConfig struct {
+	metav1.TypeMeta                        `json:",inline"`
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+	KCPConfig                              `json:",inline"`
+}
+
+func (c *projectConfig) DeepCopyObject() runtime.Object {
+	out := &projectConfig{TypeMeta: c.TypeMeta}
+	c.ControllerManagerConfigurationSpec.DeepCopyInto(&out.ControllerManagerConfigurationSpec)
+	c.KCPConfig.DeepCopyInto(&out.KCPConfig)
+	return out
+}
+
+func (c *projectConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	return c.ControllerManagerConfigurationSpec, nil
+}
+
+const configFile = `apiVersion: config.example.com/v1alpha1
+kind: ProjectConfig
+mode: kcp
+apiExportName: example
+metrics:
+  bindAddress: 127.0.0.1:8080
+leaderElection:
+  leaderElect: true
+  resourceName: 86f835c3.example.com
+  resourceNamespace: leases
+leaderElectionWorkspace: root:org:leases
+dryRun: true
+recording:
+  clusters:
+  - root:org:ws
+tracing:
+  endpoint: otel-collector:4317
+  insecure: true
+clusterRateLimit:
+  qps: 5
+  burst: 50
+sharding:
+  enabled: true
+  leaseDuration: 30s
+tenants:
+  deny:
+  - root:org:ws
+  selector: rollout=canary
+`
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "controller_manager_config.yaml")
+	if err := os.WriteFile(path, []byte(configFile), 0600); err != nil {
+		t.Fatalf("unable to write the configuration: %v", err)
+	}
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Group: "config.example.com", Version: "v1alpha1", Kind: "ProjectConfig"},
+		&projectConfig{})
+
+	var config projectConfig
+	options, err := ctrl.Options{Scheme: scheme}.AndFrom(ctrl.ConfigFile().AtPath(path).OfKind(&config))
+	if err != nil {
+		t.Fatalf("unable to load the configuration: %v", err)
+	}
+	if options.MetricsBindAddress != "127.0.0.1:8080" {
+		t.Errorf("expected the metrics bind address to be loaded, got %q", options.MetricsBindAddress)
+	}
+	if options.LeaderElectionNamespace != "leases" || options.LeaderElectionID != "86f835c3.example.com" {
+		t.Errorf("expected the lease leases/86f835c3.example.com, got %s/%s", options.LeaderElectionNamespace, options.LeaderElectionID)
+	}
+	if config.Mode != "kcp" || config.APIExportName != "example" {
+		t.Errorf("expected the kcp mode with the APIExport example, got %q and %q", config.Mode, config.APIExportName)
+	}
+	if config.LeaderElectionWorkspace != "root:org:leases" {
+		t.Errorf("expected the leader election workspace root:org:leases, got %q", config.LeaderElectionWorkspace)
+	}
+	if !config.DryRun {
+		t.Errorf("expected dry-run mode to be enabled")
+	}
+	if want := []string{"root:org:ws"}; !reflect.DeepEqual(config.Recording.Clusters, want) {
+		t.Errorf("expected the recorded logical clusters %v, got %v", want, config.Recording.Clusters)
+	}
+	if want := (TracingConfig{Endpoint: "otel-collector:4317", Insecure: true}); config.Tracing != want {
+		t.Errorf("expected tracing configuration %+v, got %+v", want, config.Tracing)
+	}
+	if want := (ClusterRateLimitConfig{QPS: 5, Burst: 50}); config.ClusterRateLimit != want {
+		t.Errorf("expected cluster rate limit configuration %+v, got %+v", want, config.ClusterRateLimit)
+	}
+	if !config.Sharding.Enabled || config.Sharding.LeaseDuration.Duration != 30*time.Second {
+		t.Errorf("expected sharding to be enabled with a lease duration of 30s, got %+v", config.Sharding)
+	}
+	if want := (TenantsConfig{Deny: []string{"root:org:ws"}, Selector: "rollout=canary"}); !reflect.DeepEqual(config.Tenants, want) {
+		t.Errorf("expected tenants configuration %+v, got %+v", want, config.Tenants)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config KCPConfig
+		// fields are the paths of the invalid fields.
+		fields []string
+	}{
+		{name: "empty"},
+		{
+			name: "valid",
+			config: KCPConfig{
+				Mode:                    "kubernetes",
+				APIExportName:           "example.com",
+				LeaderElectionWorkspace: "root:org:leases",
+				ClusterRateLimit:        ClusterRateLimitConfig{QPS: 5, Burst: 50},
+				Sharding: ShardingConfig{
+					LeaseDuration: metav1.Duration{Duration: 15 * time.Second},
+					RenewPeriod:   metav1.Duration{Duration: 5 * time.Second},
+				},
+				Tenants: TenantsConfig{Allow: []string{"root:org:ws"}, Deny: []string{"root:org:other"}, Selector: "rollout=canary"},
+			},
+		},
+		{name: "unknown mode", config: KCPConfig{Mode: "openshift"}, fields: []string{"mode"}},
+		{name: "invalid APIExport name", config: KCPConfig{APIExportName: "Example"}, fields: []string{"apiExportName"}},
+		{name: "invalid workspace", config: KCPConfig{LeaderElectionWorkspace: "root:Org"}, fields: []string{"leaderElectionWorkspace"}},
+		{
+			name:   "negative rate",
+			config: KCPConfig{ClusterRateLimit: ClusterRateLimitConfig{QPS: -1, Burst: -1}},
+			fields: []string{"clusterRateLimit.qps", "clusterRateLimit.burst"},
+		},
+		{
+			name:   "negative recording entries",
+			config: KCPConfig{Recording: RecordingConfig{MaxEntries: -1}},
+			fields: []string{"recording.maxEntries"},
+		},
+		{
+			name: "renew period longer than the lease",
+			config: KCPConfig{Sharding: ShardingConfig{
+				LeaseDuration: metav1.Duration{Duration: 5 * time.Second},
+				RenewPeriod:   metav1.Duration{Duration: 15 * time.Second},
+			}},
+			fields: []string{"sharding.renewPeriod"},
+		},
+		{
+			name:   "negative lease duration",
+			config: KCPConfig{Sharding: ShardingConfig{LeaseDuration: metav1.Duration{Duration: -time.Second}}},
+			fields: []string{"sharding.leaseDuration"},
+		},
+		{
+			name:   "allowed and denied tenant",
+			config: KCPConfig{Tenants: TenantsConfig{Allow: []string{"root:org:ws"}, Deny: []string{"root:org:ws"}}},
+			fields: []string{"tenants.allow[0]"},
+		},
+		{
+			name:   "invalid tenants",
+			config: KCPConfig{Tenants: TenantsConfig{Allow: []string{"root:Org"}, Deny: []string{"root:Org"}, Selector: "rollout in"}},
+			fields: []string{"tenants.deny[0]", "tenants.allow[0]", "tenants.allow[0]", "tenants.selector"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, err := range tt.config.Validate() {
+				fields = append(fields, err.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("expected the invalid fields %v, got %v", tt.fields, fields)
+			}
+		})
+	}
+}
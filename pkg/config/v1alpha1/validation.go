@@ -0,0 +1,100 @@
+package v1alpha1
+
+import (
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcpmanager"
+)
+
+// Validate returns the errors of the configuration. As the configuration is embedded inline, the paths of the
+// errors are relative to the root of the configuration file, and the component configuration of the project
+// appends the errors of its own settings.
+func (c *KCPConfig) Validate() field.ErrorList {
+	var errs field.ErrorList
+
+	switch c.Mode {
+	case "", kcpmanager.ModeAuto, kcpmanager.ModeKCP, kcpmanager.ModeKubernetes:
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("mode"), c.Mode,
+			[]string{string(kcpmanager.ModeAuto), string(kcpmanager.ModeKCP), string(kcpmanager.ModeKubernetes)}))
+	}
+
+	if c.APIExportName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(c.APIExportName) {
+			errs = append(errs, field.Invalid(field.NewPath("apiExportName"), c.APIExportName, msg))
+		}
+	}
+
+	if c.LeaderElectionWorkspace != "" && !logicalcluster.New(c.LeaderElectionWorkspace).IsValid() {
+		errs = append(errs, field.Invalid(field.NewPath("leaderElectionWorkspace"), c.LeaderElectionWorkspace,
+			"must be the path of a workspace, e.g. root:org:ws"))
+	}
+
+	errs = append(errs, c.ClusterRateLimit.validate(field.NewPath("clusterRateLimit"))...)
+	errs = append(errs, c.Recording.validate(field.NewPath("recording"))...)
+	errs = append(errs, c.Sharding.validate(field.NewPath("sharding"))...)
+	errs = append(errs, c.Tenants.validate(field.NewPath("tenants"))...)
+	return errs
+}
+
+func (c *ClusterRateLimitConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if c.QPS < 0 {
+		errs = append(errs, field.Invalid(path.Child("qps"), c.QPS, "must not be negative"))
+	}
+	if c.Burst < 0 {
+		errs = append(errs, field.Invalid(path.Child("burst"), c.Burst, "must not be negative"))
+	}
+	return errs
+}
+
+func (c *RecordingConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if c.MaxEntries < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxEntries"), c.MaxEntries, "must not be negative"))
+	}
+	return errs
+}
+
+func (c *ShardingConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if c.LeaseDuration.Duration < 0 {
+		errs = append(errs, field.Invalid(path.Child("leaseDuration"), c.LeaseDuration.Duration, "must not be negative"))
+	}
+	if c.RenewPeriod.Duration < 0 {
+		errs = append(errs, field.Invalid(path.Child("renewPeriod"), c.RenewPeriod.Duration, "must not be negative"))
+	}
+	if c.LeaseDuration.Duration > 0 && c.RenewPeriod.Duration >= c.LeaseDuration.Duration {
+		errs = append(errs, field.Invalid(path.Child("renewPeriod"), c.RenewPeriod.Duration,
+			"must be shorter than the lease duration"))
+	}
+	return errs
+}
+
+func (c *TenantsConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	denied := make(map[string]bool, len(c.Deny))
+	for i, name := range c.Deny {
+		if !logicalcluster.New(name).IsValid() {
+			errs = append(errs, field.Invalid(path.Child("deny").Index(i), name, "must be the name of a logical cluster"))
+		}
+		denied[name] = true
+	}
+	for i, name := range c.Allow {
+		if !logicalcluster.New(name).IsValid() {
+			errs = append(errs, field.Invalid(path.Child("allow").Index(i), name, "must be the name of a logical cluster"))
+		}
+		if denied[name] {
+			errs = append(errs, field.Invalid(path.Child("allow").Index(i), name, "must not be denied"))
+		}
+	}
+	if c.Selector != "" {
+		if _, err := labels.Parse(c.Selector); err != nil {
+			errs = append(errs, field.Invalid(path.Child("selector"), c.Selector, err.Error()))
+		}
+	}
+	return errs
+}
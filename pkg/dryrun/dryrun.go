@@ -0,0 +1,176 @@
+// Package dryrun lets a controller run against production tenants without changing their objects.
+//
+// The client returned by WrapClient performs the reads normally and turns the writes into server-side dry-run
+// requests: the server validates and admits them, and returns the objects as they would be, without persisting
+// them. Each change is logged with the logical cluster and the diff between the object in the cache and the object
+// returned by the server, and is counted by the kcp_controller_dry_run_changes_total metric, which is registered
+// with the metrics.Registry of controller-runtime. The metric is a clustermetrics.ClusterCounter: the logical
+// clusters with the most changes get their own cluster label and the other ones are aggregated, so that the number
+// of series is bounded.
+package dryrun
+
+import (
+	"context"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kcp-dev/logicalcluster/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+)
+
+// changesTotal counts the changes that would have been made.
+var changesTotal = clustermetrics.NewClusterCounter("kcp_controller_dry_run_changes_total",
+	"Total number of changes that would have been made in dry-run mode per logical cluster.",
+	[]string{"verb", "group", "kind"})
+
+func init() {
+	metrics.Registry.MustRegister(changesTotal)
+}
+
+// WrapClient returns a client performing the reads of c normally and the writes as server-side dry-run requests.
+// It is set up by kcpmanager.NewManager when kcpmanager.Options.DryRun is true.
+func WrapClient(c client.Client) client.Client {
+	return &dryRunClient{Client: c}
+}
+
+type dryRunClient struct {
+	client.Client
+}
+
+func (c *dryRunClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	record(ctx, c.Scheme(), "create", nil, obj)
+	return nil
+}
+
+func (c *dryRunClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	current := c.current(ctx, obj)
+	if err := c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	record(ctx, c.Scheme(), "update", current, obj)
+	return nil
+}
+
+func (c *dryRunClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	current := c.current(ctx, obj)
+	if err := c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	record(ctx, c.Scheme(), "patch", current, obj)
+	return nil
+}
+
+func (c *dryRunClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	record(ctx, c.Scheme(), "delete", obj, nil)
+	return nil
+}
+
+func (c *dryRunClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	if err := c.Client.DeleteAllOf(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	record(ctx, c.Scheme(), "deleteallof", obj, nil)
+	return nil
+}
+
+func (c *dryRunClient) Status() client.StatusWriter {
+	return &dryRunStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+// current returns the object in the cache with the key of obj, nil when it is not found.
+func (c *dryRunClient) current(ctx context.Context, obj client.Object) client.Object {
+	current, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return nil
+	}
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logf.FromContext(ctx).WithName("dryrun").Error(err, "unable to get the current object")
+		}
+		return nil
+	}
+	return current
+}
+
+type dryRunStatusWriter struct {
+	client.StatusWriter
+	client *dryRunClient
+}
+
+func (w *dryRunStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	current := w.client.current(ctx, obj)
+	if err := w.StatusWriter.Update(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	record(ctx, w.client.Scheme(), "updatestatus", current, obj)
+	return nil
+}
+
+func (w *dryRunStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	current := w.client.current(ctx, obj)
+	if err := w.StatusWriter.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	record(ctx, w.client.Scheme(), "patchstatus", current, obj)
+	return nil
+}
+
+// record logs the change from before to after, either of which is nil for creations and deletions, and counts it.
+// Updates and patches leaving the object unchanged are not changes.
+func record(ctx context.Context, scheme *runtime.Scheme, verb string, before, after client.Object) {
+	obj := after
+	if obj == nil {
+		obj = before
+	}
+	diff := Diff(before, after)
+	if diff == "" {
+		return
+	}
+
+	var gvk schema.GroupVersionKind
+	if scheme != nil {
+		gvk, _ = apiutil.GVKForObject(obj, scheme)
+	}
+	clusterName, ok := logicalcluster.ClusterFromContext(ctx)
+	if !ok {
+		clusterName = logicalcluster.From(obj)
+	}
+	changesTotal.Inc(clusterName.String(), verb, gvk.Group, gvk.Kind)
+	logf.FromContext(ctx).WithName("dryrun").Info("Dry-run change", "verb", verb, "clusterName", clusterName.String(),
+		"group", gvk.Group, "kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName(), "diff", diff)
+}
+
+// Diff returns the difference between the objects, either of which may be nil, ignoring the fields maintained
+// by the server on each write: the resource version and the managed fields. It returns an empty string when
+// the objects are equal.
+func Diff(before, after client.Object) string {
+	return cmp.Diff(content(before), content(after))
+}
+
+// content returns the fields of obj as a map, nil when obj is nil.
+func content(obj client.Object) map[string]interface{} {
+	if obj == nil {
+		return nil
+	}
+	fields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	unstructured.RemoveNestedField(fields, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(fields, "metadata", "managedFields")
+	return fields
+}
@@ -0,0 +1,119 @@
+package dryrun
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// changes returns the count of the changes of the ConfigMaps of root:org:ws with verb.
+func changes(t *testing.T, verb string) float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(changesTotal)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("unable to gather the metrics: %v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["cluster"] == "root:org:ws" && labels["verb"] == verb && labels["kind"] == "ConfigMap" {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestWrapClient(t *testing.T) {
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+		Data:       map[string]string{"key": "value"},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+	c := WrapClient(fakeClient)
+	ctx := logicalcluster.WithCluster(context.Background(), logicalcluster.New("root:org:ws"))
+
+	// The reads are performed normally.
+	obj := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(existing), obj); err != nil {
+		t.Fatalf("unable to get the object: %v", err)
+	}
+
+	// The writes are not persisted.
+	created := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "created", Namespace: "default"}}
+	if err := c.Create(ctx, created); err != nil {
+		t.Fatalf("unable to create the object: %v", err)
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(created), &corev1.ConfigMap{}); err == nil {
+		t.Errorf("expected the creation not to be persisted")
+	}
+	if got := changes(t, "create"); got != 1 {
+		t.Errorf("expected the creation to be recorded, got %v", got)
+	}
+
+	// An update leaving the object unchanged is not a change.
+	if err := c.Update(ctx, obj); err != nil {
+		t.Fatalf("unable to update the object: %v", err)
+	}
+	if got := changes(t, "update"); got != 0 {
+		t.Errorf("expected the update without change not to be recorded, got %v", got)
+	}
+
+	obj.Data["key"] = "changed"
+	if err := c.Update(ctx, obj); err != nil {
+		t.Fatalf("unable to update the object: %v", err)
+	}
+	if got := changes(t, "update"); got != 1 {
+		t.Errorf("expected the update to be recorded, got %v", got)
+	}
+	persisted := &corev1.ConfigMap{}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(existing), persisted); err != nil {
+		t.Fatalf("unable to get the object: %v", err)
+	}
+	if persisted.Data["key"] != "value" {
+		t.Errorf("expected the update not to be persisted, got %v", persisted.Data)
+	}
+
+	if err := c.Delete(ctx, existing); err != nil {
+		t.Fatalf("unable to delete the object: %v", err)
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(existing), &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the deletion not to be persisted, got %v", err)
+	}
+	if got := changes(t, "delete"); got != 1 {
+		t.Errorf("expected the deletion to be recorded, got %v", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "widget", ResourceVersion: "1"},
+		Data:       map[string]string{"key": "value"},
+	}
+	after := before.DeepCopy()
+	after.ResourceVersion = "2"
+	if diff := Diff(before, after); diff != "" {
+		t.Errorf("expected the resource version to be ignored, got %s", diff)
+	}
+
+	after.Data["key"] = "changed"
+	if diff := Diff(before, after); !strings.Contains(diff, "changed") {
+		t.Errorf("expected the diff to contain the changed value, got %s", diff)
+	}
+	if diff := Diff(nil, after); diff == "" {
+		t.Errorf("expected a diff for the creation")
+	}
+}
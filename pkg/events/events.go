@@ -0,0 +1,255 @@
+// Package events records the Kubernetes Events of the controllers in the workspaces of the objects they are about.
+//
+// The EventRecorder of the manager writes the events with the rest config of the manager, to a single workspace
+// or, when connected to kcp, to the virtual workspace of the APIExport, which does not serve them. The Recorder
+// returned by NewRecorder creates the events with the client of the manager instead, in the logical cluster of the
+// involved object, so that the tenants see them alongside their objects. When connected to kcp the APIExport must
+// claim the events, which is done by the golang plugin when a controller is created.
+//
+// The events repeated within AggregationWindow increment the count of the first one rather than creating new
+// events, like the EventRecorder of client-go.
+//
+// The events are written in the background, so that the reconciliations do not wait for the API server: the
+// Recorder is a manager.Runnable, to be added to the manager, writing the events queued in a buffer of BufferSize
+// events. The events are dropped when the buffer is full, e.g. when the API server does not respond.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// AggregationWindow is the period during which a repeated event increments the count of the first one.
+	AggregationWindow = 10 * time.Minute
+	// maxAggregatedEvents bounds the number of events remembered for their aggregation.
+	maxAggregatedEvents = 4096
+	// BufferSize is the number of events queued for writing, beyond which the events are dropped.
+	BufferSize = 1024
+	// writeTimeout bounds the time spent writing an event.
+	writeTimeout = 10 * time.Second
+)
+
+var (
+	_ record.EventRecorder           = &Recorder{}
+	_ manager.Runnable               = &Recorder{}
+	_ manager.LeaderElectionRunnable = &Recorder{}
+)
+
+// Recorder is a record.EventRecorder creating the events in the logical cluster of the involved objects.
+type Recorder struct {
+	client    client.Client
+	scheme    *runtime.Scheme
+	component string
+	now       func() time.Time
+	// queue holds the events until they are written by Start.
+	queue chan *queuedEvent
+
+	mu sync.Mutex
+	// recorded are the events recently created, by the fields that identify their repetitions.
+	recorded map[eventKey]*corev1.Event
+}
+
+// eventKey identifies the repetitions of an event.
+type eventKey struct {
+	cluster   logicalcluster.Name
+	uid       types.UID
+	eventType string
+	reason    string
+	message   string
+}
+
+// queuedEvent is an event waiting to be written.
+type queuedEvent struct {
+	ref         *corev1.ObjectReference
+	cluster     logicalcluster.Name
+	annotations map[string]string
+	eventType   string
+	reason      string
+	message     string
+	timestamp   time.Time
+}
+
+// NewRecorder returns a Recorder creating the events with c, which is the client of the manager, on behalf of
+// component, e.g. the name of the controller. The scheme gives the kinds of the involved objects. The events are
+// only written once the Recorder is started, usually by the manager it is added to.
+func NewRecorder(c client.Client, scheme *runtime.Scheme, component string) *Recorder {
+	return &Recorder{
+		client:    c,
+		scheme:    scheme,
+		component: component,
+		now:       time.Now,
+		queue:     make(chan *queuedEvent, BufferSize),
+		recorded:  make(map[eventKey]*corev1.Event),
+	}
+}
+
+// Start implements manager.Runnable. It writes the queued events until ctx is done.
+func (r *Recorder) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-r.queue:
+			r.write(ctx, e)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the events are written by the replicas running
+// the controllers, which are all of them when sharding is enabled.
+func (r *Recorder) NeedLeaderElection() bool {
+	return false
+}
+
+// Event implements record.EventRecorder.
+func (r *Recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.record(object, nil, eventtype, reason, message)
+}
+
+// Eventf implements record.EventRecorder.
+func (r *Recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.record(object, nil, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf implements record.EventRecorder.
+func (r *Recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.record(object, annotations, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// record queues the event, which is dropped when the queue is full. The errors are logged, the callers of an
+// EventRecorder do not expect any.
+func (r *Recorder) record(object runtime.Object, annotations map[string]string, eventtype, reason, message string) {
+	log := logf.Log.WithName("events")
+	ref, err := reference.GetReference(r.scheme, object)
+	if err != nil {
+		log.Error(err, "unable to get the reference of the object of the event", "reason", reason)
+		return
+	}
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		log.Error(err, "unable to get the metadata of the object of the event", "reason", reason)
+		return
+	}
+
+	e := &queuedEvent{
+		ref:         ref,
+		cluster:     logicalcluster.From(accessor),
+		annotations: annotations,
+		eventType:   eventtype,
+		reason:      reason,
+		message:     message,
+		timestamp:   r.now(),
+	}
+	select {
+	case r.queue <- e:
+	default:
+		log.V(1).Info("Dropping the event, the queue is full", "cluster", e.cluster.String(), "kind", ref.Kind,
+			"namespace", ref.Namespace, "name", ref.Name, "reason", reason)
+	}
+}
+
+// write creates the event, or increments the count of its last repetition.
+func (r *Recorder) write(ctx context.Context, e *queuedEvent) {
+	ref := e.ref
+	log := logf.Log.WithName("events").WithValues("cluster", e.cluster.String(), "kind", ref.Kind,
+		"namespace", ref.Namespace, "name", ref.Name, "reason", e.reason)
+
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	if !e.cluster.Empty() {
+		ctx = logicalcluster.WithCluster(ctx, e.cluster)
+	}
+
+	key := eventKey{cluster: e.cluster, uid: ref.UID, eventType: e.eventType, reason: e.reason, message: e.message}
+	now := e.timestamp
+	if original, event := r.repeat(key, now); event != nil {
+		err := r.client.Patch(ctx, event, client.MergeFrom(original))
+		if err == nil {
+			return
+		}
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "unable to update the event")
+			return
+		}
+		// The event expired, a new one is created.
+	}
+
+	namespace := ref.Namespace
+	if namespace == "" {
+		// The events of the cluster scoped objects are in the default namespace, as with client-go.
+		namespace = metav1.NamespaceDefault
+	}
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%v.%x", ref.Name, now.UnixNano()),
+			Namespace:   namespace,
+			Annotations: e.annotations,
+		},
+		InvolvedObject: *ref,
+		Reason:         e.reason,
+		Message:        e.message,
+		FirstTimestamp: metav1.NewTime(now),
+		LastTimestamp:  metav1.NewTime(now),
+		Count:          1,
+		Type:           e.eventType,
+		Source:         corev1.EventSource{Component: r.component},
+	}
+	if err := r.client.Create(ctx, event); err != nil {
+		log.Error(err, "unable to create the event")
+		return
+	}
+	r.remember(key, event)
+}
+
+// repeat increments the count of the event of key created within AggregationWindow, if any, and returns copies
+// of the event before and after the increment.
+func (r *Recorder) repeat(key eventKey, now time.Time) (*corev1.Event, *corev1.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event, ok := r.recorded[key]
+	if !ok {
+		return nil, nil
+	}
+	if now.Sub(event.FirstTimestamp.Time) > AggregationWindow {
+		delete(r.recorded, key)
+		return nil, nil
+	}
+	original := event.DeepCopy()
+	event.Count++
+	event.LastTimestamp = metav1.NewTime(now)
+	return original, event.DeepCopy()
+}
+
+// remember records event for the aggregation of its repetitions, forgetting the expired events when there are too
+// many of them.
+func (r *Recorder) remember(key eventKey, event *corev1.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.recorded) >= maxAggregatedEvents {
+		for k, e := range r.recorded {
+			if event.FirstTimestamp.Sub(e.FirstTimestamp.Time) > AggregationWindow {
+				delete(r.recorded, k)
+			}
+		}
+		if len(r.recorded) >= maxAggregatedEvents {
+			r.recorded = make(map[eventKey]*corev1.Event)
+		}
+	}
+	r.recorded[key] = event
+}
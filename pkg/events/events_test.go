@@ -0,0 +1,172 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// clusterClient records the logical clusters of the contexts of the writes.
+type clusterClient struct {
+	client.Client
+	clusters []logicalcluster.Name
+}
+
+func (c *clusterClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	cluster, _ := logicalcluster.ClusterFromContext(ctx)
+	c.clusters = append(c.clusters, cluster)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *clusterClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	cluster, _ := logicalcluster.ClusterFromContext(ctx)
+	c.clusters = append(c.clusters, cluster)
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// hangingClient blocks the writes until release is closed.
+type hangingClient struct {
+	client.Client
+	release chan struct{}
+}
+
+func (c *hangingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	<-c.release
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// flush writes the queued events.
+func flush(r *Recorder) {
+	for len(r.queue) > 0 {
+		r.write(context.Background(), <-r.queue)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	c := &clusterClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()}
+	r := NewRecorder(c, scheme.Scheme, "widget-controller")
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        "widget",
+		Namespace:   "ns",
+		UID:         "uid",
+		Annotations: map[string]string{logicalcluster.AnnotationKey: "root:org:ws"},
+	}}
+	r.Event(cm, corev1.EventTypeNormal, "Created", "created the widget")
+	now = now.Add(time.Minute)
+	r.Eventf(cm, corev1.EventTypeNormal, "Created", "created the %s", "widget")
+	r.Event(cm, corev1.EventTypeWarning, "Failed", "failed")
+	flush(r)
+
+	for _, cluster := range c.clusters {
+		if cluster != logicalcluster.New("root:org:ws") {
+			t.Errorf("expected the events to be written in the cluster of the object, got %q", cluster)
+		}
+	}
+	events := &corev1.EventList{}
+	if err := c.List(context.Background(), events, client.InNamespace("ns")); err != nil {
+		t.Fatalf("unable to list the events: %v", err)
+	}
+	if len(events.Items) != 2 {
+		t.Fatalf("expected the repeated event to be aggregated, got %d events", len(events.Items))
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Name != "widget" || event.Source.Component != "widget-controller" {
+			t.Errorf("unexpected involved object or source: %v, %v", event.InvolvedObject, event.Source)
+		}
+		switch event.Reason {
+		case "Created":
+			if event.Count != 2 || !event.LastTimestamp.Time.Equal(now) {
+				t.Errorf("expected the count and the last timestamp to be updated, got %d at %v", event.Count, event.LastTimestamp)
+			}
+		case "Failed":
+			if event.Count != 1 || event.Type != corev1.EventTypeWarning {
+				t.Errorf("unexpected event: %v", event)
+			}
+		}
+	}
+
+	// The repetitions after the aggregation window create a new event.
+	now = now.Add(AggregationWindow)
+	r.Event(cm, corev1.EventTypeNormal, "Created", "created the widget")
+	flush(r)
+	if err := c.List(context.Background(), events, client.InNamespace("ns")); err != nil {
+		t.Fatalf("unable to list the events: %v", err)
+	}
+	if len(events.Items) != 3 {
+		t.Errorf("expected a new event after the aggregation window, got %d events", len(events.Items))
+	}
+}
+
+func TestRecorderClusterScoped(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	r := NewRecorder(c, scheme.Scheme, "widget-controller")
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "widgets", UID: "uid"}}
+	r.Event(ns, corev1.EventTypeNormal, "Created", "created the namespace")
+	flush(r)
+
+	events := &corev1.EventList{}
+	if err := c.List(context.Background(), events, client.InNamespace(metav1.NamespaceDefault)); err != nil {
+		t.Fatalf("unable to list the events: %v", err)
+	}
+	if len(events.Items) != 1 || events.Items[0].InvolvedObject.Kind != "Namespace" {
+		t.Errorf("expected the event of the namespace in the default namespace, got %v", events.Items)
+	}
+}
+
+func TestRecorderDoesNotBlock(t *testing.T) {
+	c := &hangingClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), release: make(chan struct{})}
+	r := NewRecorder(c, scheme.Scheme, "widget-controller")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = r.Start(ctx)
+	}()
+
+	// The events beyond the buffer are dropped while the client hangs, rather than blocking the caller.
+	recorded := make(chan struct{})
+	go func() {
+		for i := 0; i < BufferSize+10; i++ {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "widget", Namespace: "ns", UID: types.UID(fmt.Sprint(i))}}
+			r.Event(cm, corev1.EventTypeNormal, "Created", "created the widget")
+		}
+		close(recorded)
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected Event not to block while the client hangs")
+	}
+
+	// The queued events are written once the client responds.
+	close(c.release)
+	events := &corev1.EventList{}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if err := c.List(context.Background(), events, client.InNamespace("ns")); err != nil {
+			t.Fatalf("unable to list the events: %v", err)
+		}
+		if len(events.Items) > 0 && len(r.queue) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the queued events to be written, got %d events", len(events.Items))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(events.Items) > BufferSize+1 {
+		t.Errorf("expected the events beyond the buffer to be dropped, got %d events", len(events.Items))
+	}
+}
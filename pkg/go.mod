@@ -0,0 +1,26 @@
+module github.com/fgiloux/kcp-operator-sdk/pkg
+
+go 1.19
+
+require (
+	github.com/go-logr/logr v1.2.0
+	github.com/google/go-cmp v0.5.8
+	github.com/kcp-dev/kcp/pkg/apis v0.9.1
+	github.com/kcp-dev/logicalcluster/v2 v2.0.0-alpha.1
+	github.com/prometheus/client_golang v1.12.2
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.35.0
+	go.opentelemetry.io/otel v1.10.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.10.0
+	go.opentelemetry.io/otel/sdk v1.10.0
+	go.opentelemetry.io/otel/trace v1.10.0
+	go.opentelemetry.io/proto/otlp v0.19.0
+	golang.org/x/time v0.0.0-20220210224613-90d013bbcef8
+	google.golang.org/grpc v1.46.2
+	k8s.io/api v0.24.2
+	k8s.io/apimachinery v0.24.2
+	k8s.io/client-go v0.24.2
+	k8s.io/utils v0.0.0-20220210201930-3a6ce19ff2f9
+	sigs.k8s.io/controller-runtime v0.11.2
+)
+
+replace sigs.k8s.io/controller-runtime v0.11.2 => github.com/kcp-dev/controller-runtime v0.12.2-0.20221006162808-d4b60cec23b4
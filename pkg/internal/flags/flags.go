@@ -0,0 +1,20 @@
+// Package flags has the flag.Values shared by the options of the packages.
+package flags
+
+import "strings"
+
+// List is a flag.Value of comma separated strings, which can be repeated.
+type List []string
+
+func (v *List) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *List) Set(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v = append(*v, item)
+		}
+	}
+	return nil
+}
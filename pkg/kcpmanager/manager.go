@@ -0,0 +1,388 @@
+// Package kcpmanager creates the controller manager of a project scaffolded by kcp-operator-sdk.
+//
+// When the server serves the apis.kcp.dev group the manager is cluster aware and watches the virtual
+// workspace of the APIExport of the controller. Otherwise a standard manager is created, which lets the
+// same binary run against a plain Kubernetes cluster.
+package kcpmanager
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/kcp"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/claims"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/dryrun"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/recording"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/workspaces"
+)
+
+// inClusterNamespacePath is the file with the namespace of the pod.
+const inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Mode selects whether the manager is cluster aware.
+type Mode string
+
+const (
+	// ModeAuto creates a cluster aware manager when the server serves the apis.kcp.dev group and a standard
+	// manager otherwise. It is the default mode.
+	ModeAuto Mode = "auto"
+	// ModeKCP creates a cluster aware manager and fails when the server does not serve the apis.kcp.dev group.
+	ModeKCP Mode = "kcp"
+	// ModeKubernetes creates a standard manager without looking up the apis.kcp.dev group.
+	ModeKubernetes Mode = "kubernetes"
+)
+
+// Options configures the manager.
+type Options struct {
+	// RestConfig is the configuration to connect to kcp or to the Kubernetes cluster.
+	// It defaults to ctrl.GetConfig().
+	RestConfig *rest.Config
+	// APIExportName is the name of the APIExport of the controller. When empty the APIExport
+	// is looked up and there needs to be exactly one in the workspace of RestConfig.
+	APIExportName string
+	// Mode selects whether the manager is cluster aware. It defaults to ModeAuto.
+	Mode Mode
+	// Manager are the options of the manager. When connected to kcp, Manager.LeaderElectionConfig
+	// defaults to RestConfig, pointed to LeaderElectionWorkspace when set, as leases cannot be stored
+	// through the virtual workspace.
+	Manager ctrl.Options
+	// LeaderElectionWorkspace is the path of the workspace of the leader election Lease, e.g. root:org:ws,
+	// when connected to kcp. It defaults to the workspace of RestConfig.
+	LeaderElectionWorkspace string
+	// WrapClient, when not nil, wraps the client of the manager, e.g. to instrument it. The client it is passed
+	// is created by Manager.NewClient, defaulting to the cluster aware client when connected to kcp.
+	WrapClient func(client.Client) client.Client
+	// DryRun turns the writes of the client of the manager into server-side dry-run requests, see
+	// dryrun.WrapClient. The client is wrapped before being passed to WrapClient.
+	DryRun bool
+	// Recording records the objects read and written through the client of the manager for the logical clusters
+	// of Recording.Clusters, see recording.Recorder. The Recorder is added to the manager when Recording.Clusters
+	// is not empty.
+	Recording recording.Options
+	// Sharding, when not nil, partitions the logical clusters between the replicas rather than electing a leader.
+	// The Leases of the replicas are stored with the ones of the leader election: Sharding.Name defaults to
+	// Manager.LeaderElectionID and Sharding.Namespace to Manager.LeaderElectionNamespace, or to the namespace
+	// of the pod. The Sharder is set as the default one, see sharding.SetDefault.
+	Sharding *sharding.Options
+	// Tenants selects the logical clusters reconciled. When connected to kcp, the APIBindings of the logical
+	// clusters are read from the virtual workspace of the APIExport, to honor Tenants.Selector and the
+	// tenants.PausedAnnotation. The Filter is set as the default one, see tenants.SetDefault.
+	Tenants tenants.Options
+	// WorkspaceLookup looks up the paths of the workspaces of the logical clusters. It defaults to
+	// workspaces.Identity, the name of a logical cluster being the path of its workspace with kcp v0.9. The Lookup
+	// is set as the default one, see workspaces.SetDefault.
+	WorkspaceLookup workspaces.Lookup
+}
+
+// NewManager returns a cluster aware manager watching the virtual workspace of the APIExport
+// when connected to kcp and a standard manager otherwise.
+func NewManager(ctx context.Context, opts Options) (ctrl.Manager, error) {
+	log := logf.FromContext(ctx).WithName("kcpmanager").WithValues("api-export-name", opts.APIExportName)
+
+	restConfig := opts.RestConfig
+	if restConfig == nil {
+		var err error
+		if restConfig, err = ctrl.GetConfig(); err != nil {
+			return nil, fmt.Errorf("error loading the configuration: %w", err)
+		}
+	}
+
+	kcpAPIsPresent, err := kcpMode(restConfig, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if !kcpAPIsPresent {
+		log.Info("Not connected to kcp - creating standard manager", "mode", opts.Mode)
+		mgrOpts := opts.Manager
+		recorder := newRecorder(opts.Recording)
+		mgrOpts.NewClient = wrapNewClient(mgrOpts.NewClient, cluster.DefaultNewClient, clientWrapper(opts, recorder))
+		disableLeaderElection(log, &mgrOpts, opts.Sharding)
+		mgr, err := ctrl.NewManager(restConfig, mgrOpts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create manager: %w", err)
+		}
+		if err := addRecorder(mgr, recorder); err != nil {
+			return nil, err
+		}
+		if err := addSharder(mgr, restConfig, mgrOpts, opts.Sharding); err != nil {
+			return nil, err
+		}
+		if err := setTenantFilter(nil, opts.Tenants); err != nil {
+			return nil, err
+		}
+		claims.SetDefault(claims.NewChecker(nil))
+		workspaces.SetDefault(opts.WorkspaceLookup)
+		return mgr, nil
+	}
+
+	log.Info("Looking up virtual workspace URL")
+	cfg, err := RestConfigForAPIExport(ctx, restConfig, opts.APIExportName)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up virtual workspace URL: %w", err)
+	}
+	log.Info("Using virtual workspace URL", "url", cfg.Host)
+
+	mgrOpts := opts.Manager
+	if mgrOpts.LeaderElectionConfig == nil {
+		if mgrOpts.LeaderElectionConfig, err = ConfigForWorkspace(restConfig, opts.LeaderElectionWorkspace); err != nil {
+			return nil, fmt.Errorf("error configuring the leader election: %w", err)
+		}
+	}
+	recorder := newRecorder(opts.Recording)
+	mgrOpts.NewClient = wrapNewClient(mgrOpts.NewClient, kcp.NewClusterAwareClient, clientWrapper(opts, recorder))
+	disableLeaderElection(log, &mgrOpts, opts.Sharding)
+	mgr, err := kcp.NewClusterAwareManager(cfg, mgrOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cluster aware manager: %w", err)
+	}
+	if err := addRecorder(mgr, recorder); err != nil {
+		return nil, err
+	}
+	if err := addSharder(mgr, mgrOpts.LeaderElectionConfig, mgrOpts, opts.Sharding); err != nil {
+		return nil, err
+	}
+	if err := apisv1alpha1.AddToScheme(mgr.GetScheme()); err != nil {
+		return nil, fmt.Errorf("error adding apis.kcp.dev/v1alpha1 to scheme: %w", err)
+	}
+	if err := setTenantFilter(mgr.GetCache(), opts.Tenants); err != nil {
+		return nil, err
+	}
+	// The permission claims accepted by the logical clusters are read from their APIBindings, see claims.Check.
+	claims.SetDefault(claims.NewChecker(mgr.GetCache()))
+	workspaces.SetDefault(opts.WorkspaceLookup)
+	return mgr, nil
+}
+
+// kcpMode returns whether the manager is cluster aware in mode, looking up the apis.kcp.dev group when needed.
+func kcpMode(restConfig *rest.Config, mode Mode) (bool, error) {
+	switch mode {
+	case ModeKubernetes:
+		return false, nil
+	case "", ModeAuto, ModeKCP:
+	default:
+		return false, fmt.Errorf("unknown mode %q", mode)
+	}
+	kcpAPIsPresent, err := KCPAPIsGroupPresent(restConfig)
+	if err != nil {
+		return false, fmt.Errorf("error looking up the apis.kcp.dev group: %w", err)
+	}
+	if mode == ModeKCP && !kcpAPIsPresent {
+		return false, fmt.Errorf("the apis.kcp.dev group is not present, the server is not a kcp server")
+	}
+	return kcpAPIsPresent, nil
+}
+
+// disableLeaderElection disables the leader election when the logical clusters are partitioned between the replicas.
+func disableLeaderElection(log logr.Logger, mgrOpts *ctrl.Options, shardingOpts *sharding.Options) {
+	if shardingOpts != nil && mgrOpts.LeaderElection {
+		log.Info("Sharding is enabled - disabling leader election")
+		mgrOpts.LeaderElection = false
+	}
+}
+
+// addSharder adds a Sharder storing the Leases through leaseConfig to mgr and sets it as the default one.
+func addSharder(mgr ctrl.Manager, leaseConfig *rest.Config, mgrOpts ctrl.Options, shardingOpts *sharding.Options) error {
+	if shardingOpts == nil {
+		return nil
+	}
+	o := *shardingOpts
+	shardingOpts = &o
+	if shardingOpts.Name == "" {
+		shardingOpts.Name = mgrOpts.LeaderElectionID
+	}
+	if shardingOpts.Namespace == "" {
+		shardingOpts.Namespace = mgrOpts.LeaderElectionNamespace
+	}
+	if shardingOpts.Namespace == "" {
+		shardingOpts.Namespace = "default"
+		if namespace, err := os.ReadFile(inClusterNamespacePath); err == nil {
+			shardingOpts.Namespace = strings.TrimSpace(string(namespace))
+		}
+	}
+
+	sharder, err := sharding.New(leaseConfig, *shardingOpts)
+	if err != nil {
+		return fmt.Errorf("unable to create the sharder: %w", err)
+	}
+	if err := mgr.Add(sharder); err != nil {
+		return fmt.Errorf("unable to add the sharder to the manager: %w", err)
+	}
+	sharding.SetDefault(sharder)
+	return nil
+}
+
+// setTenantFilter sets a Filter reading the APIBindings from reader, nil when not connected to kcp, as the default one.
+func setTenantFilter(reader client.Reader, tenantsOpts tenants.Options) error {
+	filter, err := tenants.New(reader, tenantsOpts)
+	if err != nil {
+		return fmt.Errorf("unable to create the tenant filter: %w", err)
+	}
+	tenants.SetDefault(filter)
+	return nil
+}
+
+// newRecorder returns the Recorder of recordingOpts, nil when no logical cluster is recorded.
+func newRecorder(recordingOpts recording.Options) *recording.Recorder {
+	if len(recordingOpts.Clusters) == 0 {
+		return nil
+	}
+	return recording.NewRecorder(recordingOpts)
+}
+
+// addRecorder adds recorder, when not nil, to mgr so that the recordings are written.
+func addRecorder(mgr ctrl.Manager, recorder *recording.Recorder) error {
+	if recorder == nil {
+		return nil
+	}
+	if err := mgr.Add(recorder); err != nil {
+		return fmt.Errorf("unable to add the recorder to the manager: %w", err)
+	}
+	return nil
+}
+
+// clientWrapper returns the function wrapping the client of the manager, nil when it is not wrapped. The dry-run
+// client is the innermost one, so that the writes are recorded and instrumented as they are sent to the server.
+func clientWrapper(opts Options, recorder *recording.Recorder) func(client.Client) client.Client {
+	var wrappers []func(client.Client) client.Client
+	if opts.DryRun {
+		wrappers = append(wrappers, dryrun.WrapClient)
+	}
+	if recorder != nil {
+		wrappers = append(wrappers, recorder.WrapClient)
+	}
+	if opts.WrapClient != nil {
+		wrappers = append(wrappers, opts.WrapClient)
+	}
+	if len(wrappers) == 0 {
+		return nil
+	}
+	return func(c client.Client) client.Client {
+		for _, wrap := range wrappers {
+			c = wrap(c)
+		}
+		return c
+	}
+}
+
+// wrapNewClient returns newClient, defaulting to defaultNewClient, wrapped with wrap when not nil.
+func wrapNewClient(newClient, defaultNewClient cluster.NewClientFunc, wrap func(client.Client) client.Client) cluster.NewClientFunc {
+	if newClient == nil {
+		newClient = defaultNewClient
+	}
+	if wrap == nil {
+		return newClient
+	}
+	return func(cache cache.Cache, config *rest.Config, options client.Options, uncachedObjects ...client.Object) (client.Client, error) {
+		c, err := newClient(cache, config, options, uncachedObjects...)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(c), nil
+	}
+}
+
+// RestConfigForAPIExport returns a *rest.Config properly configured to communicate with the endpoint for the
+// APIExport's virtual workspace. When apiExportName is empty there needs to be exactly one APIExport.
+func RestConfigForAPIExport(ctx context.Context, cfg *rest.Config, apiExportName string) (*rest.Config, error) {
+	scheme := runtime.NewScheme()
+	if err := apisv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("error adding apis.kcp.dev/v1alpha1 to scheme: %w", err)
+	}
+
+	apiExportClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("error creating APIExport client: %w", err)
+	}
+
+	var apiExport apisv1alpha1.APIExport
+
+	if apiExportName != "" {
+		if err := apiExportClient.Get(ctx, types.NamespacedName{Name: apiExportName}, &apiExport); err != nil {
+			return nil, fmt.Errorf("error getting APIExport %q: %w", apiExportName, err)
+		}
+	} else {
+		logf.FromContext(ctx).WithName("kcpmanager").Info("api-export-name is empty - listing")
+		exports := &apisv1alpha1.APIExportList{}
+		if err := apiExportClient.List(ctx, exports); err != nil {
+			return nil, fmt.Errorf("error listing APIExports: %w", err)
+		}
+		if len(exports.Items) == 0 {
+			return nil, fmt.Errorf("no APIExport found")
+		}
+		if len(exports.Items) > 1 {
+			return nil, fmt.Errorf("more than one APIExport found")
+		}
+		apiExport = exports.Items[0]
+	}
+
+	if len(apiExport.Status.VirtualWorkspaces) < 1 {
+		return nil, fmt.Errorf("APIExport %q status.virtualWorkspaces is empty", apiExport.Name)
+	}
+
+	cfg = rest.CopyConfig(cfg)
+	// TODO(ncdc): sharding support
+	cfg.Host = apiExport.Status.VirtualWorkspaces[0].URL
+
+	return cfg, nil
+}
+
+// ConfigForWorkspace returns a copy of cfg pointing to the workspace with the path, e.g. root:org:ws, in place of
+// the workspace of cfg. It returns cfg when path is empty.
+func ConfigForWorkspace(cfg *rest.Config, path string) (*rest.Config, error) {
+	if path == "" {
+		return cfg, nil
+	}
+	host, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the host %q: %w", cfg.Host, err)
+	}
+	if i := strings.Index(host.Path, "/clusters/"); i >= 0 {
+		host.Path = host.Path[:i]
+	}
+	host.Path = strings.TrimSuffix(host.Path, "/") + "/clusters/" + path
+
+	cfg = rest.CopyConfig(cfg)
+	cfg.Host = host.String()
+	return cfg, nil
+}
+
+// KCPAPIsGroupPresent returns true if the server serves the apis.kcp.dev group, i.e. if it is a kcp server.
+func KCPAPIsGroupPresent(restConfig *rest.Config) (bool, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return false, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	apiGroupList, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return false, fmt.Errorf("failed to get server groups: %w", err)
+	}
+
+	for _, group := range apiGroupList.Groups {
+		if group.Name == apisv1alpha1.SchemeGroupVersion.Group {
+			for _, version := range group.Versions {
+				if version.Version == apisv1alpha1.SchemeGroupVersion.Version {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
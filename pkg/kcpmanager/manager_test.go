@@ -0,0 +1,319 @@
+package kcpmanager
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/dryrun"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcptest"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/recording"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/workspaces"
+)
+
+func TestNewManager(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       kcptest.Options
+		mode       Mode
+		apiExports []string
+		// wantHost is the APIExport whose virtual workspace the manager connects to,
+		// the manager connects to the server itself when empty.
+		wantHost string
+		wantErr  bool
+	}{
+		{name: "kcp", apiExports: []string{"a"}, wantHost: "a"},
+		{name: "kubernetes", opts: kcptest.Options{WithoutKCPAPIs: true}},
+		{name: "kcp without APIExport", wantErr: true},
+		{name: "discovery error", opts: kcptest.Options{DiscoveryStatus: http.StatusInternalServerError}, wantErr: true},
+		{name: "kcp mode", mode: ModeKCP, apiExports: []string{"a"}, wantHost: "a"},
+		{name: "kcp mode on kubernetes", opts: kcptest.Options{WithoutKCPAPIs: true}, mode: ModeKCP, wantErr: true},
+		{name: "kubernetes mode on kcp", mode: ModeKubernetes, apiExports: []string{"a"}},
+		{name: "unknown mode", mode: "openshift", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := kcptest.NewServer(t, tt.opts)
+			for _, name := range tt.apiExports {
+				s.AddAPIExports(s.NewAPIExport(name))
+			}
+
+			mgr, err := NewManager(context.Background(), Options{
+				RestConfig: s.RestConfig(),
+				Mode:       tt.mode,
+				Manager:    ctrl.Options{MetricsBindAddress: "0"},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			want := s.URL
+			if tt.wantHost != "" {
+				want = s.VirtualWorkspaceURL(tt.wantHost)
+			}
+			if host := mgr.GetConfig().Host; host != want {
+				t.Errorf("expected the manager to connect to %s, got %s", want, host)
+			}
+		})
+	}
+}
+
+// wrappedClient marks the clients wrapped by Options.WrapClient.
+type wrappedClient struct {
+	client.Client
+}
+
+func TestNewManagerWrapClient(t *testing.T) {
+	s := kcptest.NewServer(t, kcptest.Options{WithoutKCPAPIs: true})
+
+	mgr, err := NewManager(context.Background(), Options{
+		RestConfig: s.RestConfig(),
+		Manager:    ctrl.Options{MetricsBindAddress: "0"},
+		WrapClient: func(c client.Client) client.Client { return &wrappedClient{Client: c} },
+	})
+	if err != nil {
+		t.Fatalf("unable to create the manager: %v", err)
+	}
+	if _, ok := mgr.GetClient().(*wrappedClient); !ok {
+		t.Errorf("expected the client of the manager to be wrapped, got %T", mgr.GetClient())
+	}
+}
+
+func TestNewManagerDryRun(t *testing.T) {
+	s := kcptest.NewServer(t, kcptest.Options{WithoutKCPAPIs: true})
+
+	mgr, err := NewManager(context.Background(), Options{
+		RestConfig: s.RestConfig(),
+		Manager:    ctrl.Options{MetricsBindAddress: "0"},
+		WrapClient: func(c client.Client) client.Client { return &wrappedClient{Client: c} },
+		DryRun:     true,
+	})
+	if err != nil {
+		t.Fatalf("unable to create the manager: %v", err)
+	}
+	wrapped, ok := mgr.GetClient().(*wrappedClient)
+	if !ok {
+		t.Fatalf("expected the client of the manager to be wrapped, got %T", mgr.GetClient())
+	}
+	// The dry-run client is wrapped by WrapClient.
+	if reflect.TypeOf(wrapped.Client) != reflect.TypeOf(dryrun.WrapClient(nil)) {
+		t.Errorf("expected the dry-run client to be wrapped, got %T", wrapped.Client)
+	}
+}
+
+func TestNewManagerRecording(t *testing.T) {
+	s := kcptest.NewServer(t, kcptest.Options{WithoutKCPAPIs: true})
+
+	mgr, err := NewManager(context.Background(), Options{
+		RestConfig: s.RestConfig(),
+		Manager:    ctrl.Options{MetricsBindAddress: "0"},
+		WrapClient: func(c client.Client) client.Client { return &wrappedClient{Client: c} },
+		Recording:  recording.Options{Clusters: []string{"root:org:ws"}, Dir: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("unable to create the manager: %v", err)
+	}
+	wrapped, ok := mgr.GetClient().(*wrappedClient)
+	if !ok {
+		t.Fatalf("expected the client of the manager to be wrapped, got %T", mgr.GetClient())
+	}
+	// The recording client is wrapped by WrapClient.
+	if recordingClient := recording.NewRecorder(recording.Options{}).WrapClient(nil); reflect.TypeOf(wrapped.Client) != reflect.TypeOf(recordingClient) {
+		t.Errorf("expected the recording client to be wrapped, got %T", wrapped.Client)
+	}
+}
+
+func TestKCPAPIsGroupPresent(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    kcptest.Options
+		want    bool
+		wantErr bool
+	}{
+		{name: "kcp", want: true},
+		{name: "kubernetes", opts: kcptest.Options{WithoutKCPAPIs: true}},
+		{name: "discovery error", opts: kcptest.Options{DiscoveryStatus: http.StatusInternalServerError}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := kcptest.NewServer(t, tt.opts)
+			got, err := KCPAPIsGroupPresent(s.RestConfig())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRestConfigForAPIExport(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          kcptest.Options
+		apiExports    []string
+		apiExportName string
+		// noVirtualWorkspace removes the virtual workspaces from the status of the APIExports.
+		noVirtualWorkspace bool
+		wantHost           string
+		wantErr            bool
+	}{
+		{name: "named export", apiExports: []string{"a", "b"}, apiExportName: "b", wantHost: "b"},
+		{name: "missing named export", apiExports: []string{"a"}, apiExportName: "b", wantErr: true},
+		{name: "single export", apiExports: []string{"a"}, wantHost: "a"},
+		{name: "no export", wantErr: true},
+		{name: "multiple exports", apiExports: []string{"a", "b"}, wantErr: true},
+		{name: "empty virtual workspaces", apiExports: []string{"a"}, apiExportName: "a", noVirtualWorkspace: true, wantErr: true},
+		{name: "server error", opts: kcptest.Options{APIExportStatus: http.StatusInternalServerError}, apiExports: []string{"a"}, apiExportName: "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := kcptest.NewServer(t, tt.opts)
+			for _, name := range tt.apiExports {
+				apiExport := s.NewAPIExport(name)
+				if tt.noVirtualWorkspace {
+					apiExport.Status.VirtualWorkspaces = nil
+				}
+				s.AddAPIExports(apiExport)
+			}
+
+			restConfig := s.RestConfig()
+			cfg, err := RestConfigForAPIExport(context.Background(), restConfig, tt.apiExportName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if want := s.VirtualWorkspaceURL(tt.wantHost); cfg.Host != want {
+				t.Errorf("expected host %s, got %s", want, cfg.Host)
+			}
+			if restConfig.Host != s.URL {
+				t.Errorf("the configuration passed in was modified, host is %s", restConfig.Host)
+			}
+		})
+	}
+}
+
+func TestNewManagerSharding(t *testing.T) {
+	s := kcptest.NewServer(t, kcptest.Options{})
+	s.AddAPIExports(s.NewAPIExport("a"))
+	t.Cleanup(func() { sharding.SetDefault(nil) })
+
+	if _, err := NewManager(context.Background(), Options{
+		RestConfig: s.RestConfig(),
+		Manager: ctrl.Options{
+			MetricsBindAddress: "0",
+			LeaderElection:     true,
+			LeaderElectionID:   "86f835c3.example.com",
+		},
+		Sharding: &sharding.Options{Namespace: "system"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The sharder is set as the default one and does not own any logical cluster before holding its Lease.
+	r := sharding.NewReconciler(reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+		t.Errorf("expected the request to be skipped")
+		return reconcile.Result{}, nil
+	}))
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{ClusterName: "root:org:ws"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewManagerTenants(t *testing.T) {
+	s := kcptest.NewServer(t, kcptest.Options{WithoutKCPAPIs: true})
+	t.Cleanup(func() { tenants.SetDefault(nil) })
+
+	if _, err := NewManager(context.Background(), Options{
+		RestConfig: s.RestConfig(),
+		Manager:    ctrl.Options{MetricsBindAddress: "0"},
+		Tenants:    tenants.Options{Selector: "rollout in (canary"},
+	}); err == nil {
+		t.Errorf("expected an error for the invalid label selector")
+	}
+
+	if _, err := NewManager(context.Background(), Options{
+		RestConfig: s.RestConfig(),
+		Manager:    ctrl.Options{MetricsBindAddress: "0"},
+		Tenants:    tenants.Options{Deny: []string{"root:org:ws"}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The filter is set as the default one.
+	denied := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{logicalcluster.AnnotationKey: "root:org:ws"},
+	}}
+	if tenants.Predicate().Generic(event.GenericEvent{Object: denied}) {
+		t.Errorf("expected the events of the denied logical cluster to be dropped")
+	}
+}
+
+func TestNewManagerWorkspaceLookup(t *testing.T) {
+	t.Cleanup(func() { workspaces.SetDefault(nil) })
+	lookup := func(_ context.Context, clusterName logicalcluster.Name) (logicalcluster.Name, error) {
+		return logicalcluster.New("root:org:" + clusterName.String()), nil
+	}
+
+	for _, opts := range []kcptest.Options{{}, {WithoutKCPAPIs: true}} {
+		s := kcptest.NewServer(t, opts)
+		s.AddAPIExports(s.NewAPIExport("a"))
+
+		if _, err := NewManager(context.Background(), Options{
+			RestConfig:      s.RestConfig(),
+			Manager:         ctrl.Options{MetricsBindAddress: "0"},
+			WorkspaceLookup: lookup,
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The lookup is set as the default one.
+		path, err := workspaces.Path(context.Background(), logicalcluster.New("2a8f0c"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if path.String() != "root:org:2a8f0c" {
+			t.Errorf("expected the path to be looked up with the workspace lookup, got %s", path)
+		}
+	}
+}
+
+func TestConfigForWorkspace(t *testing.T) {
+	tests := []struct {
+		host, path, want string
+	}{
+		{host: "https://kcp:6443/clusters/root:org:ws", path: "root:org:leases", want: "https://kcp:6443/clusters/root:org:leases"},
+		{host: "https://kcp:6443", path: "root:org:leases", want: "https://kcp:6443/clusters/root:org:leases"},
+		{host: "https://proxy/kcp/clusters/root:org:ws", path: "root:leases", want: "https://proxy/kcp/clusters/root:leases"},
+		{host: "https://kcp:6443/clusters/root:org:ws", want: "https://kcp:6443/clusters/root:org:ws"},
+	}
+	for _, tt := range tests {
+		cfg, err := ConfigForWorkspace(&rest.Config{Host: tt.host}, tt.path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Host != tt.want {
+			t.Errorf("expected %s in workspace %q to be %s, got %s", tt.host, tt.path, tt.want, cfg.Host)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Package kcptest provides an httptest based stand-in of a kcp server for unit testing the startup logic
+// of a controller, which looks up the virtual workspace of its APIExport before creating its manager.
+//
+// The server serves the discovery documents, with or without the apis.kcp.dev group, the get and list
+// endpoints of the APIExports and the discovery documents of the APIExport virtual workspaces.
+// Errors can be injected to exercise the failure paths.
+package kcptest
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// virtualWorkspacePrefix is the path under which the APIExport virtual workspaces are served.
+const virtualWorkspacePrefix = "/services/apiexport/"
+
+// Options configures the behaviour of the server.
+type Options struct {
+	// WithoutKCPAPIs removes the apis.kcp.dev group from discovery, as served by a plain Kubernetes API server.
+	WithoutKCPAPIs bool
+	// DiscoveryStatus is the HTTP status code returned by the discovery endpoints when not zero.
+	DiscoveryStatus int
+	// APIExportStatus is the HTTP status code returned by the APIExport endpoints when not zero.
+	APIExportStatus int
+}
+
+// Server is a fake kcp server.
+type Server struct {
+	*httptest.Server
+
+	opts Options
+
+	mu         sync.Mutex
+	apiExports []apisv1alpha1.APIExport
+}
+
+// NewServer starts a server that is closed when the test and its subtests complete.
+// It is served over TLS: the HTTP client created by client-go for a plain HTTP configuration is the shared
+// http.DefaultClient, whose transport would be replaced by the cluster aware one of the manager.
+func NewServer(t testing.TB, opts Options) *Server {
+	t.Helper()
+
+	s := &Server{opts: opts}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// RestConfig returns a configuration to connect to the server, trusting its certificate.
+func (s *Server) RestConfig() *rest.Config {
+	return &rest.Config{
+		Host: s.URL,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}),
+		},
+	}
+}
+
+// VirtualWorkspaceURL returns the URL of the virtual workspace the server serves for the APIExport.
+func (s *Server) VirtualWorkspaceURL(apiExportName string) string {
+	return s.URL + virtualWorkspacePrefix + "root/" + apiExportName
+}
+
+// NewAPIExport returns an APIExport whose status references the virtual workspace served for it.
+// It is not added to the server.
+func (s *Server) NewAPIExport(name string) apisv1alpha1.APIExport {
+	return apisv1alpha1.APIExport{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: apisv1alpha1.APIExportStatus{
+			VirtualWorkspaces: []apisv1alpha1.VirtualWorkspace{{URL: s.VirtualWorkspaceURL(name)}},
+		},
+	}
+}
+
+// AddAPIExports adds APIExports to the ones served by the server.
+func (s *Server) AddAPIExports(apiExports ...apisv1alpha1.APIExport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiExports = append(s.apiExports, apiExports...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeStatus(w, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed, r.Method+" is not supported")
+		return
+	}
+
+	path := r.URL.Path
+	if strings.HasPrefix(path, virtualWorkspacePrefix) {
+		s.serveVirtualWorkspace(w, strings.TrimPrefix(path, virtualWorkspacePrefix))
+		return
+	}
+	path = trimClusterPrefix(path)
+
+	switch {
+	case path == "/api" || path == "/apis" || path == "/api/v1" || path == "/apis/apis.kcp.dev/v1alpha1":
+		s.serveDiscovery(w, path)
+	case path == "/apis/apis.kcp.dev/v1alpha1/apiexports":
+		s.serveAPIExportList(w)
+	case strings.HasPrefix(path, "/apis/apis.kcp.dev/v1alpha1/apiexports/"):
+		s.serveAPIExport(w, strings.TrimPrefix(path, "/apis/apis.kcp.dev/v1alpha1/apiexports/"))
+	default:
+		writeNotFound(w, "the server could not find the requested resource")
+	}
+}
+
+func (s *Server) serveDiscovery(w http.ResponseWriter, path string) {
+	if s.opts.DiscoveryStatus != 0 {
+		writeStatus(w, s.opts.DiscoveryStatus, "", "injected discovery error")
+		return
+	}
+
+	switch path {
+	case "/api":
+		writeJSON(w, &metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+		})
+	case "/api/v1":
+		writeJSON(w, &metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{},
+		})
+	case "/apis":
+		groups := &metav1.APIGroupList{
+			TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+			Groups:   []metav1.APIGroup{},
+		}
+		if !s.opts.WithoutKCPAPIs {
+			version := metav1.GroupVersionForDiscovery{
+				GroupVersion: apisv1alpha1.SchemeGroupVersion.String(),
+				Version:      apisv1alpha1.SchemeGroupVersion.Version,
+			}
+			groups.Groups = append(groups.Groups, metav1.APIGroup{
+				Name:             apisv1alpha1.SchemeGroupVersion.Group,
+				Versions:         []metav1.GroupVersionForDiscovery{version},
+				PreferredVersion: version,
+			})
+		}
+		writeJSON(w, groups)
+	default:
+		if s.opts.WithoutKCPAPIs {
+			writeNotFound(w, "the server could not find the requested resource")
+			return
+		}
+		writeJSON(w, &metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: apisv1alpha1.SchemeGroupVersion.String(),
+			APIResources: []metav1.APIResource{{
+				Name:         "apiexports",
+				SingularName: "apiexport",
+				Kind:         "APIExport",
+				Verbs:        metav1.Verbs{"get", "list", "watch"},
+			}},
+		})
+	}
+}
+
+func (s *Server) serveAPIExportList(w http.ResponseWriter) {
+	if s.opts.WithoutKCPAPIs {
+		writeNotFound(w, "the server could not find the requested resource")
+		return
+	}
+	if s.opts.APIExportStatus != 0 {
+		writeStatus(w, s.opts.APIExportStatus, "", "injected APIExport error")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, &apisv1alpha1.APIExportList{
+		TypeMeta: metav1.TypeMeta{Kind: "APIExportList", APIVersion: apisv1alpha1.SchemeGroupVersion.String()},
+		Items:    append([]apisv1alpha1.APIExport{}, s.apiExports...),
+	})
+}
+
+func (s *Server) serveAPIExport(w http.ResponseWriter, name string) {
+	if s.opts.WithoutKCPAPIs {
+		writeNotFound(w, "the server could not find the requested resource")
+		return
+	}
+	if s.opts.APIExportStatus != 0 {
+		writeStatus(w, s.opts.APIExportStatus, "", "injected APIExport error")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, apiExport := range s.apiExports {
+		if apiExport.Name == name {
+			apiExport.TypeMeta = metav1.TypeMeta{Kind: "APIExport", APIVersion: apisv1alpha1.SchemeGroupVersion.String()}
+			writeJSON(w, &apiExport)
+			return
+		}
+	}
+	writeNotFound(w, fmt.Sprintf("apiexports.apis.kcp.dev %q not found", name))
+}
+
+// serveVirtualWorkspace serves the discovery documents of the virtual workspace of an APIExport.
+// The path is expected to be <workspace>/<APIExport name>/<request path>.
+func (s *Server) serveVirtualWorkspace(w http.ResponseWriter, path string) {
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) < 2 {
+		writeNotFound(w, "the server could not find the requested resource")
+		return
+	}
+	s.mu.Lock()
+	found := false
+	for _, apiExport := range s.apiExports {
+		found = found || apiExport.Name == parts[1]
+	}
+	s.mu.Unlock()
+	if !found {
+		writeNotFound(w, fmt.Sprintf("virtual workspace of APIExport %q not found", parts[1]))
+		return
+	}
+
+	var subPath string
+	if len(parts) == 3 {
+		subPath = "/" + parts[2]
+	}
+	switch trimClusterPrefix(subPath) {
+	case "/api":
+		writeJSON(w, &metav1.APIVersions{TypeMeta: metav1.TypeMeta{Kind: "APIVersions"}, Versions: []string{"v1"}})
+	case "/api/v1":
+		writeJSON(w, &metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{},
+		})
+	case "/apis":
+		writeJSON(w, &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}, Groups: []metav1.APIGroup{}})
+	default:
+		writeNotFound(w, "the server could not find the requested resource")
+	}
+}
+
+// trimClusterPrefix removes the /clusters/<name> prefix of requests made against a workspace.
+func trimClusterPrefix(path string) string {
+	if !strings.HasPrefix(path, "/clusters/") {
+		return path
+	}
+	_, rest, found := strings.Cut(strings.TrimPrefix(path, "/clusters/"), "/")
+	if !found {
+		return "/"
+	}
+	return "/" + rest
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(obj)
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason metav1.StatusReason, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Message:  message,
+		Reason:   reason,
+		Code:     int32(code),
+	})
+}
+
+func writeNotFound(w http.ResponseWriter, message string) {
+	writeStatus(w, http.StatusNotFound, metav1.StatusReasonNotFound, message)
+}
@@ -0,0 +1,85 @@
+package kcptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"k8s.io/client-go/discovery"
+)
+
+func TestDiscovery(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		// groups includes the legacy group.
+		groups  int
+		wantErr bool
+	}{
+		{name: "kcp", groups: 2},
+		{name: "kubernetes", opts: Options{WithoutKCPAPIs: true}, groups: 1},
+		{name: "error", opts: Options{DiscoveryStatus: http.StatusInternalServerError}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(t, tt.opts)
+			client, err := discovery.NewDiscoveryClientForConfig(s.RestConfig())
+			if err != nil {
+				t.Fatalf("error creating the discovery client: %v", err)
+			}
+			groups, err := client.ServerGroups()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(groups.Groups) != tt.groups {
+				t.Errorf("expected %d groups, got %v", tt.groups, groups.Groups)
+			}
+		})
+	}
+}
+
+func TestAPIExports(t *testing.T) {
+	s := NewServer(t, Options{})
+	s.AddAPIExports(s.NewAPIExport("widgets"))
+
+	for path, code := range map[string]int{
+		"/apis/apis.kcp.dev/v1alpha1/apiexports/widgets":                   http.StatusOK,
+		"/clusters/root:org/apis/apis.kcp.dev/v1alpha1/apiexports/widgets": http.StatusOK,
+		"/apis/apis.kcp.dev/v1alpha1/apiexports/gadgets":                   http.StatusNotFound,
+	} {
+		resp, err := s.Client().Get(s.URL + path)
+		if err != nil {
+			t.Fatalf("error getting %s: %v", path, err)
+		}
+		if resp.StatusCode != code {
+			t.Errorf("expected %d for %s, got %d", code, path, resp.StatusCode)
+		}
+		if code == http.StatusOK {
+			var apiExport apisv1alpha1.APIExport
+			if err := json.NewDecoder(resp.Body).Decode(&apiExport); err != nil {
+				t.Errorf("error decoding %s: %v", path, err)
+			}
+			if url := apiExport.Status.VirtualWorkspaces[0].URL; url != s.VirtualWorkspaceURL("widgets") {
+				t.Errorf("unexpected virtual workspace URL %s", url)
+			}
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := s.Client().Get(s.VirtualWorkspaceURL("widgets") + "/clusters/*/apis")
+	if err != nil {
+		t.Fatalf("error getting the virtual workspace discovery: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the virtual workspace discovery to be served, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,278 @@
+// Package lifecycle onboards and offboards the tenants, the logical clusters binding the APIExport of the controller.
+//
+// The Reconciler watches the APIBindings through the virtual workspace of the APIExport and calls the Hooks when a
+// logical cluster binds the APIExport, when the permission claims it accepts change and when it unbinds the APIExport.
+// When a logical cluster unbinds the APIExport, the Reconciler deletes the objects of the claimed resources that the
+// controllers created for the tenant, which are labeled with ManagedByLabel, see SetManagedBy. The objects of the
+// resources of the APIExport are deleted by kcp with the APIBinding.
+//
+// The cleanup runs while the APIBinding is being deleted, as the claimed resources of the logical cluster are no
+// longer served by the virtual workspace once the APIBinding is gone. The Reconciler adds Finalizer to the APIBindings
+// when their logical clusters are onboarded, and removes it once the cleanup and the Unbound hook succeeded, so that
+// the APIBinding is kept until then. The APIBindings deleted before they got the finalizer, e.g. bound by an older
+// version of the controller, are cleaned up on a best-effort basis: the objects that could not be deleted by then are
+// reported in the logs.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusteraware"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+)
+
+// ManagedByLabel labels the objects created by the controllers for a tenant, which are deleted when it unbinds the
+// APIExport. Its value is the owner of the Reconciler.
+const ManagedByLabel = "lifecycle.kcp.io/managed-by"
+
+// Finalizer keeps the APIBindings until the objects created for their logical clusters are deleted.
+const Finalizer = "lifecycle.kcp.io/cleanup"
+
+// SetManagedBy labels obj as created by the controllers of owner, so that it is deleted when its logical cluster
+// unbinds the APIExport.
+func SetManagedBy(obj metav1.Object, owner string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ManagedByLabel] = owner
+	obj.SetLabels(labels)
+}
+
+// Hooks are called by the Reconciler on the changes of the APIBindings. They are called again when they fail and
+// after a restart of the manager, and must be idempotent. The context is scoped to the logical cluster of the
+// APIBinding.
+type Hooks interface {
+	// Bound is called when a logical cluster binds the APIExport, and for all the bound logical clusters when
+	// the manager starts.
+	Bound(ctx context.Context, binding *apisv1alpha1.APIBinding) error
+	// ClaimsChanged is called when the permission claims accepted by the APIBinding change, with the ones
+	// previously accepted.
+	ClaimsChanged(ctx context.Context, binding *apisv1alpha1.APIBinding, previous []apisv1alpha1.PermissionClaim) error
+	// Unbound is called when a logical cluster unbinds the APIExport, after the cleanup of the objects created for it.
+	Unbound(ctx context.Context, clusterName logicalcluster.Name) error
+}
+
+// bindingKey identifies an APIBinding.
+type bindingKey struct {
+	cluster logicalcluster.Name
+	name    string
+}
+
+// Reconciler calls the Hooks on the changes of the APIBindings.
+type Reconciler struct {
+	client client.Client
+	mapper meta.RESTMapper
+	owner  string
+	hooks  Hooks
+
+	mu sync.Mutex
+	// claims are the permission claims accepted by the APIBindings last reconciled, by APIBinding.
+	claims map[bindingKey][]apisv1alpha1.PermissionClaim
+}
+
+// NewReconciler returns a Reconciler calling hooks and deleting the objects labeled with ManagedByLabel set to
+// owner, e.g. the name of the project, when a logical cluster unbinds the APIExport. The objects are deleted with c,
+// the client of the manager, and their kinds are given by mapper.
+func NewReconciler(c client.Client, mapper meta.RESTMapper, owner string, hooks Hooks) *Reconciler {
+	return &Reconciler{
+		client: c,
+		mapper: mapper,
+		owner:  owner,
+		hooks:  hooks,
+		claims: make(map[bindingKey][]apisv1alpha1.PermissionClaim),
+	}
+}
+
+// Reconcile implements reconcile.Reconciler.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := logf.FromContext(ctx)
+	key := bindingKey{cluster: logicalcluster.New(req.ClusterName), name: req.Name}
+
+	binding := &apisv1alpha1.APIBinding{}
+	if err := r.client.Get(ctx, req.NamespacedName, binding); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		// The APIBinding is gone before its deletion was observed. The cleanup is attempted with the claims last
+		// accepted, the claimed resources of the logical cluster are likely not served anymore.
+		r.mu.Lock()
+		claims, known := r.claims[key]
+		r.mu.Unlock()
+		if known {
+			if err := r.cleanup(ctx, key.cluster, claims); err != nil {
+				log.Error(err, "unable to delete the objects of the offboarded logical cluster")
+			}
+		}
+		return reconcile.Result{}, r.unbound(ctx, key)
+	}
+
+	claims := acceptedClaims(binding)
+	if !binding.GetDeletionTimestamp().IsZero() {
+		log.Info("Offboarding the logical cluster")
+		if err := r.cleanup(ctx, key.cluster, claims); err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := r.unbound(ctx, key); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, r.removeFinalizer(ctx, binding)
+	}
+
+	r.mu.Lock()
+	previous, known := r.claims[key]
+	r.mu.Unlock()
+	switch {
+	case !known:
+		if binding.Status.Phase != apisv1alpha1.APIBindingPhaseBound {
+			// The APIBinding is reconciled again when kcp binds it.
+			return reconcile.Result{}, nil
+		}
+		log.Info("Onboarding the logical cluster")
+		if err := r.addFinalizer(ctx, binding); err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := r.hooks.Bound(ctx, binding); err != nil {
+			return reconcile.Result{}, err
+		}
+	case !equalClaims(previous, claims):
+		log.Info("The accepted permission claims changed", "claims", claims)
+		if err := r.hooks.ClaimsChanged(ctx, binding, previous); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	r.mu.Lock()
+	r.claims[key] = claims
+	r.mu.Unlock()
+	return reconcile.Result{}, nil
+}
+
+// unbound calls the Unbound hook and forgets the APIBinding.
+func (r *Reconciler) unbound(ctx context.Context, key bindingKey) error {
+	if err := r.hooks.Unbound(ctx, key.cluster); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	delete(r.claims, key)
+	r.mu.Unlock()
+	return nil
+}
+
+// addFinalizer adds Finalizer to binding, before anything is created for its logical cluster.
+func (r *Reconciler) addFinalizer(ctx context.Context, binding *apisv1alpha1.APIBinding) error {
+	if controllerutil.ContainsFinalizer(binding, Finalizer) {
+		return nil
+	}
+	original := binding.DeepCopy()
+	controllerutil.AddFinalizer(binding, Finalizer)
+	if err := r.client.Patch(ctx, binding, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("error adding the finalizer to APIBinding %s: %w", binding.Name, err)
+	}
+	return nil
+}
+
+// removeFinalizer removes Finalizer from binding once its logical cluster is offboarded.
+func (r *Reconciler) removeFinalizer(ctx context.Context, binding *apisv1alpha1.APIBinding) error {
+	if !controllerutil.ContainsFinalizer(binding, Finalizer) {
+		return nil
+	}
+	original := binding.DeepCopy()
+	controllerutil.RemoveFinalizer(binding, Finalizer)
+	if err := r.client.Patch(ctx, binding, client.MergeFrom(original)); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error removing the finalizer from APIBinding %s: %w", binding.Name, err)
+	}
+	return nil
+}
+
+// cleanup deletes the objects of the claimed resources labeled as created by the controllers of the owner.
+func (r *Reconciler) cleanup(ctx context.Context, cluster logicalcluster.Name, claims []apisv1alpha1.PermissionClaim) error {
+	var errs []error
+	for _, claim := range claims {
+		gvk, err := r.mapper.KindFor(schema.GroupVersionResource{Group: claim.Group, Resource: claim.Resource})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error getting the kind of %s: %w", claim.GroupResource, err))
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.client.List(ctx, list, client.MatchingLabels{ManagedByLabel: r.owner}); err != nil {
+			errs = append(errs, fmt.Errorf("error listing the %s: %w", claim.Resource, err))
+			continue
+		}
+		for i := range list.Items {
+			if err := r.client.Delete(ctx, &list.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("error deleting %s %s/%s: %w", gvk.Kind,
+					list.Items[i].GetNamespace(), list.Items[i].GetName(), err))
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("error cleaning up logical cluster %s: %w", cluster, utilerrors.NewAggregate(errs))
+	}
+	return nil
+}
+
+// acceptedClaims returns the permission claims accepted by binding, sorted.
+func acceptedClaims(binding *apisv1alpha1.APIBinding) []apisv1alpha1.PermissionClaim {
+	claims := []apisv1alpha1.PermissionClaim{}
+	for _, claim := range binding.Spec.PermissionClaims {
+		if claim.State == apisv1alpha1.ClaimAccepted {
+			claims = append(claims, claim.PermissionClaim)
+		}
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		if claims[i].Group != claims[j].Group {
+			return claims[i].Group < claims[j].Group
+		}
+		return claims[i].Resource < claims[j].Resource
+	})
+	return claims
+}
+
+// equalClaims returns whether the sorted claims a and b are the same.
+func equalClaims(a, b []apisv1alpha1.PermissionClaim) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Group != b[i].Group || a[i].Resource != b[i].Resource {
+			return false
+		}
+	}
+	return true
+}
+
+// SetupWithManager sets up the controller of the APIBindings with the manager. Nothing is set up when not connected
+// to kcp. The requests are scoped to the logical clusters of the APIBindings by clusteraware.NewReconciler and, when
+// sharding is enabled, are reconciled by the replica owning the logical cluster.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	gvk := apisv1alpha1.SchemeGroupVersion.WithKind("APIBinding")
+	if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			mgr.GetLogger().WithName("lifecycle").Info("Not connected to kcp, the tenant lifecycle is not reconciled")
+			return nil
+		}
+		return fmt.Errorf("error getting the resource of the APIBindings: %w", err)
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("tenant-lifecycle").
+		For(&apisv1alpha1.APIBinding{}).
+		Complete(sharding.NewReconciler(clusteraware.NewReconciler(r)))
+}
@@ -0,0 +1,192 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// recordingHooks records the calls of the hooks.
+type recordingHooks struct {
+	calls    []string
+	previous []apisv1alpha1.PermissionClaim
+}
+
+func (h *recordingHooks) Bound(context.Context, *apisv1alpha1.APIBinding) error {
+	h.calls = append(h.calls, "bound")
+	return nil
+}
+
+func (h *recordingHooks) ClaimsChanged(_ context.Context, _ *apisv1alpha1.APIBinding, previous []apisv1alpha1.PermissionClaim) error {
+	h.calls = append(h.calls, "claims-changed")
+	h.previous = previous
+	return nil
+}
+
+func (h *recordingHooks) Unbound(context.Context, logicalcluster.Name) error {
+	h.calls = append(h.calls, "unbound")
+	return nil
+}
+
+func newClaim(resource string, state apisv1alpha1.AcceptablePermissionClaimState) apisv1alpha1.AcceptablePermissionClaim {
+	claim := apisv1alpha1.AcceptablePermissionClaim{State: state}
+	claim.Resource = resource
+	return claim
+}
+
+func newConfigMap(name string, labels map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+}
+
+func TestReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apisv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+
+	binding := &apisv1alpha1.APIBinding{ObjectMeta: metav1.ObjectMeta{Name: "widgets", Finalizers: []string{"apis.kcp.dev/apibinding-finalizer"}}}
+	binding.Spec.PermissionClaims = []apisv1alpha1.AcceptablePermissionClaim{
+		newClaim("configmaps", apisv1alpha1.ClaimAccepted),
+		newClaim("secrets", apisv1alpha1.ClaimRejected),
+	}
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	managed := newConfigMap("managed", nil)
+	SetManagedBy(managed, "widget-operator")
+	other := newConfigMap("other", map[string]string{ManagedByLabel: "gadget-operator"})
+	unmanaged := newConfigMap("unmanaged", nil)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(binding, managed, other, unmanaged).Build()
+
+	hooks := &recordingHooks{}
+	r := NewReconciler(c, mapper, "widget-operator", hooks)
+	ctx := context.Background()
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "widgets"}, ClusterName: "root:org:ws"}
+	reconcileBinding := func() {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	reconcileBinding()
+	reconcileBinding()
+	if len(hooks.calls) != 1 || hooks.calls[0] != "bound" {
+		t.Fatalf("expected the bound hook to be called once, got %v", hooks.calls)
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(binding), binding); err != nil {
+		t.Fatal(err)
+	}
+	if !controllerutil.ContainsFinalizer(binding, Finalizer) {
+		t.Fatalf("expected the finalizer to be added to the APIBinding, got %v", binding.Finalizers)
+	}
+	binding.Spec.PermissionClaims[1].State = apisv1alpha1.ClaimAccepted
+	if err := c.Update(ctx, binding); err != nil {
+		t.Fatal(err)
+	}
+	reconcileBinding()
+	if len(hooks.calls) != 2 || hooks.calls[1] != "claims-changed" || len(hooks.previous) != 1 ||
+		hooks.previous[0].Resource != "configmaps" {
+		t.Fatalf("expected the claims changed hook to be called with the previous claims, got %v, %v", hooks.calls, hooks.previous)
+	}
+
+	// The APIBinding is kept by the finalizer while the logical cluster is offboarded.
+	if err := c.Delete(ctx, binding); err != nil {
+		t.Fatal(err)
+	}
+	reconcileBinding()
+	if len(hooks.calls) != 3 || hooks.calls[2] != "unbound" {
+		t.Fatalf("expected the unbound hook to be called, got %v", hooks.calls)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(binding), binding); err != nil {
+		t.Fatal(err)
+	}
+	if controllerutil.ContainsFinalizer(binding, Finalizer) {
+		t.Errorf("expected the finalizer to be removed once the logical cluster is offboarded, got %v", binding.Finalizers)
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, cm := range configMaps.Items {
+		names = append(names, cm.Name)
+	}
+	if len(names) != 2 || names[0] != "other" || names[1] != "unmanaged" {
+		t.Errorf("expected only the objects managed by the owner to be deleted, got %v", names)
+	}
+}
+
+// failingDeleteClient fails the deletions of the objects.
+type failingDeleteClient struct {
+	client.Client
+}
+
+func (c *failingDeleteClient) Delete(context.Context, client.Object, ...client.DeleteOption) error {
+	return apierrors.NewServiceUnavailable("unavailable")
+}
+
+func TestReconcilerKeepsFinalizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apisv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+
+	binding := &apisv1alpha1.APIBinding{ObjectMeta: metav1.ObjectMeta{Name: "widgets", Finalizers: []string{Finalizer}}}
+	binding.Spec.PermissionClaims = []apisv1alpha1.AcceptablePermissionClaim{newClaim("configmaps", apisv1alpha1.ClaimAccepted)}
+	managed := newConfigMap("managed", nil)
+	SetManagedBy(managed, "widget-operator")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(binding, managed).Build()
+	if err := c.Delete(context.Background(), binding); err != nil {
+		t.Fatal(err)
+	}
+
+	// The APIBinding is kept while the cleanup fails, and the Unbound hook is not called.
+	hooks := &recordingHooks{}
+	r := NewReconciler(&failingDeleteClient{Client: c}, mapper, "widget-operator", hooks)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "widgets"}, ClusterName: "root:org:ws"}
+	if _, err := r.Reconcile(context.Background(), req); err == nil {
+		t.Fatalf("expected the cleanup to fail")
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(binding), binding); err != nil {
+		t.Fatalf("expected the APIBinding to be kept by the finalizer: %v", err)
+	}
+	if len(hooks.calls) != 0 {
+		t.Errorf("expected the unbound hook not to be called, got %v", hooks.calls)
+	}
+
+	// Once the cleanup succeeds, the finalizer is removed and the APIBinding is gone.
+	r = NewReconciler(c, mapper, "widget-operator", hooks)
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(binding), binding); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the APIBinding to be deleted, got %v", err)
+	}
+	if len(hooks.calls) != 1 || hooks.calls[0] != "unbound" {
+		t.Errorf("expected the unbound hook to be called, got %v", hooks.calls)
+	}
+}
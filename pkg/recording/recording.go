@@ -0,0 +1,439 @@
+// Package recording records the objects read and written by the controllers for some logical clusters and
+// replays them offline, to reproduce the issues reported by a tenant without access to its workspace.
+//
+// The client returned by Recorder.WrapClient records the objects of the recorded logical clusters in the state
+// in which they were first read, and the writes made to them in order. The recordings are written periodically,
+// and when the manager stops, to a file per logical cluster. The data of the secrets is redacted. A recording
+// stops after Options.MaxEntries objects and writes, so that a busy logical cluster does not fill the disk nor make
+// each write of its recording longer: it is then marked as truncated.
+//
+// A recording is loaded with Load. Recording.NewClient returns a fake client serving the recorded objects, which
+// is passed to the reconciler, and Recording.Replay runs the reconciler for an object of the logical cluster.
+package recording
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/internal/flags"
+)
+
+const (
+	// DefaultDir is the default directory of the recordings.
+	DefaultDir = "/tmp/recordings"
+	// DefaultFlushPeriod is the default period at which the recordings are written.
+	DefaultFlushPeriod = 10 * time.Second
+	// DefaultMaxEntries is the default number of objects and writes recorded per logical cluster.
+	DefaultMaxEntries = 10000
+	// Redacted replaces the values of the data of the secrets.
+	Redacted = "REDACTED"
+)
+
+// Options configures a Recorder.
+type Options struct {
+	// Clusters are the names of the logical clusters recorded. Nothing is recorded when empty.
+	Clusters []string
+	// Dir is the directory of the recordings, which are named after the logical clusters. It defaults to DefaultDir.
+	Dir string
+	// FlushPeriod is the period at which the recordings are written. It defaults to DefaultFlushPeriod.
+	FlushPeriod time.Duration
+	// MaxEntries is the number of objects and writes recorded per logical cluster, after which the recording
+	// stops. It defaults to DefaultMaxEntries.
+	MaxEntries int
+}
+
+// BindFlags binds the options to the flags of fs.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.Var((*flags.List)(&o.Clusters), "record-clusters",
+		"Comma separated names of the logical clusters whose objects read and written by the controllers are recorded.")
+	fs.StringVar(&o.Dir, "record-dir", DefaultDir, "The directory the recordings are written to.")
+	fs.IntVar(&o.MaxEntries, "record-max-entries", DefaultMaxEntries,
+		"The number of objects and writes recorded per logical cluster, after which the recording stops.")
+}
+
+// Recording is the content of the recording of a logical cluster.
+type Recording struct {
+	// ClusterName is the name of the logical cluster.
+	ClusterName string `json:"clusterName"`
+	// Objects are the objects read by the controllers, in the state in which they were first read.
+	Objects []unstructured.Unstructured `json:"objects"`
+	// Writes are the writes of the controllers, in order.
+	Writes []Write `json:"writes,omitempty"`
+	// Truncated is true when the recording stopped after the maximum number of entries: the objects read and the
+	// writes made later are missing.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Write is a write of a controller.
+type Write struct {
+	// Verb is the verb of the write, e.g. create or updatestatus.
+	Verb string `json:"verb"`
+	// Object is the object written, as returned by the server.
+	Object unstructured.Unstructured `json:"object"`
+}
+
+// Recorder records the objects read and written through the clients it wraps. It is a manager.Runnable
+// writing the recordings periodically and when it stops.
+type Recorder struct {
+	dir         string
+	flushPeriod time.Duration
+	maxEntries  int
+
+	mu         sync.Mutex
+	recordings map[string]*recordingState
+}
+
+// recordingState is the recording of a logical cluster being recorded.
+type recordingState struct {
+	recording Recording
+	// read are the objects already recorded, by group, kind, namespace and name.
+	read  map[string]bool
+	dirty bool
+}
+
+// NewRecorder returns a Recorder of the logical clusters of opts.
+func NewRecorder(opts Options) *Recorder {
+	r := &Recorder{
+		dir:         opts.Dir,
+		flushPeriod: opts.FlushPeriod,
+		maxEntries:  opts.MaxEntries,
+		recordings:  map[string]*recordingState{},
+	}
+	if r.dir == "" {
+		r.dir = DefaultDir
+	}
+	if r.flushPeriod <= 0 {
+		r.flushPeriod = DefaultFlushPeriod
+	}
+	if r.maxEntries <= 0 {
+		r.maxEntries = DefaultMaxEntries
+	}
+	for _, clusterName := range opts.Clusters {
+		r.recordings[clusterName] = &recordingState{
+			recording: Recording{ClusterName: clusterName, Objects: []unstructured.Unstructured{}},
+			read:      map[string]bool{},
+		}
+	}
+	return r
+}
+
+// Path returns the path of the recording of the logical cluster.
+func (r *Recorder) Path(clusterName string) string {
+	// The colons of the logical cluster names are not supported by all the tools copying files, e.g. kubectl cp.
+	return filepath.Join(r.dir, strings.ReplaceAll(clusterName, ":", "_")+".json")
+}
+
+// Start writes the recordings periodically until ctx is done, and then a last time. It implements manager.Runnable.
+func (r *Recorder) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("recording")
+	ticker := time.NewTicker(r.flushPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				log.Error(err, "unable to write the recordings")
+			}
+		case <-ctx.Done():
+			return r.Flush()
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the recordings are written by all the replicas.
+func (r *Recorder) NeedLeaderElection() bool {
+	return false
+}
+
+// Flush writes the recordings that changed since they were last written.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for clusterName, state := range r.recordings {
+		if !state.dirty {
+			continue
+		}
+		data, err := json.MarshalIndent(&state.recording, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding the recording of %s: %w", clusterName, err)
+		}
+		if err := writeFile(r.Path(clusterName), data); err != nil {
+			return fmt.Errorf("error writing the recording of %s: %w", clusterName, err)
+		}
+		state.dirty = false
+	}
+	return nil
+}
+
+// writeFile replaces the file at path with data, so that a recording being copied is never partially written.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// read records obj, read in the context of the logical cluster, unless it was already read.
+func (r *Recorder) read(ctx context.Context, scheme *runtime.Scheme, obj runtime.Object) {
+	state, u := r.convert(ctx, scheme, obj)
+	if state == nil {
+		return
+	}
+	key := u.GroupVersionKind().GroupKind().String() + "/" + u.GetNamespace() + "/" + u.GetName()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if state.read[key] || !r.reserve(ctx, state) {
+		return
+	}
+	state.read[key] = true
+	state.recording.Objects = append(state.recording.Objects, *u)
+	state.dirty = true
+}
+
+// write records the write of obj, made in the context of the logical cluster.
+func (r *Recorder) write(ctx context.Context, scheme *runtime.Scheme, verb string, obj runtime.Object) {
+	state, u := r.convert(ctx, scheme, obj)
+	if state == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.reserve(ctx, state) {
+		return
+	}
+	state.recording.Writes = append(state.recording.Writes, Write{Verb: verb, Object: *u})
+	state.dirty = true
+}
+
+// reserve returns whether an entry can be added to the recording, which is truncated once it has the maximum
+// number of entries. It is called with the lock held.
+func (r *Recorder) reserve(ctx context.Context, state *recordingState) bool {
+	if state.recording.Truncated {
+		return false
+	}
+	if len(state.recording.Objects)+len(state.recording.Writes) < r.maxEntries {
+		return true
+	}
+	logf.FromContext(ctx).WithName("recording").Info("The recording reached the maximum number of entries and stops",
+		"cluster", state.recording.ClusterName, "maxEntries", r.maxEntries)
+	state.recording.Truncated = true
+	state.dirty = true
+	return false
+}
+
+// convert returns the recording of the logical cluster of obj, or of ctx, and obj as redacted unstructured
+// content. The recording is nil when the logical cluster is not recorded.
+func (r *Recorder) convert(ctx context.Context, scheme *runtime.Scheme, obj runtime.Object) (*recordingState, *unstructured.Unstructured) {
+	var clusterName logicalcluster.Name
+	if o, ok := obj.(client.Object); ok {
+		clusterName = logicalcluster.From(o)
+	}
+	if clusterName.Empty() {
+		clusterName, _ = logicalcluster.ClusterFromContext(ctx)
+	}
+	state, ok := r.recordings[clusterName.String()]
+	if !ok {
+		return nil, nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		logf.FromContext(ctx).WithName("recording").Error(err, "unable to convert the object")
+		return nil, nil
+	}
+	u := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(content)}
+	if gvk, err := apiutil.GVKForObject(obj, scheme); err == nil {
+		u.SetGroupVersionKind(gvk)
+	}
+	redact(u)
+	return state, u
+}
+
+// redact replaces the values of the data of the secrets with Redacted, including in the last applied configuration.
+func redact(u *unstructured.Unstructured) {
+	if u.GroupVersionKind().GroupKind() != (schema.GroupKind{Kind: "Secret"}) {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := u.Object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range values {
+			if field == "data" {
+				values[key] = base64.StdEncoding.EncodeToString([]byte(Redacted))
+			} else {
+				values[key] = Redacted
+			}
+		}
+	}
+	if annotations := u.GetAnnotations(); annotations["kubectl.kubernetes.io/last-applied-configuration"] != "" {
+		annotations["kubectl.kubernetes.io/last-applied-configuration"] = Redacted
+		u.SetAnnotations(annotations)
+	}
+}
+
+// WrapClient returns a client recording the objects read and written through c.
+func (r *Recorder) WrapClient(c client.Client) client.Client {
+	return &recordingClient{Client: c, recorder: r}
+}
+
+type recordingClient struct {
+	client.Client
+	recorder *Recorder
+}
+
+func (c *recordingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if err := c.Client.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	c.recorder.read(ctx, c.Scheme(), obj)
+	return nil
+}
+
+func (c *recordingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	return meta.EachListItem(list, func(obj runtime.Object) error {
+		c.recorder.read(ctx, c.Scheme(), obj)
+		return nil
+	})
+}
+
+func (c *recordingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.recorder.write(ctx, c.Scheme(), "create", obj)
+	return nil
+}
+
+func (c *recordingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.recorder.write(ctx, c.Scheme(), "update", obj)
+	return nil
+}
+
+func (c *recordingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	c.recorder.write(ctx, c.Scheme(), "patch", obj)
+	return nil
+}
+
+func (c *recordingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.recorder.write(ctx, c.Scheme(), "delete", obj)
+	return nil
+}
+
+func (c *recordingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	if err := c.Client.DeleteAllOf(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.recorder.write(ctx, c.Scheme(), "deleteallof", obj)
+	return nil
+}
+
+func (c *recordingClient) Status() client.StatusWriter {
+	return &recordingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type recordingStatusWriter struct {
+	client.StatusWriter
+	client *recordingClient
+}
+
+func (w *recordingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := w.StatusWriter.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	w.client.recorder.write(ctx, w.client.Scheme(), "updatestatus", obj)
+	return nil
+}
+
+func (w *recordingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := w.StatusWriter.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	w.client.recorder.write(ctx, w.client.Scheme(), "patchstatus", obj)
+	return nil
+}
+
+// Load reads the recording at path.
+func Load(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the recording: %w", err)
+	}
+	recording := &Recording{}
+	if err := json.Unmarshal(data, recording); err != nil {
+		return nil, fmt.Errorf("error decoding the recording %s: %w", path, err)
+	}
+	return recording, nil
+}
+
+// NewClient returns a fake client serving the recorded objects in their initial state. The objects of the kinds
+// of scheme are served as typed objects, the other ones as unstructured objects.
+func (rec *Recording) NewClient(scheme *runtime.Scheme) (client.Client, error) {
+	objs := make([]client.Object, 0, len(rec.Objects))
+	for i := range rec.Objects {
+		u := rec.Objects[i].DeepCopy()
+		typed, err := scheme.New(u.GroupVersionKind())
+		if err != nil {
+			objs = append(objs, u)
+			continue
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return nil, fmt.Errorf("error converting %s %s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+		}
+		obj, ok := typed.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T", typed)
+		}
+		objs = append(objs, obj)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(), nil
+}
+
+// Replay runs r for the object with the key in the logical cluster of the recording. r is usually the reconciler
+// of the controller created with the client returned by NewClient and wrapped by clusteraware.NewReconciler.
+func (rec *Recording) Replay(ctx context.Context, r reconcile.Reconciler, key types.NamespacedName) (reconcile.Result, error) {
+	clusterName := logicalcluster.New(rec.ClusterName)
+	return r.Reconcile(logicalcluster.WithCluster(ctx, clusterName), reconcile.Request{
+		NamespacedName: key,
+		ClusterName:    clusterName.String(),
+	})
+}
@@ -0,0 +1,149 @@
+package recording
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newObjectMeta(clusterName, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   "default",
+		Annotations: map[string]string{logicalcluster.AnnotationKey: clusterName},
+	}
+}
+
+// reconcileWidget copies the data of the widget config map and of the credentials secret to the status config map.
+func reconcileWidget(c client.Client) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		widget := &corev1.ConfigMap{}
+		if err := c.Get(ctx, req.NamespacedName, widget); err != nil {
+			return reconcile.Result{}, err
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: "credentials"}, secret); err != nil {
+			return reconcile.Result{}, err
+		}
+		status := &corev1.ConfigMap{ObjectMeta: newObjectMeta(req.ClusterName, req.Name+"-status")}
+		status.Data = map[string]string{"size": widget.Data["size"], "user": string(secret.Data["user"])}
+		return reconcile.Result{}, c.Create(ctx, status)
+	})
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	objs := []client.Object{
+		&corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:ws", "widget"), Data: map[string]string{"size": "3"}},
+		&corev1.Secret{ObjectMeta: newObjectMeta("root:org:ws", "credentials"), Data: map[string][]byte{"user": []byte("admin")}},
+		&corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:other", "gadget"), Data: map[string]string{"size": "5"}},
+	}
+	recorder := NewRecorder(Options{Clusters: []string{"root:org:ws"}, Dir: t.TempDir()})
+	c := recorder.WrapClient(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...).Build())
+
+	// The logical cluster root:org:other is not recorded, and its reconciliation fails without credentials.
+	// The logical clusters are reconciled in order, the objects being recorded in the order in which they are read.
+	for _, req := range []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "widget"}, ClusterName: "root:org:ws"},
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "gadget"}, ClusterName: "root:org:other"},
+	} {
+		ctx := logicalcluster.WithCluster(context.Background(), logicalcluster.New(req.ClusterName))
+		if _, err := reconcileWidget(c).Reconcile(ctx, req); err != nil && req.ClusterName == "root:org:ws" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("unable to write the recordings: %v", err)
+	}
+
+	if _, err := os.Stat(recorder.Path("root:org:other")); !os.IsNotExist(err) {
+		t.Errorf("expected the logical cluster root:org:other not to be recorded, got %v", err)
+	}
+	rec, err := Load(recorder.Path("root:org:ws"))
+	if err != nil {
+		t.Fatalf("unable to load the recording: %v", err)
+	}
+	if len(rec.Objects) != 2 {
+		t.Fatalf("expected the objects read in the logical cluster to be recorded, got %v", rec.Objects)
+	}
+	if data, _, _ := unstructured.NestedString(rec.Objects[1].Object, "data", "user"); data != base64.StdEncoding.EncodeToString([]byte(Redacted)) {
+		t.Errorf("expected the data of the secret to be redacted, got %q", data)
+	}
+	if len(rec.Writes) != 1 || rec.Writes[0].Verb != "create" || rec.Writes[0].Object.GetName() != "widget-status" {
+		t.Errorf("expected the creation of the status to be recorded, got %v", rec.Writes)
+	}
+
+	// The reconciliation is replayed against the recorded objects.
+	replayClient, err := rec.NewClient(scheme.Scheme)
+	if err != nil {
+		t.Fatalf("unable to create the replay client: %v", err)
+	}
+	if _, err := rec.Replay(context.Background(), reconcileWidget(replayClient), types.NamespacedName{Namespace: "default", Name: "widget"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status := &corev1.ConfigMap{}
+	if err := replayClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "widget-status"}, status); err != nil {
+		t.Fatalf("unable to get the status: %v", err)
+	}
+	if want := map[string]string{"size": "3", "user": Redacted}; !reflect.DeepEqual(status.Data, want) {
+		t.Errorf("expected the replayed status %v, got %v", want, status.Data)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	objs := []client.Object{
+		&corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:ws", "a")},
+		&corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:ws", "b")},
+		&corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:ws", "c")},
+	}
+	recorder := NewRecorder(Options{Clusters: []string{"root:org:ws"}, Dir: t.TempDir(), MaxEntries: 2})
+	c := recorder.WrapClient(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...).Build())
+
+	ctx := logicalcluster.WithCluster(context.Background(), logicalcluster.New("root:org:ws"))
+	for _, name := range []string{"a", "b", "c"} {
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &corev1.ConfigMap{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	status := &corev1.ConfigMap{ObjectMeta: newObjectMeta("root:org:ws", "status")}
+	if err := c.Create(ctx, status); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("unable to write the recordings: %v", err)
+	}
+
+	rec, err := Load(recorder.Path("root:org:ws"))
+	if err != nil {
+		t.Fatalf("unable to load the recording: %v", err)
+	}
+	if len(rec.Objects) != 2 || len(rec.Writes) != 0 || !rec.Truncated {
+		t.Errorf("expected the recording to stop after 2 entries and to be truncated, got %d objects, %d writes, truncated %v",
+			len(rec.Objects), len(rec.Writes), rec.Truncated)
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	var opts Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	if err := fs.Parse([]string{"--record-clusters=root:org:a,root:org:b", "--record-dir=/var/recordings",
+		"--record-max-entries=100"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Options{Clusters: []string{"root:org:a", "root:org:b"}, Dir: "/var/recordings", MaxEntries: 100}); !reflect.DeepEqual(opts, want) {
+		t.Errorf("expected %+v, got %+v", want, opts)
+	}
+}
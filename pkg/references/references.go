@@ -0,0 +1,192 @@
+// Package references resolves the references of the objects of the tenants to objects in other workspaces, e.g.
+// a Secret or a shared configuration in the parent workspace.
+//
+// A Reference has the path of the workspace of the referenced object, which is the workspace of the referencing
+// object or one of its ancestors: a tenant cannot reference the objects of the workspaces of other tenants. A Resolver resolves the path, checks that the APIExport can reach the referenced object there,
+// see claims.Check, and gets it with the client of the manager. It tracks the references, so that the handler
+// returned by Resolver.EnqueueReferrers requeues the referencing objects when the referenced ones change.
+package references
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/claims"
+)
+
+// Reference is a reference to an object, possibly in another workspace. It is meant to be embedded in the specs
+// of the APIs of the controllers.
+type Reference struct {
+	// Workspace is the path of the workspace of the object relative to the workspace of the referencing object,
+	// which can only be one of its ancestors, e.g. .. for its parent or ..:.. for its grandparent. The object is in
+	// the workspace of the referencing object when empty.
+	// +optional
+	Workspace string `json:"workspace,omitempty"`
+	// Namespace is the namespace of the object. It defaults to the namespace of the referencing object and is
+	// ignored for cluster scoped objects.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the object.
+	Name string `json:"name"`
+}
+
+// ResolveWorkspace returns the logical cluster of the workspace path, relative to the current logical cluster. An
+// empty path is the current logical cluster, .. its parent, ..:.. its grandparent, and so on. The other paths are
+// refused, including the absolute ones, so that the objects of a tenant can only reference objects of its own
+// workspace and of its ancestors, not the ones of other tenants. The paths are not supported when not connected to
+// kcp, where current is empty.
+func ResolveWorkspace(current logicalcluster.Name, path string) (logicalcluster.Name, error) {
+	if path == "" {
+		return current, nil
+	}
+	if current.Empty() {
+		return logicalcluster.Name{}, fmt.Errorf("workspace %q cannot be resolved when not connected to kcp", path)
+	}
+	cluster := current
+	for _, segment := range strings.Split(path, ":") {
+		if segment != ".." {
+			return logicalcluster.Name{}, fmt.Errorf("invalid workspace %q: only the workspace of the referencing "+
+				"object and its ancestors, e.g. .. or ..:.., can be referenced", path)
+		}
+		parent, ok := cluster.Parent()
+		if !ok {
+			return logicalcluster.Name{}, fmt.Errorf("workspace %q is above the root of %s", path, current)
+		}
+		cluster = parent
+	}
+	return cluster, nil
+}
+
+// target identifies a referenced object.
+type target struct {
+	cluster   logicalcluster.Name
+	groupKind schema.GroupKind
+	key       types.NamespacedName
+}
+
+// Resolver gets the referenced objects and tracks their referrers.
+type Resolver struct {
+	reader client.Reader
+	scheme *runtime.Scheme
+	mapper meta.RESTMapper
+
+	mu sync.Mutex
+	// referrers are the requests of the referencing objects, by referenced object.
+	referrers map[target]map[reconcile.Request]bool
+	// targets are the referenced objects, by request of the referencing object.
+	targets map[reconcile.Request]map[target]bool
+}
+
+// NewResolver returns a Resolver getting the referenced objects from reader, the client of the manager. The scheme
+// and the mapper give the kinds and the resources of the referenced objects.
+func NewResolver(reader client.Reader, scheme *runtime.Scheme, mapper meta.RESTMapper) *Resolver {
+	return &Resolver{
+		reader:    reader,
+		scheme:    scheme,
+		mapper:    mapper,
+		referrers: make(map[target]map[reconcile.Request]bool),
+		targets:   make(map[reconcile.Request]map[target]bool),
+	}
+}
+
+// Get gets in obj the object of ref, resolved relative to the logical cluster and the namespace of req, the
+// request of the referencing object. It returns a claims.NotAcceptedError when the APIExport cannot reach the
+// object in its workspace. The reference is tracked before the object is read, so that req is requeued when the
+// object changes, including when it is created after a NotFound error.
+func (r *Resolver) Get(ctx context.Context, req reconcile.Request, ref Reference, obj client.Object) error {
+	cluster, err := ResolveWorkspace(logicalcluster.New(req.ClusterName), ref.Workspace)
+	if err != nil {
+		return err
+	}
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return fmt.Errorf("error getting the kind of the referenced object: %w", err)
+	}
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return fmt.Errorf("error getting the resource of the referenced %s: %w", gvk.Kind, err)
+	}
+	key := types.NamespacedName{Name: ref.Name}
+	if mapping.Scope.Name() != meta.RESTScopeNameRoot {
+		key.Namespace = ref.Namespace
+		if key.Namespace == "" {
+			key.Namespace = req.Namespace
+		}
+	}
+	r.track(req, target{cluster: cluster, groupKind: gvk.GroupKind(), key: key})
+
+	if !cluster.Empty() {
+		if err := claims.Check(ctx, cluster, mapping.Resource.GroupResource()); err != nil {
+			return err
+		}
+		ctx = logicalcluster.WithCluster(ctx, cluster)
+	}
+	return r.reader.Get(ctx, key, obj)
+}
+
+// track records that the object of req references t.
+func (r *Resolver) track(req reconcile.Request, t target) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.referrers[t] == nil {
+		r.referrers[t] = make(map[reconcile.Request]bool)
+	}
+	r.referrers[t][req] = true
+	if r.targets[req] == nil {
+		r.targets[req] = make(map[target]bool)
+	}
+	r.targets[req][t] = true
+}
+
+// Forget stops tracking the references of the object of req, e.g. when it is deleted. The references that an
+// object no longer has are otherwise tracked until it is deleted, which only causes unneeded reconciliations.
+func (r *Resolver) Forget(req reconcile.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for t := range r.targets[req] {
+		delete(r.referrers[t], req)
+		if len(r.referrers[t]) == 0 {
+			delete(r.referrers, t)
+		}
+	}
+	delete(r.targets, req)
+}
+
+// EnqueueReferrers returns a handler enqueuing the requests of the objects referencing the objects it is called
+// for, to be used with a watch of the referenced type.
+func (r *Resolver) EnqueueReferrers() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		gvk, err := apiutil.GVKForObject(obj, r.scheme)
+		if err != nil {
+			logf.Log.WithName("references").Error(err, "unable to get the kind of the referenced object",
+				"type", fmt.Sprintf("%T", obj))
+			return nil
+		}
+		t := target{
+			cluster:   logicalcluster.From(obj),
+			groupKind: gvk.GroupKind(),
+			key:       types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		requests := make([]reconcile.Request, 0, len(r.referrers[t]))
+		for req := range r.referrers[t] {
+			requests = append(requests, req)
+		}
+		return requests
+	})
+}
@@ -0,0 +1,157 @@
+package references
+
+import (
+	"context"
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/claims"
+)
+
+func TestResolveWorkspace(t *testing.T) {
+	current := logicalcluster.New("root:org:team")
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "", want: "root:org:team"},
+		{path: "..", want: "root:org"},
+		{path: "..:..", want: "root"},
+		{path: "..:..:..", wantErr: true},
+		// The workspaces of other tenants cannot be referenced.
+		{path: "root:other-org:ws", wantErr: true},
+		{path: "root", wantErr: true},
+		{path: "..:..:sibling-of-other-tenant", wantErr: true},
+		{path: "..:shared", wantErr: true},
+		{path: "child", wantErr: true},
+		{path: "..:", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveWorkspace(current, tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.path, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.path, tt.want, got)
+		}
+	}
+
+	if _, err := ResolveWorkspace(logicalcluster.Name{}, ".."); err == nil {
+		t.Errorf("expected an error when not connected to kcp")
+	}
+}
+
+// fakeReader serves the APIBindings and the secrets of the logical clusters it is created with.
+type fakeReader struct {
+	client.Reader
+	bindings map[string][]apisv1alpha1.APIBinding
+	secrets  map[string][]corev1.Secret
+}
+
+func (r *fakeReader) List(ctx context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	clusterName, _ := logicalcluster.ClusterFromContext(ctx)
+	list.(*apisv1alpha1.APIBindingList).Items = r.bindings[clusterName.String()]
+	return nil
+}
+
+func (r *fakeReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	clusterName, _ := logicalcluster.ClusterFromContext(ctx)
+	for _, secret := range r.secrets[clusterName.String()] {
+		if secret.Namespace == key.Namespace && secret.Name == key.Name {
+			secret.DeepCopyInto(obj.(*corev1.Secret))
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+}
+
+func newSecret(clusterName, namespace, name string) corev1.Secret {
+	return corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Annotations: map[string]string{logicalcluster.AnnotationKey: clusterName},
+	}}
+}
+
+func TestResolver(t *testing.T) {
+	binding := apisv1alpha1.APIBinding{}
+	claim := apisv1alpha1.AcceptablePermissionClaim{State: apisv1alpha1.ClaimAccepted}
+	claim.Resource = "secrets"
+	binding.Spec.PermissionClaims = []apisv1alpha1.AcceptablePermissionClaim{claim}
+	reader := &fakeReader{
+		bindings: map[string][]apisv1alpha1.APIBinding{"root:org": {binding}, "root:org:team": {binding}},
+		secrets: map[string][]corev1.Secret{
+			"root:org":      {newSecret("root:org", "default", "credentials")},
+			"root:org:team": {newSecret("root:org:team", "widgets", "local")},
+		},
+	}
+	claims.SetDefault(claims.NewChecker(reader))
+	defer claims.SetDefault(nil)
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+	r := NewResolver(reader, scheme.Scheme, mapper)
+	ctx := context.Background()
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "widgets", Name: "widget"},
+		ClusterName:    "root:org:team",
+	}
+
+	// The namespace defaults to the one of the referencing object.
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, req, Reference{Name: "local"}, secret); err != nil || secret.Name != "local" {
+		t.Fatalf("unable to get the secret of the workspace of the referencing object: %v", err)
+	}
+	if err := r.Get(ctx, req, Reference{Workspace: "..", Namespace: "default", Name: "credentials"}, secret); err != nil ||
+		secret.Name != "credentials" {
+		t.Fatalf("unable to get the secret of the parent workspace: %v", err)
+	}
+	// An ancestor that has not bound the APIExport cannot be reached.
+	err := r.Get(ctx, req, Reference{Workspace: "..:..", Namespace: "default", Name: "credentials"}, secret)
+	if !claims.IsNotAccepted(err) {
+		t.Errorf("expected a not accepted error for a workspace without APIBinding, got %v", err)
+	}
+	// The workspaces of other tenants are refused before being read.
+	for _, workspace := range []string{"root:other-org:ws", "..:..:sibling-of-other-tenant"} {
+		if err := r.Get(ctx, req, Reference{Workspace: workspace, Name: "credentials"}, secret); err == nil ||
+			claims.IsNotAccepted(err) {
+			t.Errorf("expected workspace %q to be refused, got %v", workspace, err)
+		}
+	}
+
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	h := r.EnqueueReferrers()
+	changed := newSecret("root:org", "default", "credentials")
+	h.Update(event.UpdateEvent{ObjectOld: &changed, ObjectNew: &changed}, q)
+	unrelated := newSecret("root:org:team", "default", "credentials")
+	h.Update(event.UpdateEvent{ObjectOld: &unrelated, ObjectNew: &unrelated}, q)
+	if q.Len() != 1 {
+		t.Fatalf("expected a request for the referencing object only, got %d requests", q.Len())
+	}
+	if item, _ := q.Get(); item != req {
+		t.Errorf("expected request %v, got %v", req, item)
+	}
+	q.Done(req)
+
+	// The references of a deleted object are forgotten.
+	r.Forget(req)
+	h.Update(event.UpdateEvent{ObjectOld: &changed, ObjectNew: &changed}, q)
+	if q.Len() != 0 {
+		t.Errorf("expected no request after Forget, got %d requests", q.Len())
+	}
+}
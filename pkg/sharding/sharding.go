@@ -0,0 +1,452 @@
+// Package sharding partitions the logical clusters between the replicas of a controller.
+//
+// With leader election a single replica reconciles the objects of all the logical clusters. In the sharded
+// mode all the replicas are active: each replica holds a Lease, labelled with the name of the group of
+// replicas and renewed periodically, and the replicas whose Leases have not expired are the members of the
+// group. A logical cluster is owned by a single member, chosen by rendezvous hashing of the name of the
+// logical cluster with the identities of the members, and the other replicas skip its requests, see
+// NewReconciler. When a replica joins or leaves the group only the logical clusters it owns, or is to own,
+// move, and the objects of the logical clusters a replica acquires are requeued by the source returned by
+// Source, as the events of their objects have been skipped until then.
+//
+// A replica stopping gracefully deletes its Lease so that its logical clusters are taken over at the next
+// renewal of the other replicas, a replica failing is removed once its Lease has expired. Until all the
+// replicas have observed a change of the group, two of them may reconcile the same logical cluster, for
+// at most a renewal period.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster/v2"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// GroupLabel is the label of the Leases with the name of the group of replicas.
+	GroupLabel = "sharding.kcp.io/group"
+
+	// DefaultLeaseDuration is the default duration after which the Lease of a replica that has not renewed it expires.
+	DefaultLeaseDuration = 15 * time.Second
+	// DefaultRenewPeriod is the default period of the renewal of the Lease of a replica and of the lookup of the group.
+	DefaultRenewPeriod = 5 * time.Second
+)
+
+// Options configures a Sharder.
+type Options struct {
+	// Name is the name of the group of replicas sharing the logical clusters, it must be a valid label value.
+	Name string
+	// Namespace is the namespace of the Leases.
+	Namespace string
+	// Identity is the identity of the replica. It defaults to the host name with a random suffix.
+	Identity string
+	// LeaseDuration is the duration after which the Lease of a replica that has not renewed it expires.
+	// It defaults to DefaultLeaseDuration.
+	LeaseDuration time.Duration
+	// RenewPeriod is the period of the renewal of the Lease of the replica. It defaults to DefaultRenewPeriod.
+	RenewPeriod time.Duration
+}
+
+// Sharder maintains the Lease of a replica and the members of its group. It is a manager.Runnable
+// that runs on all the replicas.
+type Sharder struct {
+	leases coordinationv1client.LeasesGetter
+	opts   Options
+	now    func() time.Time
+
+	mu sync.RWMutex
+	// members are the sorted identities of the members of the group, including the replica once it holds its Lease.
+	members   []string
+	lastRenew time.Time
+	// subscribers are notified of the changes of the members.
+	subscribers []chan struct{}
+}
+
+var _ manager.Runnable = &Sharder{}
+var _ manager.LeaderElectionRunnable = &Sharder{}
+
+// New returns a Sharder storing the Leases through config.
+func New(config *rest.Config, opts Options) (*Sharder, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the client of the leases: %w", err)
+	}
+	return newSharder(clientset.CoordinationV1(), opts)
+}
+
+func newSharder(leases coordinationv1client.LeasesGetter, opts Options) (*Sharder, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("the name of the group of replicas is required")
+	}
+	if opts.Namespace == "" {
+		return nil, fmt.Errorf("the namespace of the leases is required")
+	}
+	if opts.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("error getting the host name: %w", err)
+		}
+		opts.Identity = strings.ToLower(hostname) + "-" + rand.String(5)
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+	if opts.RenewPeriod <= 0 {
+		opts.RenewPeriod = DefaultRenewPeriod
+	}
+	return &Sharder{leases: leases, opts: opts, now: time.Now}, nil
+}
+
+// Identity returns the identity of the replica.
+func (s *Sharder) Identity() string {
+	return s.opts.Identity
+}
+
+// Members returns the identities of the members of the group.
+func (s *Sharder) Members() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.members
+}
+
+// Owns returns whether the replica owns the logical cluster. The replica does not own any logical cluster
+// until it holds its Lease and has looked up the other members of the group.
+func (s *Sharder) Owns(clusterName string) bool {
+	return owner(s.Members(), clusterName) == s.opts.Identity
+}
+
+// owner returns the member owning the logical cluster: the one with the highest hash of its identity
+// and the name of the logical cluster.
+func owner(members []string, clusterName string) string {
+	var owner string
+	var max uint64
+	for _, member := range members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(member))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(clusterName))
+		if sum := mix(h.Sum64()); owner == "" || sum > max {
+			owner, max = member, sum
+		}
+	}
+	return owner
+}
+
+// mix is the finalizer of MurmurHash3, the sums of FNV alone being too close for strings with the same prefix.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Start renews the Lease of the replica and looks up the members of the group every RenewPeriod until ctx
+// is done, then deletes the Lease.
+func (s *Sharder) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("sharding").WithValues("group", s.opts.Name, "identity", s.opts.Identity)
+
+	ticker := time.NewTicker(s.opts.RenewPeriod)
+	defer ticker.Stop()
+	for {
+		if err := s.sync(ctx, log); err != nil {
+			log.Error(err, "unable to synchronize the members of the group")
+		}
+		select {
+		case <-ctx.Done():
+			s.setMembers(nil)
+			deleteCtx, cancel := context.WithTimeout(context.Background(), s.opts.RenewPeriod)
+			defer cancel()
+			if err := s.leases.Leases(s.opts.Namespace).Delete(deleteCtx, s.leaseName(), metav1.DeleteOptions{}); err != nil &&
+				!apierrors.IsNotFound(err) {
+				return fmt.Errorf("error deleting the lease of the replica: %w", err)
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, all the replicas hold a Lease.
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// sync renews the Lease of the replica and looks up the members of the group.
+func (s *Sharder) sync(ctx context.Context, log logr.Logger) error {
+	if err := s.renew(ctx); err != nil {
+		// The other replicas stop counting the replica as a member once its Lease has expired, and so does it.
+		s.mu.RLock()
+		expired := s.now().Sub(s.lastRenew) > s.opts.LeaseDuration
+		s.mu.RUnlock()
+		if expired {
+			s.setMembers(nil)
+		}
+		return err
+	}
+
+	leases, err := s.leases.Leases(s.opts.Namespace).List(ctx, metav1.ListOptions{LabelSelector: GroupLabel + "=" + s.opts.Name})
+	if err != nil {
+		return fmt.Errorf("error listing the leases of the group: %w", err)
+	}
+	now := s.now()
+	var members []string
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if expiry.Before(now) {
+			// The Leases of the replicas that have failed are removed once they have expired for long.
+			if now.Sub(expiry) > 10*s.opts.LeaseDuration {
+				_ = s.leases.Leases(s.opts.Namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{})
+			}
+			continue
+		}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	sort.Strings(members)
+
+	if s.setMembers(members) {
+		log.Info("The members of the group have changed", "members", members)
+	}
+	return nil
+}
+
+// renew creates or renews the Lease of the replica.
+func (s *Sharder) renew(ctx context.Context) error {
+	leases := s.leases.Leases(s.opts.Namespace)
+	now := metav1.NewMicroTime(s.now())
+
+	lease, err := leases.Get(ctx, s.leaseName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.leaseName(),
+				Namespace: s.opts.Namespace,
+				Labels:    map[string]string{GroupLabel: s.opts.Name},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       pointer.String(s.opts.Identity),
+				LeaseDurationSeconds: pointer.Int32(int32(s.opts.LeaseDuration / time.Second)),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if _, err := leases.Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("error creating the lease of the replica: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("error getting the lease of the replica: %w", err)
+	default:
+		lease.Spec.RenewTime = &now
+		if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("error renewing the lease of the replica: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.lastRenew = now.Time
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Sharder) leaseName() string {
+	return s.opts.Name + "-" + s.opts.Identity
+}
+
+// setMembers sets the members of the group and notifies the subscribers when they have changed.
+func (s *Sharder) setMembers(members []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if equal(s.members, members) {
+		return false
+	}
+	s.members = members
+	for _, subscriber := range s.subscribers {
+		select {
+		case subscriber <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// subscribe returns a channel receiving a value when the members of the group have changed, and initially.
+func (s *Sharder) subscribe() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscriber := make(chan struct{}, 1)
+	subscriber <- struct{}{}
+	s.subscribers = append(s.subscribers, subscriber)
+	return subscriber
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewReconciler wraps r so that the requests of the logical clusters owned by other replicas are skipped.
+func (s *Sharder) NewReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return newReconciler(func() *Sharder { return s }, r)
+}
+
+func newReconciler(sharder func() *Sharder, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		if s := sharder(); s != nil && !s.Owns(req.ClusterName) {
+			return reconcile.Result{}, nil
+		}
+		return r.Reconcile(ctx, req)
+	})
+}
+
+// Source returns a source of generic events for the objects of the logical clusters acquired by the replica
+// when the members of the group change. list is the list type of the objects of the controller, which are
+// listed from c.
+func (s *Sharder) Source(c cache.Cache, list client.ObjectList) source.Source {
+	return &rebalanceSource{sharder: func() *Sharder { return s }, cache: c, list: list}
+}
+
+var (
+	defaultSharderMu sync.RWMutex
+	defaultSharder   *Sharder
+)
+
+// SetDefault sets the Sharder used by NewReconciler and Source. It is called by kcpmanager.NewManager
+// when sharding is enabled. The logical clusters are not partitioned when it is not set.
+func SetDefault(s *Sharder) {
+	defaultSharderMu.Lock()
+	defer defaultSharderMu.Unlock()
+	defaultSharder = s
+}
+
+func getDefault() *Sharder {
+	defaultSharderMu.RLock()
+	defer defaultSharderMu.RUnlock()
+	return defaultSharder
+}
+
+// NewReconciler wraps r so that the requests of the logical clusters owned by other replicas are skipped
+// when a default Sharder is set, see SetDefault.
+func NewReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return newReconciler(getDefault, r)
+}
+
+// Source returns the source of the default Sharder, see Sharder.Source. It does not send any event when
+// no default Sharder is set.
+func Source(c cache.Cache, list client.ObjectList) source.Source {
+	return &rebalanceSource{sharder: getDefault, cache: c, list: list}
+}
+
+// rebalanceSource sends generic events for the objects of the logical clusters acquired by the replica.
+type rebalanceSource struct {
+	sharder func() *Sharder
+	cache   cache.Cache
+	list    client.ObjectList
+}
+
+var _ source.Source = &rebalanceSource{}
+
+// Start implements source.Source.
+func (src *rebalanceSource) Start(ctx context.Context, h handler.EventHandler, q workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	s := src.sharder()
+	if s == nil {
+		return nil
+	}
+	log := logf.FromContext(ctx).WithName("sharding")
+	changes := s.subscribe()
+
+	go func() {
+		if !src.cache.WaitForCacheSync(ctx) {
+			return
+		}
+		var retry <-chan time.Time
+		var previous []string
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+			case <-retry:
+			}
+			members := s.Members()
+			if err := src.enqueueAcquired(ctx, s, previous, members, h, q, prct); err != nil {
+				log.Error(err, "unable to requeue the objects of the acquired logical clusters")
+				retry = time.After(s.opts.RenewPeriod)
+				continue
+			}
+			previous, retry = members, nil
+		}
+	}()
+	return nil
+}
+
+// enqueueAcquired sends generic events for the objects of the logical clusters owned by the replica
+// with members and not with previous.
+func (src *rebalanceSource) enqueueAcquired(ctx context.Context, s *Sharder, previous, members []string,
+	h handler.EventHandler, q workqueue.RateLimitingInterface, prct []predicate.Predicate) error {
+	list, ok := src.list.DeepCopyObject().(client.ObjectList)
+	if !ok {
+		return fmt.Errorf("unexpected list type %T", src.list)
+	}
+	if err := src.cache.List(ctx, list); err != nil {
+		return fmt.Errorf("error listing the objects: %w", err)
+	}
+	return meta.EachListItem(list, func(o runtime.Object) error {
+		obj, ok := o.(client.Object)
+		if !ok {
+			return nil
+		}
+		clusterName := logicalcluster.From(obj).String()
+		if owner(members, clusterName) != s.opts.Identity || owner(previous, clusterName) == s.opts.Identity {
+			return nil
+		}
+		evt := event.GenericEvent{Object: obj}
+		for _, p := range prct {
+			if !p.Generic(evt) {
+				return nil
+			}
+		}
+		h.Generic(evt, q)
+		return nil
+	})
+}
@@ -0,0 +1,248 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newTestSharders returns sharders with the identities sharing a fake clientset and a fake clock.
+func newTestSharders(t *testing.T, identities ...string) ([]*Sharder, *time.Time) {
+	t.Helper()
+
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	clientset := fake.NewSimpleClientset()
+	var sharders []*Sharder
+	for _, identity := range identities {
+		s, err := newSharder(clientset.CoordinationV1(), Options{Name: "86f835c3.example.com", Namespace: "system", Identity: identity})
+		if err != nil {
+			t.Fatalf("unable to create the sharder: %v", err)
+		}
+		s.now = func() time.Time { return now }
+		sharders = append(sharders, s)
+	}
+	return sharders, &now
+}
+
+// syncAll synchronizes the sharders twice, so that each of them observes the Leases of the others.
+func syncAll(t *testing.T, sharders ...*Sharder) {
+	t.Helper()
+	for i := 0; i < 2; i++ {
+		for _, s := range sharders {
+			if err := s.sync(context.Background(), logr.Discard()); err != nil {
+				t.Fatalf("unable to synchronize %s: %v", s.Identity(), err)
+			}
+		}
+	}
+}
+
+// owned returns the number of the logical clusters owned by each sharder.
+func owned(t *testing.T, clusters int, sharders ...*Sharder) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	for i := 0; i < clusters; i++ {
+		clusterName := fmt.Sprintf("root:org:ws-%d", i)
+		var owners []string
+		for _, s := range sharders {
+			if s.Owns(clusterName) {
+				owners = append(owners, s.Identity())
+			}
+		}
+		if len(owners) != 1 {
+			t.Fatalf("expected logical cluster %s to be owned by a single replica, got %v", clusterName, owners)
+		}
+		counts[owners[0]]++
+	}
+	return counts
+}
+
+func TestPartition(t *testing.T) {
+	sharders, _ := newTestSharders(t, "a", "b", "c")
+	syncAll(t, sharders...)
+
+	counts := owned(t, 300, sharders...)
+	for _, s := range sharders {
+		if counts[s.Identity()] < 50 {
+			t.Errorf("expected the logical clusters to be spread over the replicas, got %v", counts)
+		}
+	}
+}
+
+func TestNotOwnedBeforeSync(t *testing.T) {
+	sharders, _ := newTestSharders(t, "a")
+	if sharders[0].Owns("root:org:ws") {
+		t.Errorf("expected the replica not to own any logical cluster before it holds its lease")
+	}
+}
+
+func TestRebalanceOnScaleUp(t *testing.T) {
+	sharders, _ := newTestSharders(t, "a", "b", "c")
+	syncAll(t, sharders[:2]...)
+
+	before := map[string]string{}
+	for i := 0; i < 300; i++ {
+		clusterName := fmt.Sprintf("root:org:ws-%d", i)
+		before[clusterName] = owner(sharders[0].Members(), clusterName)
+	}
+	syncAll(t, sharders...)
+
+	// Only the logical clusters acquired by the new replica move.
+	for clusterName, previous := range before {
+		if current := owner(sharders[0].Members(), clusterName); current != previous && current != "c" {
+			t.Errorf("expected logical cluster %s to stay with %s or move to c, got %s", clusterName, previous, current)
+		}
+	}
+	if counts := owned(t, 300, sharders...); counts["c"] == 0 {
+		t.Errorf("expected the new replica to acquire logical clusters, got %v", counts)
+	}
+}
+
+func TestRebalanceOnScaleDown(t *testing.T) {
+	sharders, _ := newTestSharders(t, "a", "b")
+	syncAll(t, sharders...)
+
+	// b stops gracefully and deletes its lease.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sharders[1].Start(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	syncAll(t, sharders[0])
+
+	if counts := owned(t, 100, sharders...); counts["a"] != 100 {
+		t.Errorf("expected the remaining replica to own all the logical clusters, got %v", counts)
+	}
+}
+
+func TestExpiredLease(t *testing.T) {
+	sharders, now := newTestSharders(t, "a", "b")
+	syncAll(t, sharders...)
+
+	// b fails and stops renewing its lease.
+	*now = now.Add(DefaultLeaseDuration + time.Second)
+	syncAll(t, sharders[0])
+
+	if members := sharders[0].Members(); len(members) != 1 || members[0] != "a" {
+		t.Errorf("expected the failed replica to be removed from the group, got %v", members)
+	}
+}
+
+func TestNewReconciler(t *testing.T) {
+	sharders, _ := newTestSharders(t, "a", "b")
+	syncAll(t, sharders...)
+
+	var reconciled []string
+	r := reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		reconciled = append(reconciled, req.ClusterName)
+		return reconcile.Result{}, nil
+	})
+
+	SetDefault(sharders[0])
+	t.Cleanup(func() { SetDefault(nil) })
+	for i := 0; i < 100; i++ {
+		if _, err := NewReconciler(r).Reconcile(context.Background(), reconcile.Request{ClusterName: fmt.Sprintf("root:org:ws-%d", i)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for _, clusterName := range reconciled {
+		if !sharders[0].Owns(clusterName) {
+			t.Errorf("expected the requests of logical cluster %s owned by another replica to be skipped", clusterName)
+		}
+	}
+	if len(reconciled) == 0 || len(reconciled) == 100 {
+		t.Errorf("expected the requests of the logical clusters of the replica only to be reconciled, got %d", len(reconciled))
+	}
+}
+
+// fakeCache lists the config maps it is created with.
+type fakeCache struct {
+	cache.Cache
+	configMaps []corev1.ConfigMap
+}
+
+func (c *fakeCache) WaitForCacheSync(context.Context) bool {
+	return true
+}
+
+func (c *fakeCache) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*corev1.ConfigMapList).Items = c.configMaps
+	return nil
+}
+
+func TestSource(t *testing.T) {
+	sharders, _ := newTestSharders(t, "a", "b")
+	syncAll(t, sharders[0])
+
+	var configMaps []corev1.ConfigMap
+	for i := 0; i < 100; i++ {
+		configMaps = append(configMaps, corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:        "widget",
+			Annotations: map[string]string{logicalcluster.AnnotationKey: fmt.Sprintf("root:org:ws-%d", i)},
+		}})
+	}
+
+	events := make(chan string, 200)
+	h := handler.Funcs{GenericFunc: func(evt event.GenericEvent, _ workqueue.RateLimitingInterface) {
+		events <- logicalcluster.From(evt.Object).String()
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src := sharders[0].Source(&fakeCache{configMaps: configMaps}, &corev1.ConfigMapList{})
+	if err := src.Start(ctx, h, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a owns all the logical clusters initially.
+	if got := receive(t, events, 100); len(got) != 100 {
+		t.Fatalf("expected the objects of all the logical clusters to be requeued, got %d", len(got))
+	}
+
+	// Then b joins, a does not acquire any logical cluster.
+	syncAll(t, sharders...)
+	if got := receive(t, events, 0); len(got) != 0 {
+		t.Errorf("expected no object to be requeued, got %v", got)
+	}
+
+	// Then b leaves, a acquires the logical clusters of b.
+	expected := owned(t, 100, sharders...)["b"]
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	if err := sharders[1].Start(ctx2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	syncAll(t, sharders[0])
+	if got := receive(t, events, expected); len(got) != expected {
+		t.Errorf("expected the objects of the %d acquired logical clusters to be requeued, got %d", expected, len(got))
+	}
+}
+
+// receive returns the events received until n have been or none has been for a while.
+func receive(t *testing.T, events <-chan string, n int) []string {
+	t.Helper()
+	var got []string
+	for {
+		timeout := 100 * time.Millisecond
+		if len(got) < n {
+			timeout = 5 * time.Second
+		}
+		select {
+		case clusterName := <-events:
+			got = append(got, clusterName)
+		case <-time.After(timeout):
+			return got
+		}
+	}
+}
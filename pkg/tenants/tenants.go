@@ -0,0 +1,273 @@
+// Package tenants selects the logical clusters, the tenants, whose objects are reconciled by the controllers.
+//
+// The logical clusters can be selected by name, with lists of allowed and denied logical clusters, and,
+// when connected to kcp, with a label selector on the APIBindings of the APIExport of the controller. A tenant
+// pauses the reconciliation of its objects with the PausedAnnotation on its APIBinding. The reconciler returned by
+// NewReconciler skips the requests of the other logical clusters, including the ones queued or requeued before
+// their logical cluster was paused or denied, and the ones of the watches mapping the objects of a logical cluster
+// to objects of another one. The filter is also meant to be set as the predicate of the watch of the objects of
+// the controller, see Predicate, so that their events are dropped before being queued. When a logical cluster
+// becomes selected, e.g. when the annotation is removed, the objects of the logical cluster are requeued by the
+// source returned by Source.
+package tenants
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/internal/flags"
+)
+
+// PausedAnnotation pauses the reconciliation of the objects of the logical cluster of an APIBinding when "true".
+const PausedAnnotation = "tenants.kcp.io/paused"
+
+// Options configures a Filter.
+type Options struct {
+	// Allow are the names of the logical clusters reconciled. All the logical clusters are reconciled when empty.
+	Allow []string
+	// Deny are the names of the logical clusters that are not reconciled.
+	Deny []string
+	// Selector is the label selector of the APIBindings of the logical clusters reconciled, when connected to kcp.
+	Selector string
+}
+
+// BindFlags binds the options to the flags of fs.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.Var((*flags.List)(&o.Allow), "tenants-allow",
+		"Comma separated names of the logical clusters reconciled. All the logical clusters are reconciled when empty.")
+	fs.Var((*flags.List)(&o.Deny), "tenants-deny", "Comma separated names of the logical clusters that are not reconciled.")
+	fs.StringVar(&o.Selector, "tenants-selector", "",
+		"The label selector of the APIBindings of the logical clusters reconciled when connected to kcp.")
+}
+
+// Filter selects the logical clusters reconciled by the controllers.
+type Filter struct {
+	allow    map[string]bool
+	deny     map[string]bool
+	selector labels.Selector
+	// reader reads the APIBindings of the logical clusters, it is nil when not connected to kcp.
+	reader client.Reader
+}
+
+// New returns a Filter reading the APIBindings from reader, the cache of a cluster aware manager. reader is nil
+// when not connected to kcp, the logical clusters are then only selected by name.
+func New(reader client.Reader, opts Options) (*Filter, error) {
+	selector, err := labels.Parse(opts.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the label selector %q: %w", opts.Selector, err)
+	}
+	f := &Filter{
+		allow:    map[string]bool{},
+		deny:     map[string]bool{},
+		selector: selector,
+		reader:   reader,
+	}
+	for _, clusterName := range opts.Allow {
+		f.allow[clusterName] = true
+	}
+	for _, clusterName := range opts.Deny {
+		f.deny[clusterName] = true
+	}
+	return f, nil
+}
+
+// Selected returns whether the objects of the logical cluster are reconciled.
+func (f *Filter) Selected(ctx context.Context, clusterName string) bool {
+	if (len(f.allow) > 0 && !f.allow[clusterName]) || f.deny[clusterName] {
+		return false
+	}
+	if f.reader == nil {
+		return true
+	}
+
+	bindings := &apisv1alpha1.APIBindingList{}
+	if err := f.reader.List(logicalcluster.WithCluster(ctx, logicalcluster.New(clusterName)), bindings); err != nil {
+		// The objects of the logical cluster are not reconciled rather than reconciled against the selection.
+		logf.FromContext(ctx).WithName("tenants").Error(err, "unable to list the APIBindings", "clusterName", clusterName)
+		return false
+	}
+	return selected(f.selector, bindings.Items)
+}
+
+// selected returns whether one of the APIBindings of a logical cluster matches selector and none is paused.
+func selected(selector labels.Selector, bindings []apisv1alpha1.APIBinding) bool {
+	matches := false
+	for i := range bindings {
+		if bindings[i].Annotations[PausedAnnotation] == "true" {
+			return false
+		}
+		if selector.Matches(labels.Set(bindings[i].Labels)) {
+			matches = true
+		}
+	}
+	return matches || (selector.Empty() && len(bindings) == 0)
+}
+
+// Predicate returns the event filter dropping the events of the objects of the logical clusters not selected.
+func (f *Filter) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return f.Selected(context.Background(), logicalcluster.From(obj).String())
+	})
+}
+
+// NewReconciler wraps r so that the requests of the logical clusters not selected are skipped.
+func (f *Filter) NewReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return newReconciler(func() *Filter { return f }, r)
+}
+
+func newReconciler(filter func() *Filter, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		if f := filter(); f != nil && !f.Selected(ctx, req.ClusterName) {
+			return reconcile.Result{}, nil
+		}
+		return r.Reconcile(ctx, req)
+	})
+}
+
+// Source returns a source of generic events for the objects of the logical clusters that become selected,
+// after a change of their APIBindings. list is the list type of the objects of the controller, which are
+// listed from c.
+func (f *Filter) Source(c cache.Cache, list client.ObjectList) source.Source {
+	return &selectionSource{filter: func() *Filter { return f }, cache: c, list: list}
+}
+
+var (
+	defaultFilterMu sync.RWMutex
+	defaultFilter   *Filter
+)
+
+// SetDefault sets the Filter used by Predicate and Source. It is called by kcpmanager.NewManager.
+// All the logical clusters are selected when it is not set.
+func SetDefault(f *Filter) {
+	defaultFilterMu.Lock()
+	defer defaultFilterMu.Unlock()
+	defaultFilter = f
+}
+
+func getDefault() *Filter {
+	defaultFilterMu.RLock()
+	defer defaultFilterMu.RUnlock()
+	return defaultFilter
+}
+
+// Predicate returns the event filter of the default Filter, see Filter.Predicate. It does not drop any event
+// when no default Filter is set.
+func Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		f := getDefault()
+		return f == nil || f.Selected(context.Background(), logicalcluster.From(obj).String())
+	})
+}
+
+// NewReconciler wraps r so that the requests of the logical clusters not selected by the default Filter are skipped,
+// see Filter.NewReconciler. It does not skip any request when no default Filter is set.
+func NewReconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return newReconciler(getDefault, r)
+}
+
+// Source returns the source of the default Filter, see Filter.Source. It does not send any event when no default
+// Filter is set or when not connected to kcp.
+func Source(c cache.Cache, list client.ObjectList) source.Source {
+	return &selectionSource{filter: getDefault, cache: c, list: list}
+}
+
+// selectionSource sends generic events for the objects of the logical clusters that become selected.
+type selectionSource struct {
+	filter func() *Filter
+	cache  cache.Cache
+	list   client.ObjectList
+}
+
+var _ source.Source = &selectionSource{}
+
+// Start implements source.Source.
+func (src *selectionSource) Start(ctx context.Context, h handler.EventHandler, q workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate) error {
+	f := src.filter()
+	if f == nil || f.reader == nil {
+		return nil
+	}
+	informer, err := src.cache.GetInformer(ctx, &apisv1alpha1.APIBinding{})
+	if err != nil {
+		return fmt.Errorf("error getting the informer of the APIBindings: %w", err)
+	}
+
+	log := logf.FromContext(ctx).WithName("tenants")
+	var mu sync.Mutex
+	// previous is whether the logical clusters were selected at the last change of their APIBindings.
+	previous := map[string]bool{}
+	changed := func(obj interface{}) {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		binding, ok := obj.(client.Object)
+		if !ok {
+			return
+		}
+		clusterName := logicalcluster.From(binding).String()
+		isSelected := f.Selected(ctx, clusterName)
+
+		mu.Lock()
+		wasSelected, known := previous[clusterName]
+		previous[clusterName] = isSelected
+		mu.Unlock()
+
+		// The objects of the logical clusters observed for the first time are queued by their own informers.
+		if !known || wasSelected || !isSelected {
+			return
+		}
+		if err := src.enqueue(ctx, clusterName, h, q, prct); err != nil {
+			log.Error(err, "unable to requeue the objects of the selected logical cluster", "clusterName", clusterName)
+		}
+	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    changed,
+		UpdateFunc: func(_, obj interface{}) { changed(obj) },
+		DeleteFunc: changed,
+	})
+	return nil
+}
+
+// enqueue sends generic events for the objects of the logical cluster.
+func (src *selectionSource) enqueue(ctx context.Context, clusterName string, h handler.EventHandler,
+	q workqueue.RateLimitingInterface, prct []predicate.Predicate) error {
+	list, ok := src.list.DeepCopyObject().(client.ObjectList)
+	if !ok {
+		return fmt.Errorf("unexpected list type %T", src.list)
+	}
+	if err := src.cache.List(logicalcluster.WithCluster(ctx, logicalcluster.New(clusterName)), list); err != nil {
+		return fmt.Errorf("error listing the objects: %w", err)
+	}
+	return meta.EachListItem(list, func(o runtime.Object) error {
+		obj, ok := o.(client.Object)
+		if !ok {
+			return nil
+		}
+		evt := event.GenericEvent{Object: obj}
+		for _, p := range prct {
+			if !p.Generic(evt) {
+				return nil
+			}
+		}
+		h.Generic(evt, q)
+		return nil
+	})
+}
@@ -0,0 +1,262 @@
+package tenants
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeCache serves the APIBindings and the config maps of the logical clusters it is created with.
+type fakeCache struct {
+	cache.Cache
+	bindings   map[string][]apisv1alpha1.APIBinding
+	configMaps map[string][]corev1.ConfigMap
+	informer   *fakeInformer
+}
+
+func (c *fakeCache) List(ctx context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	clusterName, _ := logicalcluster.ClusterFromContext(ctx)
+	switch l := list.(type) {
+	case *apisv1alpha1.APIBindingList:
+		l.Items = c.bindings[clusterName.String()]
+	case *corev1.ConfigMapList:
+		l.Items = c.configMaps[clusterName.String()]
+	}
+	return nil
+}
+
+func (c *fakeCache) GetInformer(context.Context, client.Object) (cache.Informer, error) {
+	return c.informer, nil
+}
+
+// fakeInformer records the event handler added to it.
+type fakeInformer struct {
+	cache.Informer
+	handler toolscache.ResourceEventHandler
+}
+
+func (i *fakeInformer) AddEventHandler(h toolscache.ResourceEventHandler) {
+	i.handler = h
+}
+
+func newBinding(clusterName string, labels, annotations map[string]string) apisv1alpha1.APIBinding {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[logicalcluster.AnnotationKey] = clusterName
+	return apisv1alpha1.APIBinding{ObjectMeta: metav1.ObjectMeta{Name: "widgets", Labels: labels, Annotations: annotations}}
+}
+
+func newConfigMap(clusterName string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        "widget",
+		Annotations: map[string]string{logicalcluster.AnnotationKey: clusterName},
+	}}
+}
+
+func TestSelected(t *testing.T) {
+	c := &fakeCache{bindings: map[string][]apisv1alpha1.APIBinding{
+		"root:org:canary": {newBinding("root:org:canary", map[string]string{"rollout": "canary"}, nil)},
+		"root:org:stable": {newBinding("root:org:stable", map[string]string{"rollout": "stable"}, nil)},
+		"root:org:paused": {newBinding("root:org:paused", nil, map[string]string{PausedAnnotation: "true"})},
+		"root:org:other":  {newBinding("root:org:other", nil, map[string]string{PausedAnnotation: "false"})},
+	}}
+	tests := []struct {
+		name   string
+		reader client.Reader
+		opts   Options
+		want   map[string]bool
+	}{
+		{
+			name: "not connected to kcp",
+			opts: Options{},
+			want: map[string]bool{"root:org:canary": true, "root:org:paused": true},
+		},
+		{
+			name: "allowed",
+			opts: Options{Allow: []string{"root:org:canary"}},
+			want: map[string]bool{"root:org:canary": true, "root:org:stable": false},
+		},
+		{
+			name: "denied",
+			opts: Options{Allow: []string{"root:org:canary", "root:org:stable"}, Deny: []string{"root:org:stable"}},
+			want: map[string]bool{"root:org:canary": true, "root:org:stable": false},
+		},
+		{
+			name:   "paused",
+			reader: c,
+			opts:   Options{},
+			want:   map[string]bool{"root:org:canary": true, "root:org:paused": false, "root:org:other": true},
+		},
+		{
+			name:   "selector",
+			reader: c,
+			opts:   Options{Selector: "rollout=canary"},
+			want:   map[string]bool{"root:org:canary": true, "root:org:stable": false, "root:org:other": false},
+		},
+		{
+			name:   "selector and denied",
+			reader: c,
+			opts:   Options{Selector: "rollout=canary", Deny: []string{"root:org:canary"}},
+			want:   map[string]bool{"root:org:canary": false},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.reader, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for clusterName, want := range tt.want {
+				if got := f.Selected(context.Background(), clusterName); got != want {
+					t.Errorf("expected logical cluster %s to be selected %t, got %t", clusterName, want, got)
+				}
+				if got := f.Predicate().Generic(event.GenericEvent{Object: newConfigMap(clusterName)}); got != want {
+					t.Errorf("expected the events of logical cluster %s to be kept %t, got %t", clusterName, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidSelector(t *testing.T) {
+	if _, err := New(nil, Options{Selector: "rollout in (canary"}); err == nil {
+		t.Errorf("expected an error for the invalid label selector")
+	}
+}
+
+func TestDefaultPredicate(t *testing.T) {
+	if !Predicate().Generic(event.GenericEvent{Object: newConfigMap("root:org:ws")}) {
+		t.Errorf("expected the events to be kept when no default filter is set")
+	}
+
+	f, err := New(nil, Options{Deny: []string{"root:org:ws"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetDefault(f)
+	t.Cleanup(func() { SetDefault(nil) })
+	if Predicate().Generic(event.GenericEvent{Object: newConfigMap("root:org:ws")}) {
+		t.Errorf("expected the events of the denied logical cluster to be dropped")
+	}
+}
+
+func TestNewReconciler(t *testing.T) {
+	reconciled := map[string]int{}
+	r := reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		reconciled[req.ClusterName]++
+		return reconcile.Result{}, nil
+	})
+	ctx := context.Background()
+	if _, err := NewReconciler(r).Reconcile(ctx, reconcile.Request{ClusterName: "root:org:ws"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reconciled["root:org:ws"] != 1 {
+		t.Errorf("expected the requests to be reconciled when no default filter is set")
+	}
+
+	// The requests queued before their logical cluster is paused, e.g. requeued after a delay, are skipped.
+	binding := newBinding("root:org:ws", nil, nil)
+	reader := &fakeCache{bindings: map[string][]apisv1alpha1.APIBinding{"root:org:ws": {binding}}}
+	f, err := New(reader, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetDefault(f)
+	t.Cleanup(func() { SetDefault(nil) })
+	wrapped := NewReconciler(r)
+	reader.bindings["root:org:ws"][0].Annotations[PausedAnnotation] = "true"
+	if _, err := wrapped.Reconcile(ctx, reconcile.Request{ClusterName: "root:org:ws"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reconciled["root:org:ws"] != 1 {
+		t.Errorf("expected the request of the paused logical cluster to be skipped")
+	}
+	delete(reader.bindings["root:org:ws"][0].Annotations, PausedAnnotation)
+	if _, err := wrapped.Reconcile(ctx, reconcile.Request{ClusterName: "root:org:ws"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reconciled["root:org:ws"] != 2 {
+		t.Errorf("expected the request of the resumed logical cluster to be reconciled")
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	var opts Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	if err := fs.Parse([]string{
+		"--tenants-allow=root:org:a, root:org:b", "--tenants-allow=root:org:c",
+		"--tenants-deny=root:org:b", "--tenants-selector=rollout=canary",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Options{
+		Allow:    []string{"root:org:a", "root:org:b", "root:org:c"},
+		Deny:     []string{"root:org:b"},
+		Selector: "rollout=canary",
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("expected %+v, got %+v", want, opts)
+	}
+}
+
+func TestSource(t *testing.T) {
+	paused := newBinding("root:org:ws", nil, map[string]string{PausedAnnotation: "true"})
+	c := &fakeCache{
+		bindings: map[string][]apisv1alpha1.APIBinding{"root:org:ws": {paused}},
+		configMaps: map[string][]corev1.ConfigMap{
+			"root:org:ws":    {*newConfigMap("root:org:ws"), *newConfigMap("root:org:ws")},
+			"root:org:other": {*newConfigMap("root:org:other")},
+		},
+		informer: &fakeInformer{},
+	}
+	f, err := New(c, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var requeued []string
+	h := handler.Funcs{GenericFunc: func(evt event.GenericEvent, _ workqueue.RateLimitingInterface) {
+		requeued = append(requeued, logicalcluster.From(evt.Object).String())
+	}}
+	if err := f.Source(c, &corev1.ConfigMapList{}).Start(context.Background(), h, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The logical cluster is paused initially.
+	c.informer.handler.OnAdd(&paused)
+	if len(requeued) != 0 {
+		t.Fatalf("expected no object to be requeued, got %v", requeued)
+	}
+
+	// Then the tenant resumes the reconciliation.
+	resumed := newBinding("root:org:ws", nil, nil)
+	c.bindings["root:org:ws"] = []apisv1alpha1.APIBinding{resumed}
+	c.informer.handler.OnUpdate(&paused, &resumed)
+	if want := []string{"root:org:ws", "root:org:ws"}; !reflect.DeepEqual(requeued, want) {
+		t.Errorf("expected the objects of the resumed logical cluster to be requeued, got %v", requeued)
+	}
+
+	// The logical cluster stays selected.
+	requeued = nil
+	c.informer.handler.OnUpdate(&resumed, &resumed)
+	if len(requeued) != 0 {
+		t.Errorf("expected no object to be requeued, got %v", requeued)
+	}
+}
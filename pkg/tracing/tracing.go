@@ -0,0 +1,222 @@
+// Package tracing exports the traces of a controller to an OpenTelemetry collector.
+//
+// Setup installs the global tracer provider, which exports the spans with the OTLP gRPC exporter.
+// The spans are created by the instrumented rest transport, see WrapTransport, by the instrumented
+// client of the manager, see WrapClient, and by the reconciler middleware, see NewReconciler.
+// The spans of the client and of the reconciliations carry the logical cluster of the request
+// so that the requests of a tenant can be followed from the API call to the writes of the controller.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// instrumentationName is the name of the tracer of the package.
+const instrumentationName = "github.com/fgiloux/kcp-operator-sdk/pkg/tracing"
+
+// The attributes of the spans.
+const (
+	ClusterNameKey   = attribute.Key("kcp.cluster.name")
+	APIExportNameKey = attribute.Key("kcp.apiexport.name")
+	GroupKey         = attribute.Key("k8s.group")
+	VersionKey       = attribute.Key("k8s.version")
+	KindKey          = attribute.Key("k8s.kind")
+	NamespaceKey     = attribute.Key("k8s.namespace")
+	NameKey          = attribute.Key("k8s.name")
+)
+
+// Options configures the export of the traces.
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector. Tracing is disabled when empty.
+	Endpoint string
+	// Insecure disables the transport security towards the collector.
+	Insecure bool
+	// ServiceName is the name of the service of the spans.
+	ServiceName string
+	// APIExportName is the name of the APIExport of the controller, recorded in the spans of the reconciliations.
+	APIExportName string
+}
+
+// apiExportName is the name of the APIExport recorded by the reconciler middleware.
+var apiExportName string
+
+// Setup installs the tracer provider exporting the spans to the collector of opts.Endpoint. The returned
+// function flushes the pending spans and stops the exporter. Tracing is disabled when opts.Endpoint is empty.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	apiExportName = opts.APIExportName
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(opts.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating the resource of the traces: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// WrapTransport instruments the requests of rt, it is meant to be passed to rest.Config.Wrap.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt)
+}
+
+// NewReconciler wraps r so that each reconciliation gets a span recording the logical cluster,
+// the APIExport and the group, version and kind of obj, the object of the controller.
+// It is meant to wrap the reconciler returned by clusteraware.NewReconciler. obj may be nil.
+func NewReconciler(scheme *runtime.Scheme, obj client.Object, r reconcile.Reconciler) reconcile.Reconciler {
+	// The kind is only used for the attributes of the spans, a failed lookup is not an error.
+	var gvk schema.GroupVersionKind
+	if obj != nil {
+		gvk, _ = apiutil.GVKForObject(obj, scheme)
+	}
+	name := "Reconcile"
+	if gvk.Kind != "" {
+		name += " " + gvk.Kind
+	}
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		ctx, span := tracer().Start(ctx, name, trace.WithAttributes(
+			ClusterNameKey.String(req.ClusterName),
+			APIExportNameKey.String(apiExportName),
+			GroupKey.String(gvk.Group),
+			VersionKey.String(gvk.Version),
+			KindKey.String(gvk.Kind),
+			NamespaceKey.String(req.Namespace),
+			NameKey.String(req.Name),
+		))
+		defer span.End()
+
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	})
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// WrapClient instruments c so that each request gets a span recording the logical cluster of the context
+// and the object. It is meant to be set as kcpmanager.Options.WrapClient.
+func WrapClient(c client.Client) client.Client {
+	return &tracingClient{Client: c}
+}
+
+type tracingClient struct {
+	client.Client
+}
+
+func (c *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	ctx, span := startClientSpan(ctx, c.Scheme(), "Get", obj, key)
+	return endSpan(span, c.Client.Get(ctx, key, obj))
+}
+
+func (c *tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, span := startClientSpan(ctx, c.Scheme(), "List", list, client.ObjectKey{})
+	return endSpan(span, c.Client.List(ctx, list, opts...))
+}
+
+func (c *tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, span := startClientSpan(ctx, c.Scheme(), "Create", obj, client.ObjectKeyFromObject(obj))
+	return endSpan(span, c.Client.Create(ctx, obj, opts...))
+}
+
+func (c *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, span := startClientSpan(ctx, c.Scheme(), "Delete", obj, client.ObjectKeyFromObject(obj))
+	return endSpan(span, c.Client.Delete(ctx, obj, opts...))
+}
+
+func (c *tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := startClientSpan(ctx, c.Scheme(), "Update", obj, client.ObjectKeyFromObject(obj))
+	return endSpan(span, c.Client.Update(ctx, obj, opts...))
+}
+
+func (c *tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := startClientSpan(ctx, c.Scheme(), "Patch", obj, client.ObjectKeyFromObject(obj))
+	return endSpan(span, c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+func (c *tracingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	ctx, span := startClientSpan(ctx, c.Scheme(), "DeleteAllOf", obj, client.ObjectKey{})
+	return endSpan(span, c.Client.DeleteAllOf(ctx, obj, opts...))
+}
+
+func (c *tracingClient) Status() client.StatusWriter {
+	return &tracingStatusWriter{StatusWriter: c.Client.Status(), scheme: c.Scheme()}
+}
+
+type tracingStatusWriter struct {
+	client.StatusWriter
+	scheme *runtime.Scheme
+}
+
+func (w *tracingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := startClientSpan(ctx, w.scheme, "UpdateStatus", obj, client.ObjectKeyFromObject(obj))
+	return endSpan(span, w.StatusWriter.Update(ctx, obj, opts...))
+}
+
+func (w *tracingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := startClientSpan(ctx, w.scheme, "PatchStatus", obj, client.ObjectKeyFromObject(obj))
+	return endSpan(span, w.StatusWriter.Patch(ctx, obj, patch, opts...))
+}
+
+func startClientSpan(ctx context.Context, scheme *runtime.Scheme, verb string, obj runtime.Object, key client.ObjectKey) (context.Context, trace.Span) {
+	var gvk schema.GroupVersionKind
+	if scheme != nil {
+		gvk, _ = apiutil.GVKForObject(obj, scheme)
+	}
+	clusterName, _ := logicalcluster.ClusterFromContext(ctx)
+	return tracer().Start(ctx, "client."+verb+" "+gvk.Kind, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		ClusterNameKey.String(clusterName.String()),
+		GroupKey.String(gvk.Group),
+		VersionKey.String(gvk.Version),
+		KindKey.String(gvk.Kind),
+		NamespaceKey.String(key.Namespace),
+		NameKey.String(key.Name),
+	))
+}
+
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return err
+}
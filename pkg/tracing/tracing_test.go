@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tracing/tracingtest"
+)
+
+// setup installs a tracer provider exporting to a collector and returns the collector and a function
+// flushing the spans.
+func setup(t *testing.T) (*tracingtest.Collector, func()) {
+	t.Helper()
+
+	collector := tracingtest.NewCollector(t)
+	shutdown, err := Setup(context.Background(), Options{
+		Endpoint:      collector.Endpoint(),
+		Insecure:      true,
+		ServiceName:   "test",
+		APIExportName: "widgets",
+	})
+	if err != nil {
+		t.Fatalf("unable to set up tracing: %v", err)
+	}
+	return collector, func() {
+		if err := shutdown(context.Background()); err != nil {
+			t.Fatalf("unable to flush the spans: %v", err)
+		}
+	}
+}
+
+func TestReconcilerSpan(t *testing.T) {
+	collector, flush := setup(t)
+
+	r := NewReconciler(clientgoscheme.Scheme, &corev1.ConfigMap{}, reconcile.Func(
+		func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+			return reconcile.Result{}, nil
+		}))
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "widget"},
+		ClusterName:    "root:org:tenant",
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flush()
+
+	span, ok := collector.Span("Reconcile ConfigMap")
+	if !ok {
+		t.Fatalf("expected a reconcile span, got %v", collector.Spans())
+	}
+	for key, want := range map[string]string{
+		string(ClusterNameKey):   "root:org:tenant",
+		string(APIExportNameKey): "widgets",
+		string(VersionKey):       "v1",
+		string(KindKey):          "ConfigMap",
+		string(NameKey):          "widget",
+	} {
+		if got, _ := tracingtest.Attribute(span, key); got != want {
+			t.Errorf("expected attribute %s to be %q, got %q", key, want, got)
+		}
+	}
+}
+
+func TestClientSpan(t *testing.T) {
+	collector, flush := setup(t)
+
+	c := WrapClient(fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "widget"},
+	}).Build())
+	ctx := logicalcluster.WithCluster(context.Background(), logicalcluster.New("root:org:tenant"))
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "widget"}, &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flush()
+
+	span, ok := collector.Span("client.Get ConfigMap")
+	if !ok {
+		t.Fatalf("expected a client span, got %v", collector.Spans())
+	}
+	if got, _ := tracingtest.Attribute(span, string(ClusterNameKey)); got != "root:org:tenant" {
+		t.Errorf("expected the span to record cluster root:org:tenant, got %q", got)
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
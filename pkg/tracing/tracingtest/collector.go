@@ -0,0 +1,89 @@
+// Package tracingtest provides an in-memory OpenTelemetry collector for unit testing the export of the traces.
+//
+// The collector serves the OTLP gRPC trace service on a local port and keeps the spans it receives.
+package tracingtest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// Collector is an in-memory OTLP gRPC collector.
+type Collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	listener net.Listener
+
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+// NewCollector starts a collector that is stopped when the test and its subtests complete.
+func NewCollector(t testing.TB) *Collector {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	c := &Collector{listener: listener}
+
+	server := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(server, c)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return c
+}
+
+// Endpoint returns the host:port of the collector. The connections are not secured.
+func (c *Collector) Endpoint() string {
+	return c.listener.Addr().String()
+}
+
+// Export implements the OTLP trace service.
+func (c *Collector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceSpans := range req.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// Spans returns the spans received by the collector.
+func (c *Collector) Spans() []*tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*tracepb.Span{}, c.spans...)
+}
+
+// Span returns the first span received by the collector with the name and whether it was found.
+func (c *Collector) Span(name string) (*tracepb.Span, bool) {
+	for _, span := range c.Spans() {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return nil, false
+}
+
+// Attribute returns the string value of the attribute of span with the key and whether it was found.
+func Attribute(span *tracepb.Span, key string) (string, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value.GetStringValue(), true
+		}
+	}
+	return "", false
+}
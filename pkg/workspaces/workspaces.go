@@ -0,0 +1,55 @@
+// Package workspaces resolves the names of the logical clusters, which identify them in the requests of the
+// controllers, to the paths of their workspaces, e.g. root:org:team, which identify them for the people reading
+// the logs.
+//
+// With kcp v0.9 the name of a logical cluster is the path of its workspace and workspaces cannot be renamed: the
+// default Lookup is Identity and kcp v0.9 has no API to look up. Later kcp versions name the logical clusters with
+// opaque identifiers and a Lookup reading the path from the kcp APIs is then set with
+// kcpmanager.Options.WorkspaceLookup. Path is called for each reconciliation: such a Lookup is expected to read
+// from an informer rather than to call the API server.
+package workspaces
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+)
+
+// Lookup returns the path of the workspace of a logical cluster.
+type Lookup func(ctx context.Context, clusterName logicalcluster.Name) (logicalcluster.Name, error)
+
+// Identity is the Lookup of kcp v0.9, where the name of a logical cluster is the path of its workspace.
+func Identity(_ context.Context, clusterName logicalcluster.Name) (logicalcluster.Name, error) {
+	return clusterName, nil
+}
+
+var (
+	defaultLookupMu sync.RWMutex
+	defaultLookup   Lookup = Identity
+)
+
+// SetDefault sets the Lookup used by Path, e.g. by clusteraware.NewReconciler to add the path of the workspace
+// to the logger. It is called by kcpmanager.NewManager. Identity is used when lookup is nil.
+func SetDefault(lookup Lookup) {
+	if lookup == nil {
+		lookup = Identity
+	}
+	defaultLookupMu.Lock()
+	defer defaultLookupMu.Unlock()
+	defaultLookup = lookup
+}
+
+// Path returns the path of the workspace of the logical cluster with the default Lookup.
+func Path(ctx context.Context, clusterName logicalcluster.Name) (logicalcluster.Name, error) {
+	defaultLookupMu.RLock()
+	lookup := defaultLookup
+	defaultLookupMu.RUnlock()
+
+	path, err := lookup(ctx, clusterName)
+	if err != nil {
+		return logicalcluster.Name{}, fmt.Errorf("error looking up the workspace of logical cluster %s: %w", clusterName, err)
+	}
+	return path, nil
+}
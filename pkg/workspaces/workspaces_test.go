@@ -0,0 +1,37 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+)
+
+func TestPath(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+	clusterName := logicalcluster.New("2a8f0c")
+
+	path, err := Path(context.Background(), logicalcluster.New("root:org:team"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path.String() != "root:org:team" {
+		t.Errorf("expected the path to be the name of the logical cluster by default, got %s", path)
+	}
+
+	SetDefault(func(_ context.Context, clusterName logicalcluster.Name) (logicalcluster.Name, error) {
+		return logicalcluster.New("root:org:" + clusterName.String()), nil
+	})
+	if path, err := Path(context.Background(), clusterName); err != nil || path.String() != "root:org:2a8f0c" {
+		t.Errorf("expected path root:org:2a8f0c from the default lookup, got %s, %v", path, err)
+	}
+
+	forbidden := errors.New("forbidden")
+	SetDefault(func(context.Context, logicalcluster.Name) (logicalcluster.Name, error) {
+		return logicalcluster.Name{}, forbidden
+	})
+	if _, err := Path(context.Background(), clusterName); !errors.Is(err, forbidden) {
+		t.Errorf("expected the error of the lookup, got %v", err)
+	}
+}
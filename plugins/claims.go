@@ -0,0 +1,73 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/config"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+	goPlugin "sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang"
+)
+
+// Claim is the group and the resource of a permission claim of the APIExport.
+type Claim struct {
+	// Group is the API group of the resource, empty for the core group.
+	Group string `json:"group"`
+	// Resource is the plural name of the resource.
+	Resource string `json:"resource"`
+}
+
+// EventsClaim is the permission claim of the events, which the controllers record in the workspaces of the tenants.
+var EventsClaim = Claim{Group: "", Resource: "events"}
+
+// ConfigMapsClaim is the permission claim of the configmaps, which the scaffolded tenant lifecycle hooks provision in
+// the workspaces of the tenants.
+var ConfigMapsClaim = Claim{Group: "", Resource: "configmaps"}
+
+// ClaimFor returns the permission claim for the objects of res.
+func ClaimFor(res resource.Resource) Claim {
+	group := res.QualifiedGroup()
+	if res.Group == "core" && res.Domain == "" {
+		group = ""
+	}
+	return Claim{Group: group, Resource: res.Plural}
+}
+
+// IsExternal tells whether res is defined outside of the project, e.g. a core type. The controllers reach
+// the objects of such a resource in the workspaces of the tenants through a permission claim of the APIExport,
+// rather than through an APIResourceSchema.
+func IsExternal(cfg config.Config, res resource.Resource) bool {
+	if res.HasAPI() {
+		return false
+	}
+	r, err := cfg.GetResource(res.GVK)
+	return err != nil || !r.HasAPI()
+}
+
+// ParseResource returns the resource of gvk, written <group>/<version>/<Kind> with the group as in the
+// create api subcommand, e.g. apps/v1/Deployment or core/v1/Service. The resource is either a builtin type
+// or a type defined by the project.
+func ParseResource(cfg config.Config, gvk string) (resource.Resource, error) {
+	parts := strings.Split(gvk, "/")
+	if len(parts) != 3 {
+		return resource.Resource{}, fmt.Errorf("invalid resource %q, expected <group>/<version>/<Kind>", gvk)
+	}
+	res := resource.Resource{
+		GVK: resource.GVK{
+			Group:   parts[0],
+			Domain:  cfg.GetDomain(),
+			Version: parts[1],
+			Kind:    parts[2],
+		},
+		Plural: resource.RegularPlural(parts[2]),
+	}
+	if r, err := cfg.GetResource(res.GVK); err == nil && r.HasAPI() {
+		return r, nil
+	}
+	// Sets the domain and the path of the builtin types.
+	goPlugin.Options{}.UpdateResource(&res, cfg)
+	if res.Path == "" {
+		return resource.Resource{}, fmt.Errorf("resource %q is neither a builtin type nor a type of the project", gvk)
+	}
+	return res, res.Validate()
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/pflag"
 
+	kcpplugins "github.com/fgiloux/kcp-operator-sdk/plugins"
 	"github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3/scaffolds"
 	"sigs.k8s.io/kubebuilder/v3/pkg/config"
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
@@ -43,6 +44,22 @@ type createAPISubcommand struct {
 
 	// runMake indicates whether to run make or not after scaffolding APIs
 	runMake bool
+
+	// withFinalizer indicates whether the controller handles a finalizer on the objects of the resource
+	withFinalizer bool
+
+	// generateClient indicates whether the clients of the resource are generated by make generate-client
+	generateClient bool
+
+	// ownsFlag are the resources owned by the objects of the resource, as <group>/<version>/<Kind>
+	ownsFlag []string
+	// owns are the resources parsed from ownsFlag
+	owns []resource.Resource
+
+	// referencesFlag are the resources referenced by the objects of the resource, as <group>/<version>/<Kind>
+	referencesFlag []string
+	// references are the resources parsed from referencesFlag
+	references []resource.Resource
 }
 
 func (p *createAPISubcommand) UpdateMetadata(cliMeta plugin.CLIMetadata, subcmdMeta *plugin.SubcommandMetadata) {
@@ -52,11 +69,23 @@ If information about whether the resource and controller should be scaffolded
 was not explicitly provided, it will prompt the user if they should be.
 
 After the scaffold is written, the dependencies will be updated and
-make generate will be run.
+make generate will be run, followed by make generate-client with --generate-client.
 `
 	subcmdMeta.Examples = fmt.Sprintf(`  # Create a frigates API with Group: ship, Version: v1beta1 and Kind: Frigate
   %[1]s create api --group ship --version v1beta1 --kind Frigate
 
+  # Create a controller adding a finalizer to the frigates and cleaning up when they are deleted
+  %[1]s create api --group ship --version v1beta1 --kind Frigate --with-finalizer
+
+  # Create a controller for the frigates owning Deployments and Services
+  %[1]s create api --group ship --version v1beta1 --kind Frigate --owns=apps/v1/Deployment,core/v1/Service
+
+  # Create a controller for the frigates reading Secrets, possibly in other workspaces
+  %[1]s create api --group ship --version v1beta1 --kind Frigate --references=core/v1/Secret
+
+  # Create a frigates API with single cluster and cluster aware clients, listers and informers generated into client/
+  %[1]s create api --group ship --version v1beta1 --kind Frigate --generate-client
+
   # Edit the API Scheme
   nano api/v1beta1/frigate_types.go
 
@@ -101,6 +130,18 @@ func (p *createAPISubcommand) BindFlags(fs *pflag.FlagSet) {
 		"if set, generate the controller without prompting the user")
 	p.controllerFlag = fs.Lookup("controller")
 
+	fs.BoolVar(&p.withFinalizer, "with-finalizer", false,
+		"if set, scaffold a controller adding a finalizer to the objects and cleaning up when they are deleted")
+	fs.StringSliceVar(&p.ownsFlag, "owns", nil,
+		"resources owned by the objects, as <group>/<version>/<Kind>, e.g. apps/v1/Deployment or core/v1/Service, "+
+			"whose changes are reconciled by the controller")
+	fs.StringSliceVar(&p.referencesFlag, "references", nil,
+		"resources referenced by the objects, as <group>/<version>/<Kind>, e.g. core/v1/Secret, "+
+			"read by the controller in the workspace of the objects or in other workspaces")
+	fs.BoolVar(&p.generateClient, "generate-client", false,
+		"if set, mark the type for the generation of its clientsets, listers and informers, single cluster and "+
+			"cluster aware, into client/ by make generate-client")
+
 	// (not required raise an error in this case)
 	// nolint:errcheck,gosec
 	fs.MarkDeprecated("crd-version", deprecateMsg)
@@ -134,6 +175,36 @@ func (p *createAPISubcommand) InjectResource(res *resource.Resource) error {
 		return err
 	}
 
+	if p.withFinalizer && (!p.options.DoController || p.resource.Path == "") {
+		return errors.New("--with-finalizer requires a controller for a resource with a Go type")
+	}
+
+	if p.generateClient && !p.options.DoAPI {
+		return errors.New("--generate-client requires the resource to be created")
+	}
+
+	if len(p.ownsFlag) != 0 && (!p.options.DoController || p.resource.Path == "") {
+		return errors.New("--owns requires a controller for a resource with a Go type")
+	}
+	for _, gvk := range p.ownsFlag {
+		owned, err := kcpplugins.ParseResource(p.config, gvk)
+		if err != nil {
+			return fmt.Errorf("invalid --owns: %w", err)
+		}
+		p.owns = append(p.owns, owned)
+	}
+
+	if len(p.referencesFlag) != 0 && (!p.options.DoController || p.resource.Path == "") {
+		return errors.New("--references requires a controller for a resource with a Go type")
+	}
+	for _, gvk := range p.referencesFlag {
+		referenced, err := kcpplugins.ParseResource(p.config, gvk)
+		if err != nil {
+			return fmt.Errorf("invalid --references: %w", err)
+		}
+		p.references = append(p.references, referenced)
+	}
+
 	// In case we want to scaffold a resource API we need to do some checks
 	if p.options.DoAPI {
 		// Check that resource doesn't have the API scaffolded or flag force was set
@@ -167,11 +238,62 @@ func (p *createAPISubcommand) PreScaffold(machinery.Filesystem) error {
 }
 
 func (p *createAPISubcommand) Scaffold(fs machinery.Filesystem) error {
-	scaffolder := scaffolds.NewAPIScaffolder(p.config, *p.resource, p.force)
+	var cfg Config
+	if err := p.config.DecodePluginConfig(pluginKey, &cfg); err != nil &&
+		!errors.As(err, &config.UnsupportedFieldError{}) && !errors.As(err, &config.PluginKeyNotFoundError{}) {
+		return err
+	}
+
+	// The objects of the core and external types are reached through permission claims of the APIExport.
+	// The events are recorded in the workspaces of the objects.
+	var claims []kcpplugins.Claim
+	if p.resource.HasController() {
+		claims = append(claims, kcpplugins.EventsClaim)
+	}
+	if kcpplugins.IsExternal(p.config, *p.resource) {
+		claims = append(claims, kcpplugins.ClaimFor(*p.resource))
+	}
+	for _, owned := range p.owns {
+		if kcpplugins.IsExternal(p.config, owned) {
+			claims = append(claims, kcpplugins.ClaimFor(owned))
+		}
+	}
+	for _, referenced := range p.references {
+		if kcpplugins.IsExternal(p.config, referenced) {
+			claims = append(claims, kcpplugins.ClaimFor(referenced))
+		}
+	}
+	if len(claims) != 0 {
+		cfg.PermissionClaims = appendClaims(cfg.PermissionClaims, claims...)
+		if err := p.config.EncodePluginConfig(pluginKey, cfg); err != nil {
+			return err
+		}
+	}
+
+	// The tenants of the end-to-end tests accept all the permission claims of the APIExport.
+	scaffolder := scaffolds.NewAPIScaffolder(p.config, *p.resource, p.force, cfg.Tracing, cfg.TenantLifecycle,
+		p.withFinalizer, p.generateClient, p.owns, p.references, cfg.PermissionClaims)
 	scaffolder.InjectFS(fs)
 	return scaffolder.Scaffold()
 }
 
+// appendClaims appends to claims the new ones.
+func appendClaims(claims []kcpplugins.Claim, newClaims ...kcpplugins.Claim) []kcpplugins.Claim {
+	for _, newClaim := range newClaims {
+		found := false
+		for _, claim := range claims {
+			if claim == newClaim {
+				found = true
+				break
+			}
+		}
+		if !found {
+			claims = append(claims, newClaim)
+		}
+	}
+	return claims
+}
+
 func (p *createAPISubcommand) PostScaffold() error {
 
 	// Update the makefile to allow generate Webhooks to ensure backwards compatibility
@@ -192,6 +314,12 @@ func (p *createAPISubcommand) PostScaffold() error {
 		if err != nil {
 			return err
 		}
+		if p.generateClient {
+			err = util.RunCmd("Generating the clients", "make", "generate-client")
+			if err != nil {
+				return err
+			}
+		}
 		fmt.Print("Next: implement your new API and generate the manifests (e.g. APIResourceSchema, CRDs,CRs) with:\n$ make manifests apiresourceschemas\n")
 	}
 
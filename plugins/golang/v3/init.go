@@ -1,6 +1,7 @@
 package v3
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +16,7 @@ import (
 	"sigs.k8s.io/kubebuilder/v3/pkg/plugin/util"
 	"sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang"
 	// "sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang/v3/scaffolds"
+	kcpplugins "github.com/fgiloux/kcp-operator-sdk/plugins"
 	"github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3/scaffolds"
 )
 
@@ -41,6 +43,8 @@ type initSubcommand struct {
 	// flags
 	fetchDeps          bool
 	skipGoVersionCheck bool
+	tracing            bool
+	tenantLifecycle    bool
 }
 
 func (p *initSubcommand) UpdateMetadata(cliMeta plugin.CLIMetadata, subcmdMeta *plugin.SubcommandMetadata) {
@@ -58,6 +62,12 @@ func (p *initSubcommand) UpdateMetadata(cliMeta plugin.CLIMetadata, subcmdMeta *
 
   # Initialize a new project defining a specific project version
   %[1]s init --plugins go/v3 --project-version 3
+
+  # Initialize a new project exporting OpenTelemetry traces
+  %[1]s init --plugins go/v3 --domain example.org --tracing
+
+  # Initialize a new project with the hooks onboarding and offboarding the tenants binding the APIExport
+  %[1]s init --plugins go/v3 --domain example.org --tenant-lifecycle
 `, cliMeta.CommandName)
 }
 
@@ -73,6 +83,14 @@ func (p *initSubcommand) BindFlags(fs *pflag.FlagSet) {
 		"license to use to boilerplate, may be one of 'apache2', 'none'")
 	fs.StringVar(&p.owner, "owner", "", "owner to add to the copyright")
 
+	// tracing args
+	fs.BoolVar(&p.tracing, "tracing", false, "if specified, the manager exports OpenTelemetry traces "+
+		"of the reconciliations and of the requests of its client")
+
+	// tenant lifecycle args
+	fs.BoolVar(&p.tenantLifecycle, "tenant-lifecycle", false, "if specified, scaffold the hooks onboarding and "+
+		"offboarding the tenants binding the APIExport, which delete the objects created for a tenant when it unbinds it")
+
 	// project args
 	fs.StringVar(&p.repo, "repo", "", "name to use for go module (e.g., github.com/user/repo), "+
 		"defaults to the go package of the current working directory.")
@@ -106,13 +124,23 @@ func (p *initSubcommand) PreScaffold(machinery.Filesystem) error {
 }
 
 func (p *initSubcommand) Scaffold(fs machinery.Filesystem) error {
-	scaffolder := scaffolds.NewInitScaffolder(p.config, p.license, p.owner)
+	scaffolder := scaffolds.NewInitScaffolder(p.config, p.license, p.owner, p.tracing, p.tenantLifecycle)
 	scaffolder.InjectFS(fs)
 	err := scaffolder.Scaffold()
 	if err != nil {
 		return err
 	}
 
+	cfg := Config{Tracing: p.tracing, TenantLifecycle: p.tenantLifecycle}
+	if p.tenantLifecycle {
+		// The scaffolded hooks provision a ConfigMap in the workspaces of the tenants.
+		cfg.PermissionClaims = []kcpplugins.Claim{kcpplugins.ConfigMapsClaim}
+	}
+	if err := p.config.EncodePluginConfig(pluginKey, cfg); err != nil &&
+		!errors.As(err, &config.UnsupportedFieldError{}) {
+		return err
+	}
+
 	if !p.fetchDeps {
 		fmt.Println("Skipping fetching dependencies.")
 		return nil
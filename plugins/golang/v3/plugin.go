@@ -1,6 +1,7 @@
 package v3
 
 import (
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
 	"github.com/fgiloux/kcp-operator-sdk/plugins/golang"
 	"sigs.k8s.io/kubebuilder/v3/pkg/config"
 	cfgv3 "sigs.k8s.io/kubebuilder/v3/pkg/config/v3"
@@ -12,6 +13,7 @@ const pluginName = "base." + golang.DefaultNameQualifier
 var (
 	pluginVersion            = plugin.Version{Number: 3}
 	supportedProjectVersions = []config.Version{cfgv3.Version}
+	pluginKey                = plugin.KeyFor(Plugin{})
 )
 
 // FGI: starting simple
@@ -47,3 +49,14 @@ func (p Plugin) GetCreateAPISubcommand() plugin.CreateAPISubcommand { return &p.
 
 // GetEditSubcommand will return the subcommand which is responsible for editing the scaffold of the project
 // func (p Plugin) GetEditSubcommand() plugin.EditSubcommand { return &p.editSubcommand }
+
+// Config is the configuration of the plugin stored in the PROJECT file.
+type Config struct {
+	// Tracing is true if the project is scaffolded with OpenTelemetry tracing.
+	Tracing bool `json:"tracing,omitempty"`
+	// TenantLifecycle is true if the project is scaffolded with the hooks onboarding and offboarding the tenants.
+	TenantLifecycle bool `json:"tenantLifecycle,omitempty"`
+	// PermissionClaims are the permission claims of the APIExport needed by the controllers, for the core
+	// and external types they reconcile or own. They are added to the APIExport by the manifests plugin.
+	PermissionClaims []plugins.Claim `json:"permissionClaims,omitempty"`
+}
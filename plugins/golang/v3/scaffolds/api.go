@@ -2,6 +2,7 @@ package scaffolds
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/spf13/afero"
 
@@ -14,6 +15,8 @@ import (
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
 	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
 	"sigs.k8s.io/kubebuilder/v3/pkg/plugins"
+
+	kcpplugins "github.com/fgiloux/kcp-operator-sdk/plugins"
 )
 
 var _ plugins.Scaffolder = &apiScaffolder{}
@@ -29,14 +32,44 @@ type apiScaffolder struct {
 
 	// force indicates whether to scaffold controller files even if it exists or not
 	force bool
+
+	// tracing indicates whether the project was initialized with OpenTelemetry tracing
+	tracing bool
+
+	// tenantLifecycle indicates whether the project was initialized with the hooks onboarding and offboarding the tenants
+	tenantLifecycle bool
+
+	// withFinalizer indicates whether the controller handles a finalizer on the objects of the resource
+	withFinalizer bool
+
+	// generateClient indicates whether the clients of the resource are generated by make generate-client
+	generateClient bool
+
+	// owns are the resources owned by the objects of the resource
+	owns []resource.Resource
+
+	// references are the resources referenced by the objects of the resource
+	references []resource.Resource
+
+	// claims are the permission claims of the APIExport needed by the controllers of the project
+	claims []kcpplugins.Claim
 }
 
 // NewAPIScaffolder returns a new Scaffolder for API/controller creation operations
-func NewAPIScaffolder(config config.Config, res resource.Resource, force bool) plugins.Scaffolder {
+func NewAPIScaffolder(config config.Config, res resource.Resource,
+	force, tracing, tenantLifecycle, withFinalizer, generateClient bool,
+	owns, references []resource.Resource, claims []kcpplugins.Claim) plugins.Scaffolder {
 	return &apiScaffolder{
-		config:   config,
-		resource: res,
-		force:    force,
+		config:          config,
+		resource:        res,
+		force:           force,
+		tracing:         tracing,
+		tenantLifecycle: tenantLifecycle,
+		withFinalizer:   withFinalizer,
+		generateClient:  generateClient,
+		owns:            owns,
+		references:      references,
+		claims:          claims,
 	}
 }
 
@@ -72,23 +105,71 @@ func (s *apiScaffolder) Scaffold() error {
 
 	if doAPI {
 		if err := scaffold.Execute(
-			&api.Types{Force: s.force},
+			&api.Types{Force: s.force, GenerateClient: s.generateClient},
 			&api.Group{},
 		); err != nil {
 			return fmt.Errorf("error scaffolding APIs: %v", err)
 		}
 	}
 
+	// The generated clients require the versions of client-go and of the kcp libraries they are generated for,
+	// go mod tidy adds back the other dependencies of the project.
+	if s.generateClient {
+		if err := scaffold.Execute(
+			&api.GroupClient{},
+			&templates.GoMod{
+				ControllerRuntimeVersion: ControllerRuntimeVersion,
+				GenerateClient:           true,
+				ClientGoVersion:          ClientGoVersion,
+				LogicalClusterVersion:    LogicalClusterVersion,
+				KCPAPIMachineryVersion:   KCPAPIMachineryVersion,
+			},
+		); err != nil {
+			return fmt.Errorf("error scaffolding the dependencies of the clients: %v", err)
+		}
+	}
+
 	if doController {
+		// The owned and referenced core and external types are reached through permission claims of the APIExport.
+		var controllerClaims []kcpplugins.Claim
+		for _, res := range append(append([]resource.Resource{}, s.owns...), s.references...) {
+			if kcpplugins.IsExternal(s.config, res) {
+				controllerClaims = append(controllerClaims, kcpplugins.ClaimFor(res))
+			}
+		}
+
 		if err := scaffold.Execute(
 			&controllers.SuiteTest{Force: s.force},
-			&controllers.Controller{ControllerRuntimeVersion: ControllerRuntimeVersion, Force: s.force},
-			&e2e.E2ETest{},
-			&e2e.APIBinding{},
+			&controllers.Controller{ControllerRuntimeVersion: ControllerRuntimeVersion, Force: s.force, Tracing: s.tracing,
+				WithFinalizer: s.withFinalizer, Owns: s.owns, References: s.references, Claims: controllerClaims},
+			&e2e.E2ETest{Claims: s.claims},
+			&e2e.APIBinding{Claims: s.claims},
 			&e2e.Audit{},
 		); err != nil {
 			return fmt.Errorf("error scaffolding controller: %v", err)
 		}
+
+		if s.withFinalizer {
+			if err := scaffold.Execute(
+				&controllers.FinalizerTest{Force: s.force, WithReferences: len(s.references) != 0},
+				&e2e.FinalizerTest{},
+			); err != nil {
+				return fmt.Errorf("error scaffolding the finalizer tests: %v", err)
+			}
+		}
+
+		// The tenant lifecycle is tested once, along the end-to-end tests of the first controller.
+		if s.tenantLifecycle {
+			tested, err := s.tenantLifecycleTested()
+			if err != nil {
+				return err
+			}
+			if !tested {
+				if err := scaffold.Execute(&e2e.TenantLifecycleTest{}); err != nil {
+					return fmt.Errorf("error scaffolding the tenant lifecycle test: %v", err)
+				}
+			}
+		}
 	}
 
 	if err := scaffold.Execute(
@@ -99,3 +180,21 @@ func (s *apiScaffolder) Scaffold() error {
 
 	return nil
 }
+
+// tenantLifecycleTested tells whether the end-to-end test of the tenant lifecycle is already scaffolded, in the
+// package of the end-to-end tests or, for multi-group projects, in the one of a group.
+func (s *apiScaffolder) tenantLifecycleTested() (bool, error) {
+	for _, pattern := range []string{
+		filepath.Join("test", "e2e", "tenantlifecycle_test.go"),
+		filepath.Join("test", "e2e", "*", "tenantlifecycle_test.go"),
+	} {
+		matches, err := afero.Glob(s.fs.FS, pattern)
+		if err != nil {
+			return false, fmt.Errorf("error looking for the tenant lifecycle test: %w", err)
+		}
+		if len(matches) != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
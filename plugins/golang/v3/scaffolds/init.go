@@ -16,6 +16,8 @@ import (
 	// "sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang/v3/scaffolds/internal/templates"
 	// "sigs.k8s.io/kubebuilder/v3/pkg/plugins/golang/v3/scaffolds/internal/templates/hack"
 	"github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3/scaffolds/internal/templates"
+	configtemplates "github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3/scaffolds/internal/templates/config"
+	"github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3/scaffolds/internal/templates/controllers"
 	"github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3/scaffolds/internal/templates/hack"
 )
 
@@ -31,6 +33,17 @@ const (
 	Registry               = "localhost"
 	ImageName              = "controller:0.1"
 	EnvTestK8s             = "1.25"
+
+	// KCPCodeGeneratorVersion is the version of the code generators of kcp generating the cluster aware clients
+	KCPCodeGeneratorVersion = "v2.0.0-alpha.1"
+	// LogicalClusterVersion and KCPAPIMachineryVersion are the versions of github.com/kcp-dev/logicalcluster/v2 and
+	// github.com/kcp-dev/apimachinery the cluster aware clients are generated for by KCPCodeGeneratorVersion, the
+	// ones of the kcp fork of controller-runtime
+	LogicalClusterVersion  = "v2.0.0-alpha.3"
+	KCPAPIMachineryVersion = "v0.0.0-20220922165458-607ac5e87531"
+	// ClientGoVersion is the version of k8s.io/client-go, k8s.io/api and k8s.io/apimachinery the single cluster
+	// clients are generated for, the one of the kcp fork of controller-runtime
+	ClientGoVersion = "v0.24.3"
 )
 
 var _ plugins.Scaffolder = &initScaffolder{}
@@ -42,18 +55,24 @@ type initScaffolder struct {
 	boilerplatePath string
 	license         string
 	owner           string
+	// tracing indicates whether the manager exports OpenTelemetry traces
+	tracing bool
+	// tenantLifecycle indicates whether the manager runs the hooks onboarding and offboarding the tenants
+	tenantLifecycle bool
 
 	// fs is the filesystem that will be used by the scaffolder
 	fs machinery.Filesystem
 }
 
 // NewInitScaffolder returns a new Scaffolder for project initialization operations
-func NewInitScaffolder(config config.Config, license, owner string) plugins.Scaffolder {
+func NewInitScaffolder(config config.Config, license, owner string, tracing, tenantLifecycle bool) plugins.Scaffolder {
 	return &initScaffolder{
 		config:          config,
 		boilerplatePath: hack.DefaultBoilerplatePath,
 		license:         license,
 		owner:           owner,
+		tracing:         tracing,
+		tenantLifecycle: tenantLifecycle,
 	}
 }
 
@@ -107,8 +126,27 @@ func (s *initScaffolder) Scaffold() error {
 		}
 	}
 
+	if s.config.IsComponentConfig() {
+		// The component configuration is loaded into the ProjectConfig kind of the project rather than the one of
+		// controller-runtime, the manager ConfigMap is rendered from it.
+		if err := scaffold.Execute(
+			&configtemplates.ProjectConfigGroup{},
+			&configtemplates.ProjectConfigTypes{},
+			&configtemplates.ControllerManagerConfig{Tracing: s.tracing},
+		); err != nil {
+			return err
+		}
+	}
+
+	if s.tenantLifecycle {
+		if err := scaffold.Execute(&controllers.TenantLifecycle{}); err != nil {
+			return err
+		}
+	}
+
 	return scaffold.Execute(
-		&templates.Main{},
+		&templates.Main{Tracing: s.tracing, TenantLifecycle: s.tenantLifecycle},
+		&templates.MainTest{Tracing: s.tracing},
 		&templates.GoMod{
 			ControllerRuntimeVersion: ControllerRuntimeVersion,
 		},
@@ -122,8 +160,12 @@ func (s *initScaffolder) Scaffold() error {
 			ControllerRuntimeVersion: ControllerRuntimeVersion,
 			KCPVersion:               KCPVersion,
 			YQVersion:                YQVersion,
+			KCPCodeGeneratorVersion:  KCPCodeGeneratorVersion,
+			LogicalClusterVersion:    LogicalClusterVersion,
+			KCPAPIMachineryVersion:   KCPAPIMachineryVersion,
 			EnvTestK8s:               EnvTestK8s,
 		},
+		&hack.UpdateCodegen{},
 		&templates.Dockerfile{},
 		&templates.DockerIgnore{},
 		&templates.Readme{},
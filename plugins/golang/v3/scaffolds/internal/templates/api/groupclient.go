@@ -0,0 +1,55 @@
+package api
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &GroupClient{}
+
+// GroupClient scaffolds the file that defines the helpers of a certain group and version expected by the clients,
+// listers and informers generated by make generate-client
+type GroupClient struct {
+	machinery.TemplateMixin
+	machinery.MultiGroupMixin
+	machinery.BoilerplateMixin
+	machinery.ResourceMixin
+}
+
+// SetTemplateDefaults implements file.Template
+func (f *GroupClient) SetTemplateDefaults() error {
+	if f.Path == "" {
+		if f.MultiGroup {
+			if f.Resource.Group != "" {
+				f.Path = filepath.Join("apis", "%[group]", "%[version]", "groupversion_client.go")
+			} else {
+				f.Path = filepath.Join("apis", "%[version]", "groupversion_client.go")
+			}
+		} else {
+			f.Path = filepath.Join("api", "%[version]", "groupversion_client.go")
+		}
+	}
+	f.Path = f.Resource.Replacer().Replace(f.Path)
+
+	f.TemplateBody = groupClientTemplate
+
+	return nil
+}
+
+const groupClientTemplate = `{{ .Boilerplate }}
+
+package {{ .Resource.Version }}
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SchemeGroupVersion is the group version of the generated clients.
+var SchemeGroupVersion = GroupVersion
+
+// Resource takes an unqualified resource and returns a group qualified GroupResource, for the generated listers.
+func Resource(resource string) schema.GroupResource {
+	return GroupVersion.WithResource(resource).GroupResource()
+}
+`
@@ -18,6 +18,9 @@ type Types struct {
 	machinery.ResourceMixin
 
 	Force bool
+
+	// GenerateClient indicates whether the clients of the resource are generated by make generate-client
+	GenerateClient bool
 }
 
 // SetTemplateDefaults implements file.Template
@@ -67,14 +70,38 @@ type {{ .Resource.Kind }}Spec struct {
 	Foo string ` + "`" + `json:"foo,omitempty"` + "`" + `
 }
 
+// The types of the conditions of {{ .Resource.Kind }}.
+const (
+	// {{ .Resource.Kind }}Ready is True when the spec of the observed generation is applied.
+	{{ .Resource.Kind }}Ready = "Ready"
+	// {{ .Resource.Kind }}Progressing is True while a new generation of the spec is being applied.
+	{{ .Resource.Kind }}Progressing = "Progressing"
+)
+
 // {{ .Resource.Kind }}Status defines the observed state of {{ .Resource.Kind }}
 type {{ .Resource.Kind }}Status struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-}
 
+	// Conditions are the latest observations of the state of the {{ .Resource.Kind }}.
+	//+listType=map
+	//+listMapKey=type
+	//+patchStrategy=merge
+	//+patchMergeKey=type
+	//+optional
+	Conditions []metav1.Condition ` + "`" + `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"` + "`" + `
+}
+{{ if .GenerateClient }}
+//+genclient
+{{- if not .Resource.API.Namespaced }}
+//+genclient:nonNamespaced
+{{- end }}
+{{- end }}
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 {{- if and (not .Resource.API.Namespaced) (not .Resource.IsRegularPlural) }}
 //+kubebuilder:resource:path={{ .Resource.Plural }},scope=Cluster
 {{- else if not .Resource.API.Namespaced }}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
+)
+
+var _ machinery.Template = &ControllerManagerConfig{}
+
+// ControllerManagerConfig scaffolds the component configuration of the manager in the config/manager folder.
+// It replaces the one of the kustomize plugin as main.go loads the ProjectConfig kind of the project, which
+// extends the one of controller-runtime with the kcp specific behaviours of the manager.
+type ControllerManagerConfig struct {
+	machinery.TemplateMixin
+	machinery.DomainMixin
+	machinery.RepositoryMixin
+	machinery.ProjectNameMixin
+
+	// Mode selects whether the manager is cluster aware
+	Mode string
+
+	// APIExportName is the name of the APIExport watched by the manager
+	APIExportName string
+
+	// Tracing indicates whether the manager exports OpenTelemetry traces
+	Tracing bool
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *ControllerManagerConfig) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("config", "manager", "controller_manager_config.yaml")
+	}
+
+	if f.Mode == "" {
+		f.Mode = "auto"
+	}
+
+	f.TemplateBody = plugins.ProjectConfigTemplate
+
+	f.IfExistsAction = machinery.OverwriteFile
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &ProjectConfigGroup{}
+
+// ProjectConfigGroup scaffolds the file that defines the registration methods of the group of the component
+// configuration of the manager
+type ProjectConfigGroup struct {
+	machinery.TemplateMixin
+	machinery.BoilerplateMixin
+	machinery.DomainMixin
+}
+
+// SetTemplateDefaults implements file.Template
+func (f *ProjectConfigGroup) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("config", "v1alpha1", "groupversion_info.go")
+	}
+
+	f.TemplateBody = projectConfigGroupTemplate
+
+	return nil
+}
+
+//nolint:lll
+const projectConfigGroupTemplate = `{{ .Boilerplate }}
+
+// Package v1alpha1 contains the component configuration of the manager, loaded from the file of the --config flag.
+// It is not served as an API: no CustomResourceDefinition is generated for it.
+//+kubebuilder:object:generate=true
+//+kubebuilder:skip
+//+groupName=config.{{ .Domain }}
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is group version used to register these objects
+	GroupVersion = schema.GroupVersion{Group: "config.{{ .Domain }}", Version: "v1alpha1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+`
+
+var _ machinery.Template = &ProjectConfigTypes{}
+
+// ProjectConfigTypes scaffolds the file that defines the ProjectConfig type, the component configuration of the
+// manager, which extends the one of controller-runtime with the kcp specific behaviours of the manager
+type ProjectConfigTypes struct {
+	machinery.TemplateMixin
+	machinery.BoilerplateMixin
+}
+
+// SetTemplateDefaults implements file.Template
+func (f *ProjectConfigTypes) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("config", "v1alpha1", "projectconfig_types.go")
+	}
+
+	f.TemplateBody = projectConfigTypesTemplate
+
+	return nil
+}
+
+//nolint:lll
+const projectConfigTypesTemplate = `{{ .Boilerplate }}
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+
+	configv1alpha1 "github.com/fgiloux/kcp-operator-sdk/pkg/config/v1alpha1"
+)
+
+//+kubebuilder:object:root=true
+
+// ProjectConfig is the component configuration of the manager. It extends the configuration of controller-runtime
+// with the kcp specific behaviours of the manager. It is validated when it is loaded, see Complete.
+type ProjectConfig struct {
+	metav1.TypeMeta ` + "`" + `json:",inline"` + "`" + `
+
+	// ControllerManagerConfigurationSpec returns the configurations for controllers
+	cfg.ControllerManagerConfigurationSpec ` + "`" + `json:",inline"` + "`" + `
+
+	// KCPConfig configures the kcp specific behaviours of the manager: mode, apiExportName, sharding and tenants,
+	// among others.
+	configv1alpha1.KCPConfig ` + "`" + `json:",inline"` + "`" + `
+
+	// TODO(user): add the configuration of the controllers of the project.
+}
+
+// Validate returns the errors of the configuration.
+func (c *ProjectConfig) Validate() error {
+	errs := c.KCPConfig.Validate()
+	// TODO(user): validate the configuration of the controllers of the project.
+	return errs.ToAggregate()
+}
+
+// Complete validates the configuration and returns the one of controller-runtime. It is called when the
+// configuration is loaded.
+func (c *ProjectConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	if err := c.Validate(); err != nil {
+		return cfg.ControllerManagerConfigurationSpec{}, fmt.Errorf("invalid %s: %w", c.Kind, err)
+	}
+	return c.ControllerManagerConfigurationSpec, nil
+}
+
+func init() {
+	SchemeBuilder.Register(&ProjectConfig{})
+}
+`
@@ -3,8 +3,12 @@ package controllers
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
 )
 
 var _ machinery.Template = &Controller{}
@@ -16,10 +20,29 @@ type Controller struct {
 	machinery.MultiGroupMixin
 	machinery.BoilerplateMixin
 	machinery.ResourceMixin
+	machinery.DomainMixin
 
 	ControllerRuntimeVersion string
 
 	Force bool
+
+	// Tracing indicates whether the project was initialized with OpenTelemetry tracing
+	Tracing bool
+
+	// WithFinalizer indicates whether the controller handles a finalizer on the objects of the resource
+	WithFinalizer bool
+
+	// Owns are the resources owned by the objects of the resource
+	Owns []resource.Resource
+	// References are the resources referenced by the objects of the resource
+	References []resource.Resource
+	// BuiltinImports and ProjectImports are the packages of the owned and referenced builtin resources and of the
+	// other owned and referenced resources, by import alias
+	BuiltinImports map[string]string
+	ProjectImports map[string]string
+	// Claims are the permission claims of the owned and referenced resources, which the tenants must accept before
+	// the objects of the resource are reconciled
+	Claims []plugins.Claim
 }
 
 // SetTemplateDefaults implements file.Template
@@ -34,6 +57,23 @@ func (f *Controller) SetTemplateDefaults() error {
 	f.Path = f.Resource.Replacer().Replace(f.Path)
 	fmt.Println(f.Path)
 
+	f.BuiltinImports = make(map[string]string)
+	f.ProjectImports = make(map[string]string)
+	for _, res := range append(append([]resource.Resource{}, f.Owns...), f.References...) {
+		switch {
+		case res.Path == f.Resource.Path:
+		case strings.HasPrefix(res.Path, "k8s.io/"):
+			f.BuiltinImports[res.ImportAlias()] = res.Path
+		default:
+			f.ProjectImports[res.ImportAlias()] = res.Path
+		}
+	}
+
+	// The claims that are not accepted are reported in the status of the objects of the resource.
+	if !f.Resource.HasAPI() {
+		f.Claims = nil
+	}
+
 	f.TemplateBody = controllerTemplate
 
 	if f.Force {
@@ -53,25 +93,103 @@ package {{ if and .MultiGroup .Resource.Group }}{{ .Resource.PackageName }}{{ el
 import (
 	"context"
 
-	"github.com/kcp-dev/logicalcluster/v2"
+	{{ if .Resource.HasAPI -}}
+	"k8s.io/apimachinery/pkg/api/equality"
+	{{ end -}}
+	{{ if or .WithFinalizer .Resource.HasAPI -}}
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	{{ end -}}
+	{{ if .Resource.HasAPI -}}
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	{{ end -}}
 	"k8s.io/apimachinery/pkg/runtime"
+	{{- if .Claims }}
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	{{- end }}
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	{{- if not (isEmptyStr .Resource.Path) }}
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	{{- end }}
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	{{- if .WithFinalizer }}
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	{{- end }}
+	{{- if not (isEmptyStr .Resource.Path) }}
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	{{- end }}
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	{{- if or .Owns .References }}
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	{{- end }}
+	{{- range $alias, $path := .BuiltinImports }}
+	{{ $alias }} "{{ $path }}"
+	{{- end }}
+
+	{{ if .Claims -}}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/claims"
+	{{ end -}}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusteraware"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusterratelimit"
+	{{- if .References }}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/references"
+	{{- end }}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+	{{- if .Tracing }}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tracing"
+	{{- end }}
 	{{ if not (isEmptyStr .Resource.Path) -}}
 	{{ .Resource.ImportAlias }} "{{ .Resource.Path }}"
 	{{- end }}
+	{{- range $alias, $path := .ProjectImports }}
+	{{ $alias }} "{{ $path }}"
+	{{- end }}
 )
 
+{{ if .WithFinalizer -}}
+// {{ .Resource.Kind }}Finalizer is the finalizer added to the {{ .Resource.Kind }} objects, so that their deletion
+// waits for the cleanup of the controller.
+const {{ .Resource.Kind }}Finalizer = "{{ if .Resource.Domain }}{{ .Resource.QualifiedGroup }}{{ else }}{{ .Resource.Group }}.{{ .Domain }}{{ end }}/finalizer"
+
+{{ end -}}
+{{ if .Claims -}}
+// {{ lower .Resource.Kind }}Claims are the resources reached in the workspaces of the tenants through the permission
+// claims of the APIExport, which the tenants must accept before the {{ .Resource.Kind }} objects are reconciled.
+var {{ lower .Resource.Kind }}Claims = []schema.GroupResource{
+	{{- range .Claims }}
+	{Group: "{{ .Group }}", Resource: "{{ .Resource }}"},
+	{{- end }}
+}
+
+{{ end -}}
 // {{ .Resource.Kind }}Reconciler reconciles a {{ .Resource.Kind }} object
 type {{ .Resource.Kind }}Reconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Recorder records the events of the reconciled objects in their workspaces, where the tenants see them, e.g.
+	//	r.Recorder.Event(obj, corev1.EventTypeNormal, "Reconciled", "The spec is applied")
+	Recorder record.EventRecorder
+	{{- if .References }}
+
+	// resolver reads the objects referenced by the {{ .Resource.Kind }} objects, see SetupWithManager.
+	resolver *references.Resolver
+	{{- end }}
 }
 
 //+kubebuilder:rbac:groups={{ .Resource.QualifiedGroup }},resources={{ .Resource.Plural }},verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups={{ .Resource.QualifiedGroup }},resources={{ .Resource.Plural }}/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups={{ .Resource.QualifiedGroup }},resources={{ .Resource.Plural }}/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+{{- range .Owns }}
+//+kubebuilder:rbac:groups={{ .QualifiedGroup }},resources={{ .Plural }},verbs=get;list;watch;create;update;patch;delete
+{{- end }}
+{{- range .References }}
+//+kubebuilder:rbac:groups={{ .QualifiedGroup }},resources={{ .Plural }},verbs=get;list;watch
+{{- end }}
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -83,30 +201,222 @@ type {{ .Resource.Kind }}Reconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@{{ .ControllerRuntimeVersion }}/pkg/reconcile
 func (r *{{ .Resource.Kind }}Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	// The logger and the context are scoped to the logical cluster of the request, see SetupWithManager.
 	logger := log.FromContext(ctx)
-
-	// Include the clusterName from req.ObjectKey in the logger, similar to the namespace and name keys that are already
-        // there.
-        logger = logger.WithValues("clusterName", req.ClusterName)
 	logger.V(1).Info("Starting reconcile")
+{{- if or .WithFinalizer .Resource.HasAPI }}
+
+	// The client scopes its requests to the logical cluster of ctx: always pass ctx, never a new context.
+	{{ lower .Resource.Kind }} := &{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}{}
+	if err := r.Get(ctx, req.NamespacedName, {{ lower .Resource.Kind }}); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The object was deleted{{ if .WithFinalizer }} after its cleanup{{ end }}, there is nothing left to do.
+			{{- if .References }}
+			r.resolver.Forget(req)
+			{{- end }}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+{{- end }}
+{{- if .WithFinalizer }}
 
-	// Add the logical cluster to the context
-        ctx = logicalcluster.WithCluster(ctx, logicalcluster.New(req.ClusterName))
+	if !{{ lower .Resource.Kind }}.GetDeletionTimestamp().IsZero() {
+		if controllerutil.ContainsFinalizer({{ lower .Resource.Kind }}, {{ .Resource.Kind }}Finalizer) {
+			logger.Info("Cleaning up before deletion")
+			if err := r.cleanup(ctx, {{ lower .Resource.Kind }}); err != nil {
+				return ctrl.Result{}, err
+			}
+			controllerutil.RemoveFinalizer({{ lower .Resource.Kind }}, {{ .Resource.Kind }}Finalizer)
+			if err := r.Update(ctx, {{ lower .Resource.Kind }}); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer({{ lower .Resource.Kind }}, {{ .Resource.Kind }}Finalizer) {
+		controllerutil.AddFinalizer({{ lower .Resource.Kind }}, {{ .Resource.Kind }}Finalizer)
+		if err := r.Update(ctx, {{ lower .Resource.Kind }}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+{{- end }}
+{{- if .Claims }}
+
+	// The {{ .Resource.Kind }} waits until the tenant accepts the permission claims. Ready tells the tenant which claim
+	// to accept, and the claims are checked again after claims.RecheckInterval.
+	if err := claims.CheckCurrent(ctx, {{ lower .Resource.Kind }}Claims...); err != nil {
+		if !claims.IsNotAccepted(err) {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Waiting for the permission claims to be accepted", "reason", err.Error())
+		if err := r.updateStatus(ctx, {{ lower .Resource.Kind }}, metav1.Condition{
+			Type:    {{ .Resource.ImportAlias }}.{{ .Resource.Kind }}Ready,
+			Status:  metav1.ConditionFalse,
+			Reason:  claims.NotAcceptedReason,
+			Message: err.Error(),
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: claims.RecheckInterval}, nil
+	}
+{{- end }}
+{{- if .Resource.HasAPI }}
+
+	// Progressing is set when a new generation of the spec is observed, until it is applied.
+	ready := meta.FindStatusCondition({{ lower .Resource.Kind }}.Status.Conditions, {{ .Resource.ImportAlias }}.{{ .Resource.Kind }}Ready)
+	if ready == nil || ready.ObservedGeneration != {{ lower .Resource.Kind }}.GetGeneration() {
+		if err := r.updateStatus(ctx, {{ lower .Resource.Kind }}, metav1.Condition{
+			Type:    {{ .Resource.ImportAlias }}.{{ .Resource.Kind }}Progressing,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Reconciling",
+			Message: "The spec is being applied",
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// TODO(user): your logic here. When it fails, set Ready to False with a reason telling the tenant what
+	// is wrong before returning the error, e.g.
+	//	_ = r.updateStatus(ctx, {{ lower .Resource.Kind }}, metav1.Condition{Type: {{ .Resource.ImportAlias }}.{{ .Resource.Kind }}Ready,
+	//		Status: metav1.ConditionFalse, Reason: "Failed", Message: err.Error()})
+{{- template "ownsExample" . }}
+{{- template "referencesExample" . }}
+
+	if err := r.updateStatus(ctx, {{ lower .Resource.Kind }}, metav1.Condition{
+		Type:    {{ .Resource.ImportAlias }}.{{ .Resource.Kind }}Ready,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Reconciled",
+		Message: "The spec is applied",
+	}, metav1.Condition{
+		Type:    {{ .Resource.ImportAlias }}.{{ .Resource.Kind }}Progressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Reconciled",
+		Message: "The spec is applied",
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+{{- else }}
 
 	// TODO(user): your logic here
+{{- template "ownsExample" . }}
+{{- template "referencesExample" . }}
+{{- end }}
 
 	return ctrl.Result{}, nil
 }
+{{- if .Resource.HasAPI }}
+
+// updateStatus sets the conditions of {{ lower .Resource.Kind }} for its current generation and patches its status.
+// The status is not patched when it is unchanged, so that the reconciliation does not trigger itself.
+func (r *{{ .Resource.Kind }}Reconciler) updateStatus(ctx context.Context, {{ lower .Resource.Kind }} *{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}, conditions ...metav1.Condition) error {
+	original := {{ lower .Resource.Kind }}.DeepCopy()
+	for _, condition := range conditions {
+		condition.ObservedGeneration = {{ lower .Resource.Kind }}.GetGeneration()
+		meta.SetStatusCondition(&{{ lower .Resource.Kind }}.Status.Conditions, condition)
+	}
+	if equality.Semantic.DeepEqual(original.Status, {{ lower .Resource.Kind }}.Status) {
+		return nil
+	}
+	return r.Status().Patch(ctx, {{ lower .Resource.Kind }}, client.MergeFrom(original))
+}
+{{- end }}
+{{- if .WithFinalizer }}
+
+// cleanup is called before the deletion of {{ lower .Resource.Kind }}, whose finalizer is removed when it succeeds.
+func (r *{{ .Resource.Kind }}Reconciler) cleanup(ctx context.Context, {{ lower .Resource.Kind }} *{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}) error {
+	// TODO(user): release the resources of {{ lower .Resource.Kind }} outside of its workspace, e.g. in an external
+	// system. The objects of the workspace it owns are garbage collected.
+	return nil
+}
+{{- end }}
 
 // SetupWithManager sets up the controller with the Manager.
+// The reconciler is wrapped by clusteraware.NewReconciler, which scopes the context and the logger
+// to the logical cluster of each request and recovers from panics, and by clustermetrics.NewReconciler,
+// which records the reconciliations per logical cluster.
+{{- if .Tracing }}
+// Each reconciliation gets a span from tracing.NewReconciler.
+{{- end }}
+// The requests are admitted by a clusterratelimit.Limiter, which gives each logical cluster its own token bucket:
+// the requests of a logical cluster creating many objects are requeued and spread at the rate of its bucket.
+// The workqueue of the controller is replaced by the clusterratelimit.Queue of the limiter, which has a sub-queue
+// per logical cluster and hands out their requests in turn, so that the requests of the other logical clusters do
+// not wait behind the backlog of a logical cluster. Its depth per logical cluster is exposed by clustermetrics.
+// When sharding is enabled, the requests of the logical clusters owned by other replicas are skipped by
+// sharding.NewReconciler and the objects of the logical clusters acquired by the replica are requeued by
+// sharding.Source.
+// The requests of the logical clusters not selected by the tenants flags, or paused by their APIBinding, are
+// skipped by tenants.NewReconciler, including the requests queued before the logical cluster was paused and the
+// ones mapped from the changes of other objects. The events of their objects are dropped by tenants.Predicate and
+// the objects of the logical clusters that become selected are requeued by tenants.Source.
+{{- if .Owns }}
+// The changes of the owned objects are mapped to their owner in the same logical cluster by
+// clusteraware.EnqueueRequestForOwner.
+{{- end }}
+{{- if .References }}
+// The changes of the referenced objects, possibly in other workspaces, are mapped to the objects referencing them
+// by the resolver.
+{{- end }}
 func (r *{{ .Resource.Kind }}Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	{{- if .References }}
+	r.resolver = references.NewResolver(mgr.GetClient(), mgr.GetScheme(), mgr.GetRESTMapper())
+	{{- end }}
+	limiter := clusterratelimit.NewLimiter()
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		{{ if not (isEmptyStr .Resource.Path) -}}
-		For(&{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}{}).
+		For(&{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}{}, builder.WithPredicates(tenants.Predicate())).
+		Watches(sharding.Source(mgr.GetCache(), &{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}List{}), &handler.EnqueueRequestForObject{}).
+		Watches(tenants.Source(mgr.GetCache(), &{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}List{}), &handler.EnqueueRequestForObject{}).
+		{{- range .Owns }}
+		Watches(&source.Kind{Type: &{{ .ImportAlias }}.{{ .Kind }}{}},
+			clusteraware.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), &{{ $.Resource.ImportAlias }}.{{ $.Resource.Kind }}{})).
+		{{- end }}
+		{{- range .References }}
+		Watches(&source.Kind{Type: &{{ .ImportAlias }}.{{ .Kind }}{}}, r.resolver.EnqueueReferrers()).
+		{{- end }}
 		{{- else -}}
 		// Uncomment the following line adding a pointer to an instance of the controlled resource as an argument
 		// For().
 		{{- end }}
-		Complete(r)
+		WithOptions(controller.Options{RateLimiter: limiter.RateLimiter()}).
+		{{- if .Tracing }}
+		Build(sharding.NewReconciler(tenants.NewReconciler(limiter.NewReconciler(clustermetrics.NewReconciler("{{ lower .Resource.Kind }}", tracing.NewReconciler(mgr.GetScheme(),
+			{{ if not (isEmptyStr .Resource.Path) }}&{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}{}{{ else }}nil{{ end }}, clusteraware.NewReconciler(r)))))))
+		{{- else }}
+		Build(sharding.NewReconciler(tenants.NewReconciler(limiter.NewReconciler(clustermetrics.NewReconciler("{{ lower .Resource.Kind }}", clusteraware.NewReconciler(r))))))
+		{{- end }}
+	if err != nil {
+		return err
+	}
+	return limiter.SetQueue(c, "{{ lower .Resource.Kind }}")
 }
+{{- define "ownsExample" }}
+{{- if .Owns }}
+{{- with index .Owns 0 }}
+
+	// The objects created for the {{ $.Resource.Kind }} are owned by it, so that their changes are reconciled and they
+	// are garbage collected with it. clusteraware.SetControllerReference checks that they are in the same workspace.
+	//	{{ lower .Kind }} := &{{ .ImportAlias }}.{{ .Kind }}{ObjectMeta: metav1.ObjectMeta{Namespace: req.Namespace, Name: req.Name}}
+	//	if err := clusteraware.SetControllerReference(ctx, {{ lower $.Resource.Kind }}, {{ lower .Kind }}, r.Scheme); err != nil {
+	//		return ctrl.Result{}, err
+	//	}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- define "referencesExample" }}
+{{- if .References }}
+{{- with index .References 0 }}
+
+	// The objects referenced by the {{ $.Resource.Kind }} are read with r.resolver, in its workspace or in the workspace
+	// of the reference, relative to it. The resolver checks that the APIExport can reach them there and requeues the
+	// {{ $.Resource.Kind }} when they change. The reference is typically a references.Reference field of the spec, e.g.
+	//	{{ lower .Kind }} := &{{ .ImportAlias }}.{{ .Kind }}{}
+	//	if err := r.resolver.Get(ctx, req, references.Reference{Workspace: "..", Name: "shared"}, {{ lower .Kind }}); err != nil {
+	//		return ctrl.Result{}, err
+	//	}
+{{- end }}
+{{- end }}
+{{- end }}
 `
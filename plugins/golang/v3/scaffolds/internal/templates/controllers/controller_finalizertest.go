@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &FinalizerTest{}
+
+// FinalizerTest scaffolds the file that tests the finalizer handling of the controller against the test environment
+// set up by the suite
+// nolint:maligned
+type FinalizerTest struct {
+	machinery.TemplateMixin
+	machinery.MultiGroupMixin
+	machinery.BoilerplateMixin
+	machinery.ResourceMixin
+
+	Force bool
+
+	// WithReferences indicates whether the reconciler has a resolver of the referenced objects
+	WithReferences bool
+}
+
+// SetTemplateDefaults implements file.Template
+func (f *FinalizerTest) SetTemplateDefaults() error {
+	if f.Path == "" {
+		if f.MultiGroup && f.Resource.Group != "" {
+			f.Path = filepath.Join("controllers", "%[group]", "%[kind]_controller_test.go")
+		} else {
+			f.Path = filepath.Join("controllers", "%[kind]_controller_test.go")
+		}
+	}
+	f.Path = f.Resource.Replacer().Replace(f.Path)
+
+	f.TemplateBody = finalizerTestTemplate
+
+	if f.Force {
+		f.IfExistsAction = machinery.OverwriteFile
+	}
+
+	return nil
+}
+
+const finalizerTestTemplate = `{{ .Boilerplate }}
+
+package {{ if and .MultiGroup .Resource.Group }}{{ .Resource.PackageName }}{{ else }}controllers{{ end }}
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusteraware"
+	{{- if .WithReferences }}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/references"
+	{{- end }}
+	{{ .Resource.ImportAlias }} "{{ .Resource.Path }}"
+)
+
+var _ = Describe("{{ .Resource.Kind }} controller", func() {
+	It("adds the finalizer and removes it after the cleanup", func() {
+		ctx := context.Background()
+		// The reconciler is wrapped as in SetupWithManager, the requests of the test environment have no logical cluster.
+		reconciler := clusteraware.NewReconciler(&{{ .Resource.Kind }}Reconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: record.NewFakeRecorder(16),
+			{{- if .WithReferences }}
+			resolver: references.NewResolver(k8sClient, k8sClient.Scheme(), k8sClient.RESTMapper()),
+			{{- end }}
+		})
+
+		{{ lower .Resource.Kind }} := &{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-{{ lower .Resource.Kind }}",
+				{{- if or (not .Resource.API) .Resource.API.Namespaced }}
+				Namespace: "default",
+				{{- end }}
+			},
+		}
+		key := client.ObjectKeyFromObject({{ lower .Resource.Kind }})
+		req := ctrl.Request{NamespacedName: key}
+
+		By("reconciling the created {{ .Resource.Kind }}")
+		Expect(k8sClient.Create(ctx, {{ lower .Resource.Kind }})).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, {{ lower .Resource.Kind }})).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer({{ lower .Resource.Kind }}, {{ .Resource.Kind }}Finalizer)).To(BeTrue())
+
+		By("reconciling the deleted {{ .Resource.Kind }}")
+		Expect(k8sClient.Delete(ctx, {{ lower .Resource.Kind }})).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, key, {{ lower .Resource.Kind }})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("reconciling the {{ .Resource.Kind }} once it is gone")
+		_, err = reconciler.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+`
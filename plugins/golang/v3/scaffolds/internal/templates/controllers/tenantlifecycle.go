@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &TenantLifecycle{}
+
+// TenantLifecycle scaffolds the file that defines the hooks onboarding and offboarding the tenants, the logical
+// clusters binding the APIExport
+type TenantLifecycle struct {
+	machinery.TemplateMixin
+	machinery.BoilerplateMixin
+	machinery.ProjectNameMixin
+}
+
+// SetTemplateDefaults implements file.Template
+func (f *TenantLifecycle) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("controllers", "tenantlifecycle.go")
+	}
+
+	f.TemplateBody = tenantLifecycleTemplate
+
+	return nil
+}
+
+const tenantLifecycleTemplate = `{{ .Boilerplate }}
+
+package controllers
+
+import (
+	"context"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/lifecycle"
+)
+
+// TenantLifecycleOwner is the value of the lifecycle.ManagedByLabel label of the objects created for the tenants,
+// which are deleted when they unbind the APIExport.
+const TenantLifecycleOwner = "{{ .ProjectName }}"
+
+// TenantConfigMapName is the name of the ConfigMap provisioned in the default namespace of each tenant.
+const TenantConfigMapName = "{{ .ProjectName }}-tenant"
+
+// TenantLifecycle onboards and offboards the tenants, the logical clusters binding the APIExport.
+// The hooks are called with a context scoped to the logical cluster of the tenant and must be idempotent.
+type TenantLifecycle struct {
+	client.Client
+}
+
+var _ lifecycle.Hooks = &TenantLifecycle{}
+
+//+kubebuilder:rbac:groups=apis.kcp.dev,resources=apibindings,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;delete
+
+// Bound onboards a tenant when it binds the APIExport.
+func (t *TenantLifecycle) Bound(ctx context.Context, binding *apisv1alpha1.APIBinding) error {
+	// TODO(user): provision the state of the tenant, e.g. a default configuration or an account in an external
+	// system. The objects created in the workspace of the tenant are labeled with lifecycle.SetManagedBy, so that
+	// they are deleted when it unbinds the APIExport. Their resources must be claimed by the APIExport.
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      TenantConfigMapName,
+		},
+	}
+	lifecycle.SetManagedBy(cm, TenantLifecycleOwner)
+	if err := t.Create(ctx, cm); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// ClaimsChanged is called when the permission claims accepted by the tenant change.
+func (t *TenantLifecycle) ClaimsChanged(ctx context.Context, binding *apisv1alpha1.APIBinding,
+	previous []apisv1alpha1.PermissionClaim) error {
+	// TODO(user): provision or release the state depending on the claims accepted by the tenant.
+	log.FromContext(ctx).Info("The accepted permission claims changed", "previous", previous)
+	return nil
+}
+
+// Unbound offboards a tenant when it unbinds the APIExport, after the objects created for it are deleted.
+func (t *TenantLifecycle) Unbound(ctx context.Context, clusterName logicalcluster.Name) error {
+	// TODO(user): release the state of the tenant kept outside of its workspace, e.g. in an external system.
+	return nil
+}
+`
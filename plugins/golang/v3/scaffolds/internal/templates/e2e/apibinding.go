@@ -1,20 +1,29 @@
 package e2e
 
 import (
+	"fmt"
 	"path/filepath"
 
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
 )
 
 var _ machinery.Template = &APIBinding{}
+var _ machinery.Inserter = &APIBinding{}
 
 // APIBinding scaffolds an apibinding.yaml for the e2e tests.
 // This is needed as long as a controller can not start
 // watching resources of an APIExport for which no APIBinding has been created.
+// The permission claims needed by the controllers are accepted in it.
 type APIBinding struct {
 	machinery.TemplateMixin
 	machinery.ProjectNameMixin
 	machinery.DomainMixin
+	machinery.ResourceMixin
+
+	// Claims are the permission claims of the APIExport needed by the controller of the resource
+	Claims []plugins.Claim
 }
 
 // SetTemplateDefaults implements machinery.Template
@@ -29,11 +38,38 @@ func (f *APIBinding) SetTemplateDefaults() error {
 	// needs to be replaced with /spec/template/spec/containers/0/volumeMounts/0
 	f.IfExistsAction = machinery.SkipFile
 
-	f.TemplateBody = apibindingTemplate
+	f.TemplateBody = fmt.Sprintf(apibindingTemplate,
+		machinery.NewMarkerFor(f.Path, permissionClaimsMarker),
+	)
 
 	return nil
 }
 
+// GetMarkers implements file.Inserter
+func (f *APIBinding) GetMarkers() []machinery.Marker {
+	return []machinery.Marker{
+		machinery.NewMarkerFor(f.Path, permissionClaimsMarker),
+	}
+}
+
+const acceptedPermissionClaimFragment = `  - group: %q
+    resource: %s
+    state: Accepted
+`
+
+// GetCodeFragments implements file.Inserter
+func (f *APIBinding) GetCodeFragments() machinery.CodeFragmentsMap {
+	fragments := make(machinery.CodeFragmentsMap, 1)
+	claims := make([]string, 0, len(f.Claims))
+	for _, claim := range f.Claims {
+		claims = append(claims, fmt.Sprintf(acceptedPermissionClaimFragment, claim.Group, claim.Resource))
+	}
+	if len(claims) != 0 {
+		fragments[machinery.NewMarkerFor(f.Path, permissionClaimsMarker)] = claims
+	}
+	return fragments
+}
+
 const apibindingTemplate = `---
 apiVersion: apis.kcp.dev/v1alpha1
 kind: APIBinding
@@ -44,7 +80,8 @@ spec:
     workspace:
       path: WORKSPACE
       exportName: {{ .ProjectName }}-{{ .ProjectName }}.{{ .Domain }}
+  # The permission claims of the APIExport accepted by the tenant
   permissionClaims:
-  # TODO (user)
+  %s
 
 `
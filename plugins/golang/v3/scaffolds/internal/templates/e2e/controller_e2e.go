@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
 )
 
 var _ machinery.Template = &E2ETest{}
@@ -19,6 +21,9 @@ type E2ETest struct {
 	machinery.ResourceMixin
 	machinery.ProjectNameMixin
 	machinery.DomainMixin
+
+	// Claims are the permission claims of the APIExport needed by the controller of the resource
+	Claims []plugins.Claim
 }
 
 // SetTemplateDefaults implements file.Template
@@ -34,29 +39,48 @@ func (f *E2ETest) SetTemplateDefaults() error {
 
 	f.TemplateBody = fmt.Sprintf(e2eTestTemplate,
 		machinery.NewMarkerFor(f.Path, importMarker),
+		machinery.NewMarkerFor(f.Path, addSchemeMarker),
+		machinery.NewMarkerFor(f.Path, permissionClaimsMarker),
 	)
 
 	return nil
 }
 
 const (
-	importMarker = "imports"
+	importMarker           = "imports"
+	addSchemeMarker        = "scheme"
+	permissionClaimsMarker = "permissionclaims"
 )
 
 // GetMarkers implements file.Inserter
 func (f *E2ETest) GetMarkers() []machinery.Marker {
 	return []machinery.Marker{
 		machinery.NewMarkerFor(f.Path, importMarker),
+		machinery.NewMarkerFor(f.Path, addSchemeMarker),
+		machinery.NewMarkerFor(f.Path, permissionClaimsMarker),
 	}
 }
 
 const (
-	apiImportCodeFragment = `%s "%s"`
+	apiImportCodeFragment = `%s "%s"
+`
+	// The error message does not use the GroupVersion variable as the packages of the core types do not define it.
+	addschemeCodeFragment = `if err := %[1]s.AddToScheme(scheme); err != nil {
+	t.Fatalf("failed to add %[1]s to scheme: %%v", err)
+}
+`
+	permissionClaimCodeFragment = `{
+	PermissionClaim: apisv1alpha1.PermissionClaim{
+		GroupResource: apisv1alpha1.GroupResource{Group: %q, Resource: %q},
+	},
+	State: apisv1alpha1.ClaimAccepted,
+},
+`
 )
 
 // GetCodeFragments implements file.Inserter
 func (f *E2ETest) GetCodeFragments() machinery.CodeFragmentsMap {
-	fragments := make(machinery.CodeFragmentsMap, 1)
+	fragments := make(machinery.CodeFragmentsMap, 3)
 
 	// Generate import code fragments
 	imports := make([]string, 0)
@@ -64,10 +88,26 @@ func (f *E2ETest) GetCodeFragments() machinery.CodeFragmentsMap {
 		imports = append(imports, fmt.Sprintf(apiImportCodeFragment, f.Resource.ImportAlias(), f.Resource.Path))
 	}
 
+	// Generate add scheme code fragments
+	addScheme := make([]string, 0)
+	if f.Resource.Path != "" {
+		addScheme = append(addScheme, fmt.Sprintf(addschemeCodeFragment, f.Resource.ImportAlias()))
+	}
+
 	// Only store code fragments in the map if the slices are non-empty
 	if len(imports) != 0 {
 		fragments[machinery.NewMarkerFor(f.Path, importMarker)] = imports
 	}
+	if len(addScheme) != 0 {
+		fragments[machinery.NewMarkerFor(f.Path, addSchemeMarker)] = addScheme
+	}
+	claims := make([]string, 0, len(f.Claims))
+	for _, claim := range f.Claims {
+		claims = append(claims, fmt.Sprintf(permissionClaimCodeFragment, claim.Group, claim.Resource))
+	}
+	if len(claims) != 0 {
+		fragments[machinery.NewMarkerFor(f.Path, permissionClaimsMarker)] = claims
+	}
 
 	return fragments
 }
@@ -81,9 +121,12 @@ package e2e
 {{end}}
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	kcpclienthelper "github.com/kcp-dev/apimachinery/pkg/client"
 	"github.com/kcp-dev/logicalcluster/v2"
@@ -98,6 +141,7 @@ import (
 	"k8s.io/client-go/rest"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
 	%s
@@ -147,12 +191,11 @@ func loadClient(t *testing.T, clusterName logicalcluster.Name) client.Client {
         if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
                 t.Fatalf("failed to add %%s to scheme: %%v", tenancyv1alpha1.SchemeGroupVersion, err)
         }
-        if err := {{ .Resource.ImportAlias }}.AddToScheme(scheme); err != nil {
-                t.Fatalf("failed to add %%s to scheme: %%v", {{ .Resource.ImportAlias }}.GroupVersion, err)
-        }
         if err := apisv1alpha1.AddToScheme(scheme); err != nil {
                 t.Fatalf("failed to add %%s to scheme: %%v", apisv1alpha1.SchemeGroupVersion, err)
         }
+	%s
+
         tenancyClient, err := client.New(loadClusterConfig(t, clusterName), client.Options{Scheme: scheme})
         if err != nil {
                 t.Fatalf("failed to create a client: %%v", err)
@@ -218,16 +261,10 @@ func createAPIBinding(t *testing.T, workspaceCluster logicalcluster.Name) client
                                         ExportName: apiName,
                                 },
                         },
-                        // TODO(user): PermissionClaims need to be configured for the desired resources
-                        // Example:
-                        // PermissionClaims: []apisv1alpha1.AcceptablePermissionClaim{
-                        //      {
-                        //              PermissionClaim: apisv1alpha1.PermissionClaim{
-                        //                      GroupResource: apisv1alpha1.GroupResource{Resource: "configmaps"},
-                        //              },
-                        //              State: apisv1alpha1.ClaimAccepted,
-                        //      },
-                        // },
+                        // The permission claims of the APIExport accepted by the tenant
+                        PermissionClaims: []apisv1alpha1.AcceptablePermissionClaim{
+                                %s
+                        },
                 },
         }); err != nil {
                 t.Fatalf("could not create APIBinding %%s|%%s: %%v", workspaceCluster, apiName, err)
@@ -277,7 +314,7 @@ func TestController(t *testing.T) {
                         t.Parallel()
                         workspaceCluster := parentWorkspace(t).Join(randomName())
                         c := createWorkspace(t, workspaceCluster)
-			t.Logf("workspace client %v", c)
+			t.Logf("workspace client %%v", c)
 
                         // TODO(user): Create resources and check that the desired reconciliation took place.
                         // Example: 
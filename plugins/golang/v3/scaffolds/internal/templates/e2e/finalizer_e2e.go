@@ -0,0 +1,108 @@
+package e2e
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &FinalizerTest{}
+
+// FinalizerTest scaffolds the file that checks end-to-end that the controller adds its finalizer and lets
+// the objects be deleted after the cleanup
+// nolint:maligned
+type FinalizerTest struct {
+	machinery.TemplateMixin
+	machinery.MultiGroupMixin
+	machinery.BoilerplateMixin
+	machinery.ResourceMixin
+	machinery.RepositoryMixin
+}
+
+// SetTemplateDefaults implements file.Template
+func (f *FinalizerTest) SetTemplateDefaults() error {
+	if f.Path == "" {
+		if f.MultiGroup && f.Resource.Group != "" {
+			f.Path = filepath.Join("test", "e2e", "%[group]", "%[kind]_finalizer_test.go")
+		} else {
+			f.Path = filepath.Join("test", "e2e", "%[kind]_finalizer_test.go")
+		}
+	}
+	f.Path = f.Resource.Replacer().Replace(f.Path)
+
+	f.TemplateBody = finalizerTestTemplate
+
+	return nil
+}
+
+const finalizerTestTemplate = `{{ .Boilerplate }}
+
+{{if and .MultiGroup .Resource.Group }}
+package {{ .Resource.PackageName }}
+{{else}}
+package e2e
+{{end}}
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	{{ .Resource.ImportAlias }} "{{ .Resource.Path }}"
+	controllers "{{ .Repo }}/controllers{{ if and .MultiGroup .Resource.Group }}/{{ .Resource.Group }}{{ end }}"
+)
+
+// Test{{ .Resource.Kind }}Finalizer verifies that the controller adds its finalizer to a {{ .Resource.Kind }} and
+// removes it once the {{ .Resource.Kind }} is deleted, letting the deletion complete.
+func Test{{ .Resource.Kind }}Finalizer(t *testing.T) {
+	t.Parallel()
+	workspaceCluster := parentWorkspace(t).Join(randomName())
+	c := createWorkspace(t, workspaceCluster)
+
+	{{ lower .Resource.Kind }} := &{{ .Resource.ImportAlias }}.{{ .Resource.Kind }}{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: randomName(),
+			{{- if or (not .Resource.API) .Resource.API.Namespaced }}
+			Namespace: "default",
+			{{- end }}
+		},
+	}
+	key := client.ObjectKeyFromObject({{ lower .Resource.Kind }})
+	t.Logf("creating {{ .Resource.Kind }} %s|%s", workspaceCluster, key)
+	if err := c.Create(context.TODO(), {{ lower .Resource.Kind }}); err != nil {
+		t.Fatalf("failed to create {{ .Resource.Kind }}: %v", err)
+	}
+
+	t.Logf("waiting for the finalizer to be added to {{ .Resource.Kind }} %s|%s", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		if err := c.Get(context.TODO(), key, {{ lower .Resource.Kind }}); err != nil {
+			return false, err
+		}
+		return controllerutil.ContainsFinalizer({{ lower .Resource.Kind }}, controllers.{{ .Resource.Kind }}Finalizer), nil
+	}); err != nil {
+		t.Fatalf("finalizer never added to {{ .Resource.Kind }} %s|%s: %v", workspaceCluster, key, err)
+	}
+
+	t.Logf("deleting {{ .Resource.Kind }} %s|%s", workspaceCluster, key)
+	if err := c.Delete(context.TODO(), {{ lower .Resource.Kind }}); err != nil {
+		t.Fatalf("failed to delete {{ .Resource.Kind }}: %v", err)
+	}
+
+	t.Logf("waiting for {{ .Resource.Kind }} %s|%s to be gone", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		err = c.Get(context.TODO(), key, {{ lower .Resource.Kind }})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		t.Fatalf("{{ .Resource.Kind }} %s|%s never deleted: %v", workspaceCluster, key, err)
+	}
+}
+`
@@ -0,0 +1,104 @@
+package e2e
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &TenantLifecycleTest{}
+
+// TenantLifecycleTest scaffolds the file that checks end-to-end that the tenants are onboarded when they bind the
+// APIExport and that the objects created for them are deleted when they unbind it
+// nolint:maligned
+type TenantLifecycleTest struct {
+	machinery.TemplateMixin
+	machinery.MultiGroupMixin
+	machinery.BoilerplateMixin
+	machinery.ResourceMixin
+	machinery.RepositoryMixin
+	machinery.ProjectNameMixin
+	machinery.DomainMixin
+}
+
+// SetTemplateDefaults implements file.Template
+func (f *TenantLifecycleTest) SetTemplateDefaults() error {
+	if f.Path == "" {
+		if f.MultiGroup && f.Resource.Group != "" {
+			f.Path = filepath.Join("test", "e2e", "%[group]", "tenantlifecycle_test.go")
+		} else {
+			f.Path = filepath.Join("test", "e2e", "tenantlifecycle_test.go")
+		}
+	}
+	f.Path = f.Resource.Replacer().Replace(f.Path)
+
+	f.TemplateBody = tenantLifecycleTestTemplate
+
+	return nil
+}
+
+const tenantLifecycleTestTemplate = `{{ .Boilerplate }}
+
+{{if and .MultiGroup .Resource.Group }}
+package {{ .Resource.PackageName }}
+{{else}}
+package e2e
+{{end}}
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/lifecycle"
+
+	"{{ .Repo }}/controllers"
+)
+
+// TestTenantLifecycle verifies that a tenant is onboarded by controllers.TenantLifecycle when it binds the APIExport
+// and that the objects created for it are deleted when it unbinds the APIExport.
+func TestTenantLifecycle(t *testing.T) {
+	t.Parallel()
+	workspaceCluster := parentWorkspace(t).Join(randomName())
+	c := createWorkspace(t, workspaceCluster)
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: controllers.TenantConfigMapName}
+	t.Logf("waiting for ConfigMap %s|%s to be provisioned", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		if err := c.Get(context.TODO(), key, cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return cm.Labels[lifecycle.ManagedByLabel] == controllers.TenantLifecycleOwner, nil
+	}); err != nil {
+		t.Fatalf("ConfigMap %s|%s never provisioned: %v", workspaceCluster, key, err)
+	}
+
+	apiName := "{{ .ProjectName }}-{{ .ProjectName }}.{{ .Domain }}"
+	t.Logf("deleting APIBinding %s|%s", workspaceCluster, apiName)
+	if err := c.Delete(context.TODO(), &apisv1alpha1.APIBinding{ObjectMeta: metav1.ObjectMeta{Name: apiName}}); err != nil {
+		t.Fatalf("failed to delete APIBinding: %v", err)
+	}
+
+	t.Logf("waiting for ConfigMap %s|%s to be deleted", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		err = c.Get(context.TODO(), key, cm)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		t.Fatalf("ConfigMap %s|%s never deleted: %v", workspaceCluster, key, err)
+	}
+}
+`
@@ -12,6 +12,13 @@ type GoMod struct {
 	machinery.RepositoryMixin
 
 	ControllerRuntimeVersion string
+
+	// GenerateClient indicates whether the dependencies of the clients generated by make generate-client are
+	// required at the versions they are generated for
+	GenerateClient         bool
+	ClientGoVersion        string
+	LogicalClusterVersion  string
+	KCPAPIMachineryVersion string
 }
 
 // SetTemplateDefaults implements file.Template
@@ -30,9 +37,16 @@ func (f *GoMod) SetTemplateDefaults() error {
 const goModTemplate = `
 module {{ .Repo }}
 
-go 1.17
+go 1.19
 
 require (
+{{- if .GenerateClient }}
+	github.com/kcp-dev/apimachinery {{ .KCPAPIMachineryVersion }}
+	github.com/kcp-dev/logicalcluster/v2 {{ .LogicalClusterVersion }}
+	k8s.io/api {{ .ClientGoVersion }}
+	k8s.io/apimachinery {{ .ClientGoVersion }}
+	k8s.io/client-go {{ .ClientGoVersion }}
+{{- end }}
 	sigs.k8s.io/controller-runtime {{ .ControllerRuntimeVersion }}
 )
 
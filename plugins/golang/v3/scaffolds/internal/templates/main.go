@@ -3,6 +3,7 @@ package templates
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
 )
@@ -18,6 +19,13 @@ type Main struct {
 	machinery.DomainMixin
 	machinery.RepositoryMixin
 	machinery.ComponentConfigMixin
+	machinery.ProjectNameMixin
+
+	// Tracing indicates whether the manager exports OpenTelemetry traces
+	Tracing bool
+
+	// TenantLifecycle indicates whether the manager runs the hooks onboarding and offboarding the tenants
+	TenantLifecycle bool
 }
 
 // SetTemplateDefaults implements file.Template
@@ -78,22 +86,36 @@ const (
 	controllerImportCodeFragment = `"%s/controllers"
 `
 	multiGroupControllerImportCodeFragment = `%scontrollers "%s/controllers/%s"
+`
+	eventsImportCodeFragment = `"github.com/fgiloux/kcp-operator-sdk/pkg/events"
 `
 	addschemeCodeFragment = `utilruntime.Must(%s.AddToScheme(scheme))
 `
-	reconcilerSetupCodeFragment = `if err = (&controllers.%sReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	reconcilerSetupCodeFragment = `%[1]sRecorder := events.NewRecorder(mgr.GetClient(), mgr.GetScheme(), "%[2]s-controller")
+	if err = mgr.Add(%[1]sRecorder); err != nil {
+		setupLog.Error(err, "unable to add the event recorder", "controller", "%[3]s")
+		os.Exit(1)
+	}
+	if err = (&controllers.%[3]sReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: %[1]sRecorder,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "%s")
+		setupLog.Error(err, "unable to create controller", "controller", "%[3]s")
 		os.Exit(1)
 	}
 `
-	multiGroupReconcilerSetupCodeFragment = `if err = (&%scontrollers.%sReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	multiGroupReconcilerSetupCodeFragment = `%[1]sRecorder := events.NewRecorder(mgr.GetClient(), mgr.GetScheme(), "%[2]s-controller")
+	if err = mgr.Add(%[1]sRecorder); err != nil {
+		setupLog.Error(err, "unable to add the event recorder", "controller", "%[3]s")
+		os.Exit(1)
+	}
+	if err = (&%[4]scontrollers.%[3]sReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: %[1]sRecorder,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "%s")
+		setupLog.Error(err, "unable to create controller", "controller", "%[3]s")
 		os.Exit(1)
 	}
 `
@@ -126,6 +148,8 @@ func (f *MainUpdater) GetCodeFragments() machinery.CodeFragmentsMap {
 			imports = append(imports, fmt.Sprintf(multiGroupControllerImportCodeFragment,
 				f.Resource.PackageName(), f.Repo, f.Resource.Group))
 		}
+		// The reconcilers record their events in the workspaces of the objects
+		imports = append(imports, eventsImportCodeFragment)
 	}
 
 	// Generate add scheme code fragments
@@ -137,12 +161,15 @@ func (f *MainUpdater) GetCodeFragments() machinery.CodeFragmentsMap {
 	// Generate setup code fragments
 	setup := make([]string, 0)
 	if f.WireController {
+		// The recorders write the events in the background, they are started by the manager
 		if !f.MultiGroup || f.Resource.Group == "" {
+			recorder := strings.ToLower(f.Resource.Kind[:1]) + f.Resource.Kind[1:]
 			setup = append(setup, fmt.Sprintf(reconcilerSetupCodeFragment,
-				f.Resource.Kind, f.Resource.Kind))
+				recorder, strings.ToLower(f.Resource.Kind), f.Resource.Kind))
 		} else {
+			recorder := f.Resource.PackageName() + f.Resource.Kind
 			setup = append(setup, fmt.Sprintf(multiGroupReconcilerSetupCodeFragment,
-				f.Resource.PackageName(), f.Resource.Kind, f.Resource.Kind))
+				recorder, strings.ToLower(f.Resource.Kind), f.Resource.Kind, f.Resource.PackageName()))
 		}
 	}
 	if f.WireWebhook {
@@ -169,28 +196,44 @@ var mainTemplate = `{{ .Boilerplate }}
 package main
 
 import (
+{{- if .Tracing }}
 	"context"
+{{- end }}
 	"flag"
-	"fmt"
 	"os"
 
-	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
-
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/client-go/discovery"
         clientgoscheme "k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/kcp"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusterratelimit"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcpmanager"
+{{- if .TenantLifecycle }}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/lifecycle"
+{{- end }}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/recording"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+{{- if .Tracing }}
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tracing"
+{{- end }}
+{{- if or .ComponentConfig .TenantLifecycle }}
+{{ end }}
+{{- if .ComponentConfig }}
+	configv1alpha1 "{{ .Repo }}/config/v1alpha1"
+{{- end }}
+{{- if .TenantLifecycle }}
+	"{{ .Repo }}/controllers"
+{{- end }}
 
 	%s
 )
@@ -202,6 +245,9 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+{{- if .ComponentConfig }}
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
+{{- end }}
 
 	%s
 }
@@ -212,20 +258,63 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var apiExportName string
+	var metricsTopClusters int
 	flag.StringVar(&apiExportName, "api-export-name", "", "The name of the APIExport.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. " +
 		"Enabling this will ensure there is only one active controller manager.")
+	var leaderElectionWorkspace string
+	var leaderElectionNamespace string
+	var leaderElectionID string
+	flag.StringVar(&leaderElectionWorkspace, "leader-election-workspace", "",
+		"The path of the workspace of the leader election lease when connected to kcp, e.g. root:org:ws. " +
+		"It defaults to the workspace of the kubeconfig.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"The namespace of the leader election lease. It defaults to the namespace of the pod.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "{{ hashFNV .Repo }}.{{ .Domain }}",
+		"The name of the leader election lease.")
+	flag.IntVar(&metricsTopClusters, "metrics-top-clusters", clustermetrics.DefaultTopClusters,
+		"The number of logical clusters with the most reconciliations exposed under their own label " +
+		"by the metrics of each controller. The other logical clusters are aggregated.")
+	var clusterQPS float64
+	var clusterBurst int
+	flag.Float64Var(&clusterQPS, "cluster-qps", clusterratelimit.DefaultQPS,
+		"The rate of the reconciliations of a logical cluster per controller.")
+	flag.IntVar(&clusterBurst, "cluster-burst", clusterratelimit.DefaultBurst,
+		"The number of reconciliations of a logical cluster admitted at once per controller.")
+	var enableSharding bool
+	flag.BoolVar(&enableSharding, "enable-sharding", false,
+		"Partition the logical clusters between the replicas of the controller manager rather than electing a leader.")
+	var tenantsOptions tenants.Options
+	tenantsOptions.BindFlags(flag.CommandLine)
+	var dryRun bool
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Turn the writes of the controllers into server-side dry-run requests. " +
+		"The changes are logged and counted per logical cluster by the kcp_controller_dry_run_changes_total metric.")
+	var recordingOptions recording.Options
+	recordingOptions.BindFlags(flag.CommandLine)
+{{- if .Tracing }}
+	var otlpEndpoint string
+	var otlpInsecure bool
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP gRPC collector the traces are exported to. Tracing is disabled when empty.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "Disable the transport security towards the OTLP collector.")
+{{- end }}
 {{- else }}
 	var configFile string
 	var apiExportName string
-        flag.StringVar(&apiExportName, "api-export-name", "", "The name of the APIExport.")
+	var metricsTopClusters int
+	flag.StringVar(&apiExportName, "api-export-name", "",
+		"The name of the APIExport. It overrides the apiExportName of the configuration file.")
 	flag.StringVar(&configFile, "config", "", 
 		"The controller will load its initial configuration from this file. " +
 		"Omit this flag to use the default configuration values. " +
 		"Command-line flags override configuration from this file.")
+	flag.IntVar(&metricsTopClusters, "metrics-top-clusters", clustermetrics.DefaultTopClusters,
+		"The number of logical clusters with the most reconciliations exposed under their own label " +
+		"by the metrics of each controller. The other logical clusters are aggregated.")
 {{- end }}
 	opts := zap.Options{
 		Development: true,
@@ -234,104 +323,166 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+{{- if not .ComponentConfig }}
 	setupLog = setupLog.WithValues("api-export-name", apiExportName)
+{{- end }}
 
 	ctx := ctrl.SetupSignalHandler()
-
-	restConfig := ctrl.GetConfigOrDie()
-
-	var mgr ctrl.Manager
-        var err error
-
-	if kcpAPIsGroupPresent(restConfig) {
-		setupLog.Info("Looking up virtual workspace URL")
-		cfg, err := restConfigForAPIExport(ctx, restConfig, apiExportName)
+{{ if not .ComponentConfig }}
+	options := ctrl.Options{
+		Scheme:			scheme,
+		MetricsBindAddress:	metricsAddr,
+		Port:			9443,
+		HealthProbeBindAddress: probeAddr,
+		LeaderElection:		enableLeaderElection,
+		LeaderElectionID:	leaderElectionID,
+		LeaderElectionNamespace: leaderElectionNamespace,
+		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
+		// when the Manager ends. This requires the binary to immediately end when the
+		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+		// speeds up voluntary leader transitions as the new leader don't have to wait
+		// LeaseDuration time first.
+		//
+		// In the default scaffold provided, the program ends immediately after 
+		// the manager stops, so would be fine to enable this option. However, 
+		// if you are doing or is intended to do any operation such as perform cleanups 
+		// after the manager stops then its usage might be unsafe.
+		// LeaderElectionReleaseOnCancel: true,
+	}
+{{- else }}
+	var err error
+	ctrlConfig := configv1alpha1.ProjectConfig{}
+	options := ctrl.Options{Scheme: scheme}
+	if configFile != "" {
+		// The configuration is validated when it is loaded, see ProjectConfig.Complete.
+		options, err = options.AndFrom(ctrl.ConfigFile().AtPath(configFile).OfKind(&ctrlConfig))
 		if err != nil {
-			setupLog.Error(err, "error looking up virtual workspace URL")
+			setupLog.Error(err, "unable to load the config file")
+			os.Exit(1)
 		}
+	}
+	if apiExportName == "" {
+		apiExportName = ctrlConfig.APIExportName
+	}
+	setupLog = setupLog.WithValues("api-export-name", apiExportName)
+{{- end }}
 
-		setupLog.Info("Using virtual workspace URL", "url", cfg.Host)
+	// The reconciliations are recorded per logical cluster by the controllers, see SetupWithManager.
+	if err := clustermetrics.Register(metrics.Registry, clustermetrics.Options{TopClusters: metricsTopClusters}); err != nil {
+		setupLog.Error(err, "unable to register the logical cluster metrics")
+		os.Exit(1)
+	}
 
+	// The reconciliations of the controllers are rate limited per logical cluster, see SetupWithManager.
+{{- if not .ComponentConfig }}
+	clusterratelimit.SetDefaultOptions(clusterratelimit.Options{QPS: clusterQPS, Burst: clusterBurst})
+{{- else }}
+	clusterratelimit.SetDefaultOptions(clusterratelimit.Options{
+		QPS:   float64(ctrlConfig.ClusterRateLimit.QPS),
+		Burst: ctrlConfig.ClusterRateLimit.Burst,
+	})
+{{- end }}
+{{- if .Tracing }}
 
-{{ if not .ComponentConfig }}
-		mgr, err = kcp.NewClusterAwareManager(cfg, ctrl.Options{
-			Scheme:			scheme,
-			MetricsBindAddress:	metricsAddr,
-			Port:			9443,
-			HealthProbeBindAddress: probeAddr,
-			LeaderElection:		enableLeaderElection,
-			LeaderElectionID:	"{{ hashFNV .Repo }}.{{ .Domain }}",
-			// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-			// when the Manager ends. This requires the binary to immediately end when the
-			// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-			// speeds up voluntary leader transitions as the new leader don't have to wait
-			// LeaseDuration time first.
-			//
-			// In the default scaffold provided, the program ends immediately after 
-			// the manager stops, so would be fine to enable this option. However, 
-			// if you are doing or is intended to do any operation such as perform cleanups 
-			// after the manager stops then its usage might be unsafe.
-			// LeaderElectionReleaseOnCancel: true,
-			LeaderElectionConfig:	restConfig,
-		})
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+{{- if .ComponentConfig }}
+		Endpoint:      ctrlConfig.Tracing.Endpoint,
+		Insecure:      ctrlConfig.Tracing.Insecure,
 {{- else }}
-		options := ctrl.Options{Scheme: scheme}
-		options.LeaderElectionConfig = restConfig
-		if configFile != "" {
-			options, err = options.AndFrom(ctrl.ConfigFile().AtPath(configFile))
-			if err != nil {
-				setupLog.Error(err, "unable to load the config file")
-				os.Exit(1)
-			}
+		Endpoint:      otlpEndpoint,
+		Insecure:      otlpInsecure,
+{{- end }}
+		ServiceName:   "{{ .ProjectName }}",
+		APIExportName: apiExportName,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "unable to flush the traces")
 		}
+	}()
 
-		mgr, err = kcp.NewClusterAwareManager(cfg, options)
+	// The requests of the rest transport and of the client of the manager are traced.
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.Wrap(tracing.WrapTransport)
 {{- end }}
-		if err != nil {
-			setupLog.Error(err, "unable to start cluster aware manager")
-			os.Exit(1)
-		}
-	} else {
-		setupLog.Info("The apis.kcp.dev group is not present - creating standard manager")
-{{ if not .ComponentConfig }}
-		mgr, err = ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-			Scheme:			scheme,
-			MetricsBindAddress:	metricsAddr,
-			Port:			9443,
-			HealthProbeBindAddress: probeAddr,
-			LeaderElection:		enableLeaderElection,
-			LeaderElectionID:	"{{ hashFNV .Repo }}.{{ .Domain }}",
-			// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-			// when the Manager ends. This requires the binary to immediately end when the
-			// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-			// speeds up voluntary leader transitions as the new leader don't have to wait
-			// LeaseDuration time first.
-			//
-			// In the default scaffold provided, the program ends immediately after 
-			// the manager stops, so would be fine to enable this option. However, 
-			// if you are doing or is intended to do any operation such as perform cleanups 
-			// after the manager stops then its usage might be unsafe.
-			// LeaderElectionReleaseOnCancel: true,
-		})
+
+	// The logical clusters are partitioned between the replicas when sharding is enabled, see SetupWithManager.
+	var shardingOptions *sharding.Options
+{{- if not .ComponentConfig }}
+	if enableSharding {
+		shardingOptions = &sharding.Options{}
+	}
 {{- else }}
-		options := ctrl.Options{Scheme: scheme}
-		if configFile != "" {
-			options, err = options.AndFrom(ctrl.ConfigFile().AtPath(configFile))
-			if err != nil {
-				setupLog.Error(err, "unable to load the config file")
-				os.Exit(1)
-			}
+	if ctrlConfig.Sharding.Enabled {
+		shardingOptions = &sharding.Options{
+			LeaseDuration: ctrlConfig.Sharding.LeaseDuration.Duration,
+			RenewPeriod:   ctrlConfig.Sharding.RenewPeriod.Duration,
 		}
+	}
+{{- end }}
 
-		mgr, err = ctrl.NewManager(ctrl.GetConfigOrDie(), options)
+	// The manager is cluster aware and watches the virtual workspace of the APIExport when
+	// connected to kcp. A standard manager is created when connected to a Kubernetes cluster.
+	mgr, err := kcpmanager.NewManager(ctx, kcpmanager.Options{
+{{- if .Tracing }}
+		RestConfig:    restConfig,
 {{- end }}
-		if err != nil {
-			setupLog.Error(err, "unable to start manager")
-			os.Exit(1)
-		}
+		APIExportName: apiExportName,
+{{- if .ComponentConfig }}
+		Mode:          ctrlConfig.Mode,
+{{- end }}
+		Manager:       options,
+{{- if not .ComponentConfig }}
+		LeaderElectionWorkspace: leaderElectionWorkspace,
+{{- else }}
+		LeaderElectionWorkspace: ctrlConfig.LeaderElectionWorkspace,
+{{- end }}
+{{- if .Tracing }}
+		WrapClient:    tracing.WrapClient,
+{{- end }}
+{{- if not .ComponentConfig }}
+		DryRun:        dryRun,
+		Recording:     recordingOptions,
+{{- else }}
+		DryRun:        ctrlConfig.DryRun,
+		Recording: recording.Options{
+			Clusters:   ctrlConfig.Recording.Clusters,
+			Dir:        ctrlConfig.Recording.Dir,
+			MaxEntries: ctrlConfig.Recording.MaxEntries,
+		},
+{{- end }}
+		Sharding:      shardingOptions,
+		// The events of the logical clusters not selected are dropped by the controllers, see SetupWithManager.
+{{- if not .ComponentConfig }}
+		Tenants:       tenantsOptions,
+{{- else }}
+		Tenants: tenants.Options{
+			Allow:    ctrlConfig.Tenants.Allow,
+			Deny:     ctrlConfig.Tenants.Deny,
+			Selector: ctrlConfig.Tenants.Selector,
+		},
+{{- end }}
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
 	}
 
 	%s
+{{- if .TenantLifecycle }}
+
+	// The tenants binding the APIExport are onboarded and offboarded by the hooks of controllers.TenantLifecycle.
+	// The objects created for a tenant are deleted when it unbinds the APIExport.
+	if err = lifecycle.NewReconciler(mgr.GetClient(), mgr.GetRESTMapper(), controllers.TenantLifecycleOwner,
+		&controllers.TenantLifecycle{Client: mgr.GetClient()}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TenantLifecycle")
+		os.Exit(1)
+	}
+{{- end }}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -350,73 +501,4 @@ func main() {
 }
 
 // +kubebuilder:rbac:groups="apis.kcp.dev",resources=apiexports,verbs=get;list;watch
-
-// restConfigForAPIExport returns a *rest.Config properly configured to communicate with the endpoint for the
-// APIExport's virtual workspace.
-func restConfigForAPIExport(ctx context.Context, cfg *rest.Config, apiExportName string) (*rest.Config, error) {
-	scheme := runtime.NewScheme()
-	if err := apisv1alpha1.AddToScheme(scheme); err != nil {
-		return nil, fmt.Errorf("error adding apis.kcp.dev/v1alpha1 to scheme: %%w", err)
-	}
-
-	apiExportClient, err := client.New(cfg, client.Options{Scheme: scheme})
-	if err != nil {
-		return nil, fmt.Errorf("error creating APIExport client: %%w", err)
-	}
-
-	var apiExport apisv1alpha1.APIExport
-
-	if apiExportName != "" {
-		if err := apiExportClient.Get(ctx, types.NamespacedName{Name: apiExportName}, &apiExport); err != nil {
-			return nil, fmt.Errorf("error getting APIExport %%q: %%w", apiExportName, err)
-		}
-	} else {
-		setupLog.Info("api-export-name is empty - listing")
-		exports := &apisv1alpha1.APIExportList{}
-		if err := apiExportClient.List(ctx, exports); err != nil {
-			return nil, fmt.Errorf("error listing APIExports: %%w", err)
-		}
-		if len(exports.Items) == 0 {
-			return nil, fmt.Errorf("no APIExport found")
-		}
-		if len(exports.Items) > 1 {
-			return nil, fmt.Errorf("more than one APIExport found")
-		}
-		apiExport = exports.Items[0]
-	}
-
-	if len(apiExport.Status.VirtualWorkspaces) < 1 {
-		return nil, fmt.Errorf("APIExport %%q status.virtualWorkspaces is empty", apiExportName)
-	}
-
-	cfg = rest.CopyConfig(cfg)
-	// TODO(ncdc): sharding support
-	cfg.Host = apiExport.Status.VirtualWorkspaces[0].URL
-
-	return cfg, nil
-}
-
-func kcpAPIsGroupPresent(restConfig *rest.Config) bool {
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
-	if err != nil {
-		setupLog.Error(err, "failed to create discovery client")
-		os.Exit(1)
-	}
-	apiGroupList, err := discoveryClient.ServerGroups()
-	if err != nil {
-		setupLog.Error(err, "failed to get server groups")
-		os.Exit(1)
-	}
-
-	for _, group := range apiGroupList.Groups {
-		if group.Name == apisv1alpha1.SchemeGroupVersion.Group {
-			for _, version := range group.Versions {
-				if version.Version == apisv1alpha1.SchemeGroupVersion.Version {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
 `
@@ -29,6 +29,11 @@ type Makefile struct {
 	KCPVersion string
 	// yq version used for parsing yaml files
 	YQVersion string
+	// version of the code generators of kcp generating the cluster aware clients
+	KCPCodeGeneratorVersion string
+	// versions of logicalcluster and kcp-dev/apimachinery the cluster aware clients are generated for
+	LogicalClusterVersion  string
+	KCPAPIMachineryVersion string
 	// version of the Kubebuilder assets used by EnvTest
 	EnvTestK8s string
 }
@@ -90,6 +95,8 @@ SHELL = /usr/bin/env bash -o pipefail
 
 # kcp specific
 APIEXPORT_PREFIX ?= today
+# The path of the workspace of the leader election lease, e.g. root:my-org:my-workspace.
+LEADER_ELECTION_WORKSPACE ?=
 
 .PHONY: all
 all: build
@@ -110,6 +117,12 @@ apiresourceschemas: kustomize ## Convert CRDs from config/crds to APIResourceSch
 generate: controller-gen ## Generate code containing DeepCopy, DeepCopyInto, and DeepCopyObject method implementations.
 	$(CONTROLLER_GEN) object:headerFile={{printf "%q" .BoilerplatePath}} paths="./..."
 
+.PHONY: generate-client
+generate-client: client-gen kcp-code-generator ## Generate the clientsets, listers and informers of the types marked with +genclient into client/.
+	CLIENT_GEN=$(CLIENT_GEN) KCP_CODE_GENERATOR=$(KCP_CODE_GENERATOR) BOILERPLATE={{ .BoilerplatePath }} \
+	KCP_CODE_GENERATOR_VERSION=$(KCP_CODE_GENERATOR_VERSION) LOGICALCLUSTER_VERSION=$(LOGICALCLUSTER_VERSION) \
+	KCP_APIMACHINERY_VERSION=$(KCP_APIMACHINERY_VERSION) bash hack/update-codegen.sh
+
 .PHONY: fmt
 fmt: ## Run go fmt against code.
 	go fmt ./...
@@ -269,6 +282,11 @@ deploy-kcp: manifests apiresourceschemas ## Deploy controller onto kcp
 undeploy-kcp: ## Undeploy controller. Call with ignore-not-found=true to ignore resource not found errors during deletion.
 	$(KUSTOMIZE) build config/default-kcp | $(KCP_KUBECTL) delete --ignore-not-found=$(ignore-not-found) -f -
 
+.PHONY: deploy-leader-election
+deploy-leader-election: kustomize ## Create the namespace and the permissions of the leader election lease in the LEADER_ELECTION_WORKSPACE workspace of kcp.
+	@if [ -z "$(LEADER_ELECTION_WORKSPACE)" ]; then echo "LEADER_ELECTION_WORKSPACE is required"; exit 1; fi
+	$(KUSTOMIZE) build config/kcp-leader-election | $(KCP_KUBECTL) --server=$$($(KCP_KUBECTL) config view --minify -o jsonpath='{.clusters[0].cluster.server}' | sed 's|/clusters/.*||')/clusters/$(LEADER_ELECTION_WORKSPACE) apply -f -
+
 ##@ Build Dependencies
 
 ## Location to install dependencies to
@@ -283,12 +301,21 @@ ENVTEST ?= $(LOCALBIN)/setup-envtest
 KCP ?= $(LOCALBIN)/kcp
 KUBECTL_KCP ?= $(LOCALBIN)/kubectl-kcp
 YQ ?= $(LOCALBIN)/yq
+CLIENT_GEN ?= $(LOCALBIN)/client-gen
+KCP_CODE_GENERATOR ?= $(LOCALBIN)/code-generator
 
 ## Tool Versions
 KUSTOMIZE_VERSION ?= {{ .KustomizeVersion }}
 CONTROLLER_TOOLS_VERSION ?= {{ .ControllerToolsVersion }}
 KCP_VERSION ?= {{ .KCPVersion }}
 YQ_VERSION ?= {{ .YQVersion }}
+# The single cluster clients are generated for the version of client-go of go.mod.
+CODE_GENERATOR_VERSION ?= $(shell go list -m -f '{{ "{{" }} .Version {{ "}}" }}' k8s.io/client-go)
+# The cluster aware clients are generated for these versions of logicalcluster and kcp-dev/apimachinery, which
+# hack/update-codegen.sh checks against go.mod.
+KCP_CODE_GENERATOR_VERSION ?= {{ .KCPCodeGeneratorVersion }}
+LOGICALCLUSTER_VERSION ?= {{ .LogicalClusterVersion }}
+KCP_APIMACHINERY_VERSION ?= {{ .KCPAPIMachineryVersion }}
 
 KUSTOMIZE_INSTALL_SCRIPT ?= "https://raw.githubusercontent.com/kubernetes-sigs/kustomize/master/hack/install_kustomize.sh"
 .PHONY: kustomize
@@ -306,6 +333,16 @@ envtest: $(ENVTEST) ## Download envtest-setup locally if necessary.
 $(ENVTEST): $(LOCALBIN)
 	test -s $(LOCALBIN)/setup-envtest || GOBIN=$(LOCALBIN) go install sigs.k8s.io/controller-runtime/tools/setup-envtest@latest
 
+.PHONY: client-gen
+client-gen: $(CLIENT_GEN) ## Download client-gen locally if necessary.
+$(CLIENT_GEN): $(LOCALBIN)
+	test -s $(LOCALBIN)/client-gen || GOBIN=$(LOCALBIN) go install k8s.io/code-generator/cmd/client-gen@$(CODE_GENERATOR_VERSION)
+
+.PHONY: kcp-code-generator
+kcp-code-generator: $(KCP_CODE_GENERATOR) ## Download the code generators of kcp locally if necessary.
+$(KCP_CODE_GENERATOR): $(LOCALBIN)
+	test -s $(LOCALBIN)/code-generator || GOBIN=$(LOCALBIN) go install github.com/kcp-dev/code-generator/v2@$(KCP_CODE_GENERATOR_VERSION)
+
 .PHONY: yq
 yq: $(YQ) ## Download yq locally if necessary.
 $(YQ): $(LOCALBIN)
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 
 	"sigs.k8s.io/kubebuilder/v3/pkg/config"
@@ -8,6 +9,8 @@ import (
 	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
 	"sigs.k8s.io/kubebuilder/v3/pkg/plugin"
 
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
+	golangv3 "github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3"
 	kcptemplates "github.com/fgiloux/kcp-operator-sdk/plugins/manifests/v1/templates/config/kcp"
 )
 
@@ -42,9 +45,16 @@ func (s *createAPISubcommand) Scaffold(fs machinery.Filesystem) error {
 
 	// If the gvk is non-empty
 	if s.resource.Group != "" || s.resource.Version != "" || s.resource.Kind != "" {
+		// The permission claims are recorded by the golang plugin, which scaffolds the controllers.
+		var goConfig golangv3.Config
+		if err := s.config.DecodePluginConfig(plugin.KeyFor(golangv3.Plugin{}), &goConfig); err != nil &&
+			!errors.As(err, &config.UnsupportedFieldError{}) && !errors.As(err, &config.PluginKeyNotFoundError{}) {
+			return err
+		}
+
 		if err := scaffold.Execute(
-			&kcptemplates.APIExport{},
-			&kcptemplates.PatchAPIExport{},
+			&kcptemplates.APIExport{Claims: goConfig.PermissionClaims},
+			&kcptemplates.PatchAPIExport{External: plugins.IsExternal(s.config, *s.resource)},
 		); err != nil {
 			return fmt.Errorf("error scaffolding manifests: %v", err)
 		}
@@ -12,8 +12,11 @@ import (
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
 	"sigs.k8s.io/kubebuilder/v3/pkg/plugin"
 
+	golangv3 "github.com/fgiloux/kcp-operator-sdk/plugins/golang/v3"
 	"github.com/fgiloux/kcp-operator-sdk/plugins/manifests/v1/templates/config/defaultkcp"
+	"github.com/fgiloux/kcp-operator-sdk/plugins/manifests/v1/templates/config/grafana"
 	kcptemplates "github.com/fgiloux/kcp-operator-sdk/plugins/manifests/v1/templates/config/kcp"
+	"github.com/fgiloux/kcp-operator-sdk/plugins/manifests/v1/templates/config/kcpleaderelection"
 )
 
 const filePath = "Makefile"
@@ -65,6 +68,13 @@ func (s *initSubcommand) Scaffold(fs machinery.Filesystem) error {
 		return fmt.Errorf("error updating Makefile: %w", err)
 	}
 
+	// The tracing configuration is recorded by the golang plugin, which scaffolds the manager.
+	var goConfig golangv3.Config
+	if err := s.config.DecodePluginConfig(plugin.KeyFor(golangv3.Plugin{}), &goConfig); err != nil &&
+		!errors.As(err, &config.UnsupportedFieldError{}) && !errors.As(err, &config.PluginKeyNotFoundError{}) {
+		return err
+	}
+
 	// Initialize the machinery.Scaffold that will write the files to disk
 	scaffold := machinery.NewScaffold(fs,
 		// NOTE: kubebuilder's default permissions are only for root users
@@ -80,10 +90,22 @@ func (s *initSubcommand) Scaffold(fs machinery.Filesystem) error {
 		&defaultkcp.Kustomization{},
 		&defaultkcp.KustomizeConfig{},
 		&defaultkcp.ManagerPatch{},
+		&kcpleaderelection.Kustomization{},
+		&kcpleaderelection.Namespace{},
+		&kcpleaderelection.Role{},
+		&kcpleaderelection.RoleBinding{},
+		&grafana.Dashboard{},
 	); err != nil {
 		return fmt.Errorf("error scaffolding manifests: %w", err)
 	}
 
+	if s.config.IsComponentConfig() {
+		// The component configuration of the kcp overlay is rendered from the ProjectConfig type of the project.
+		if err := scaffold.Execute(&defaultkcp.ManagerConfig{Tracing: goConfig.Tracing}); err != nil {
+			return fmt.Errorf("error scaffolding manifests: %w", err)
+		}
+	}
+
 	if err := s.config.EncodePluginConfig(pluginKey, Config{}); err != nil && !errors.As(err, &config.UnsupportedFieldError{}) {
 		return err
 	}
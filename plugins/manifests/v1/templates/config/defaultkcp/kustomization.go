@@ -13,6 +13,7 @@ type Kustomization struct {
 	machinery.TemplateMixin
 	machinery.ProjectNameMixin
 	machinery.DomainMixin
+	machinery.ComponentConfigMixin
 }
 
 // SetTemplateDefaults implements machinery.Template
@@ -57,6 +58,19 @@ patchesStrategicMerge:
 
 configurations:
 - kustomizeconfig.yaml
+{{- if .ComponentConfig }}
+
+# The component configuration of the manager, with the kcp mode and the name of
+# the APIExport, replaces the one of config/manager.
+generatorOptions:
+  disableNameSuffixHash: true
+
+configMapGenerator:
+- name: manager-config
+  behavior: replace
+  files:
+  - controller_manager_config.yaml
+{{- end }}
 
 # Adjust to prefix
 vars:
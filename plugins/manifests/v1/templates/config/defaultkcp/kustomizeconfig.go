@@ -6,13 +6,14 @@ import (
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
 )
 
-var _ machinery.Template = &Kustomization{}
+var _ machinery.Template = &KustomizeConfig{}
 
 // KustomizeConfig scaffolds a kustomizeconfig.yaml for the manifests overlay folder.
 type KustomizeConfig struct {
 	machinery.TemplateMixin
 	machinery.ProjectNameMixin
 	machinery.DomainMixin
+	machinery.ComponentConfigMixin
 }
 
 // SetTemplateDefaults implements machinery.Template
@@ -41,5 +42,12 @@ const kustomizeConfigTemplate = `nameReference:
   fieldSpecs:
   - kind: Deployment
     path: spec/volumes/configMap/name
+{{- if .ComponentConfig }}
+
+# The name of the APIExport is substituted in the component configuration.
+varReference:
+- kind: ConfigMap
+  path: data
+{{- end }}
 
 `
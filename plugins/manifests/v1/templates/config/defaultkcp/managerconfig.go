@@ -0,0 +1,51 @@
+package defaultkcp
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
+)
+
+var _ machinery.Template = &ManagerConfig{}
+
+// ManagerConfig scaffolds the component configuration of the manager for the manifests overlay folder. It replaces
+// the one of config/manager to require a kcp server and to name the APIExport of the controller.
+type ManagerConfig struct {
+	machinery.TemplateMixin
+	machinery.ProjectNameMixin
+	machinery.DomainMixin
+	machinery.RepositoryMixin
+
+	// Mode selects whether the manager is cluster aware
+	Mode string
+
+	// APIExportName is the name of the APIExport watched by the manager
+	APIExportName string
+
+	// Tracing indicates whether the manager exports OpenTelemetry traces
+	Tracing bool
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *ManagerConfig) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("config", "default-kcp", "controller_manager_config.yaml")
+	}
+
+	if f.Mode == "" {
+		f.Mode = "kcp"
+	}
+	if f.APIExportName == "" {
+		// Substituted by kustomize with the name of the APIExport, see kustomizeconfig.yaml.
+		f.APIExportName = "$(API_EXPORT_NAME)"
+	}
+
+	// The file is owned by the user once scaffolded, like manager_patch.yaml.
+	f.IfExistsAction = machinery.SkipFile
+
+	f.TemplateBody = plugins.ProjectConfigTemplate
+
+	return nil
+}
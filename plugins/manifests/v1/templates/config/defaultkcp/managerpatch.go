@@ -33,7 +33,11 @@ func (f *ManagerPatch) SetTemplateDefaults() error {
 	return nil
 }
 
-const mgrPatchTemplate = `# Pass the name of the APIExport to the controller
+const mgrPatchTemplate = `{{ if .ComponentConfig -}}
+# Mount the component configuration of the controller, which names the APIExport
+{{- else -}}
+# Pass the name of the APIExport to the controller
+{{- end }}
 ---
 apiVersion: apps/v1
 kind: Deployment
@@ -46,7 +50,6 @@ spec:
       containers:
       - name: manager
         args:
-        - "--api-export-name=$(API_EXPORT_NAME)"
 {{- if .ComponentConfig }}
         - "--config=controller_manager_config.yaml"
         volumeMounts:
@@ -58,7 +61,22 @@ spec:
         configMap:
           name: manager-config
 {{- else }}
+        - "--api-export-name=$(API_EXPORT_NAME)"
         - --leader-elect
+        # Store the leader election lease in another workspace than the one of the kubeconfig.
+        # The namespace and the permissions of the lease are scaffolded in config/kcp-leader-election.
+        # - --leader-election-workspace=root:my-org:my-workspace
+        # - --leader-election-namespace={{ .ProjectName }}-system
+        # Partition the logical clusters between the replicas rather than electing a leader,
+        # the replicas of the deployment can then be scaled.
+        # - --enable-sharding
+        # Reconcile the objects of some logical clusters only, e.g. to roll out a new version
+        # to the tenants whose APIBinding has the rollout=canary label first.
+        # - --tenants-selector=rollout=canary
+        # Log the changes the controllers would make rather than making them.
+        # - --dry-run
+        # Record the objects read and written for a logical cluster to replay them offline.
+        # - --record-clusters=root:my-org:my-workspace
 {{- end }}
 
 `
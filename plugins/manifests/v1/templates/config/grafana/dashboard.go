@@ -0,0 +1,277 @@
+package grafana
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &Dashboard{}
+
+// Dashboard scaffolds the Grafana dashboard of the metrics recorded per logical cluster.
+type Dashboard struct {
+	machinery.TemplateMixin
+	machinery.ProjectNameMixin
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *Dashboard) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("config", "grafana", "kcp-cluster-metrics.json")
+	}
+
+	// The legends of Grafana use {{ }}, which are the default delimiters of go templates.
+	f.SetDelim("[[", "]]")
+	f.TemplateBody = dashboardTemplate
+
+	return nil
+}
+
+// nolint: lll
+const dashboardTemplate = `{
+  "__inputs": [
+    {
+      "name": "DS_PROMETHEUS",
+      "label": "Prometheus",
+      "description": "",
+      "type": "datasource",
+      "pluginId": "prometheus",
+      "pluginName": "Prometheus"
+    }
+  ],
+  "annotations": {
+    "list": []
+  },
+  "description": "Reconciliations of the [[ .ProjectName ]] controllers per logical cluster. The logical clusters outside of the top ones of a controller are aggregated under the other cluster, see the --metrics-top-clusters flag.",
+  "editable": true,
+  "graphTooltip": 1,
+  "links": [],
+  "panels": [
+    {
+      "datasource": "${DS_PROMETHEUS}",
+      "description": "Reconciliations per second of the logical clusters with the most reconciliations",
+      "fieldConfig": {
+        "defaults": {
+          "unit": "ops"
+        },
+        "overrides": []
+      },
+      "gridPos": {
+        "h": 8,
+        "w": 12,
+        "x": 0,
+        "y": 0
+      },
+      "id": 1,
+      "targets": [
+        {
+          "datasource": "${DS_PROMETHEUS}",
+          "expr": "topk($top, sum by (cluster) (rate(kcp_controller_reconcile_total{job=\"$job\", controller=~\"$controller\"}[5m])))",
+          "legendFormat": "{{cluster}}",
+          "refId": "A"
+        }
+      ],
+      "title": "Reconciliations per Logical Cluster",
+      "type": "timeseries"
+    },
+    {
+      "datasource": "${DS_PROMETHEUS}",
+      "description": "Reconciliation errors per second of the logical clusters with the most errors",
+      "fieldConfig": {
+        "defaults": {
+          "unit": "ops"
+        },
+        "overrides": []
+      },
+      "gridPos": {
+        "h": 8,
+        "w": 12,
+        "x": 12,
+        "y": 0
+      },
+      "id": 2,
+      "targets": [
+        {
+          "datasource": "${DS_PROMETHEUS}",
+          "expr": "topk($top, sum by (cluster) (rate(kcp_controller_reconcile_errors_total{job=\"$job\", controller=~\"$controller\"}[5m])))",
+          "legendFormat": "{{cluster}}",
+          "refId": "A"
+        }
+      ],
+      "title": "Reconciliation Errors per Logical Cluster",
+      "type": "timeseries"
+    },
+    {
+      "datasource": "${DS_PROMETHEUS}",
+      "description": "99th percentile of the reconciliation time of the slowest logical clusters",
+      "fieldConfig": {
+        "defaults": {
+          "unit": "s"
+        },
+        "overrides": []
+      },
+      "gridPos": {
+        "h": 8,
+        "w": 12,
+        "x": 0,
+        "y": 8
+      },
+      "id": 3,
+      "targets": [
+        {
+          "datasource": "${DS_PROMETHEUS}",
+          "expr": "topk($top, histogram_quantile(0.99, sum by (cluster, le) (rate(kcp_controller_reconcile_time_seconds_bucket{job=\"$job\", controller=~\"$controller\"}[5m]))))",
+          "legendFormat": "{{cluster}}",
+          "refId": "A"
+        }
+      ],
+      "title": "P99 Reconciliation Time per Logical Cluster",
+      "type": "timeseries"
+    },
+    {
+      "datasource": "${DS_PROMETHEUS}",
+      "description": "Workers reconciling the objects of the logical clusters and requeues per second, which make up the backlog of a logical cluster",
+      "fieldConfig": {
+        "defaults": {},
+        "overrides": []
+      },
+      "gridPos": {
+        "h": 8,
+        "w": 12,
+        "x": 12,
+        "y": 8
+      },
+      "id": 4,
+      "targets": [
+        {
+          "datasource": "${DS_PROMETHEUS}",
+          "expr": "topk($top, sum by (cluster) (kcp_controller_active_workers{job=\"$job\", controller=~\"$controller\"}))",
+          "legendFormat": "{{cluster}} workers",
+          "refId": "A"
+        },
+        {
+          "datasource": "${DS_PROMETHEUS}",
+          "expr": "topk($top, sum by (cluster) (rate(kcp_controller_reconcile_total{job=\"$job\", controller=~\"$controller\", result=~\"requeue|requeue_after\"}[5m])))",
+          "legendFormat": "{{cluster}} requeues",
+          "refId": "B"
+        }
+      ],
+      "title": "Active Workers and Requeues per Logical Cluster",
+      "type": "timeseries"
+    },
+    {
+      "datasource": "${DS_PROMETHEUS}",
+      "description": "Requests queued per logical cluster in the workqueues of the controllers",
+      "fieldConfig": {
+        "defaults": {},
+        "overrides": []
+      },
+      "gridPos": {
+        "h": 8,
+        "w": 12,
+        "x": 0,
+        "y": 16
+      },
+      "id": 5,
+      "targets": [
+        {
+          "datasource": "${DS_PROMETHEUS}",
+          "expr": "topk($top, sum by (cluster) (kcp_controller_workqueue_depth{job=\"$job\", controller=~\"$controller\"}))",
+          "legendFormat": "{{cluster}}",
+          "refId": "A"
+        }
+      ],
+      "title": "Workqueue Depth per Logical Cluster",
+      "type": "timeseries"
+    },
+    {
+      "datasource": "${DS_PROMETHEUS}",
+      "description": "Logical clusters reconciled by the controllers since their start",
+      "fieldConfig": {
+        "defaults": {},
+        "overrides": []
+      },
+      "gridPos": {
+        "h": 8,
+        "w": 12,
+        "x": 12,
+        "y": 16
+      },
+      "id": 6,
+      "targets": [
+        {
+          "datasource": "${DS_PROMETHEUS}",
+          "expr": "max by (controller) (kcp_controller_clusters{job=\"$job\", controller=~\"$controller\"})",
+          "legendFormat": "{{controller}}",
+          "refId": "A"
+        }
+      ],
+      "title": "Logical Clusters per Controller",
+      "type": "timeseries"
+    }
+  ],
+  "refresh": "",
+  "schemaVersion": 36,
+  "style": "dark",
+  "tags": ["kcp"],
+  "templating": {
+    "list": [
+      {
+        "datasource": "${DS_PROMETHEUS}",
+        "definition": "label_values(kcp_controller_reconcile_total, job)",
+        "hide": 0,
+        "includeAll": false,
+        "multi": false,
+        "name": "job",
+        "options": [],
+        "query": {
+          "query": "label_values(kcp_controller_reconcile_total, job)",
+          "refId": "StandardVariableQuery"
+        },
+        "refresh": 2,
+        "type": "query"
+      },
+      {
+        "current": {
+          "selected": true,
+          "text": ["All"],
+          "value": ["$__all"]
+        },
+        "datasource": "${DS_PROMETHEUS}",
+        "definition": "label_values(kcp_controller_reconcile_total{job=\"$job\"}, controller)",
+        "hide": 0,
+        "includeAll": true,
+        "multi": true,
+        "name": "controller",
+        "options": [],
+        "query": {
+          "query": "label_values(kcp_controller_reconcile_total{job=\"$job\"}, controller)",
+          "refId": "StandardVariableQuery"
+        },
+        "refresh": 2,
+        "type": "query"
+      },
+      {
+        "current": {
+          "selected": true,
+          "text": "10",
+          "value": "10"
+        },
+        "hide": 0,
+        "name": "top",
+        "options": [],
+        "query": "5,10,20,50",
+        "type": "custom"
+      }
+    ]
+  },
+  "time": {
+    "from": "now-1h",
+    "to": "now"
+  },
+  "timepicker": {},
+  "timezone": "",
+  "title": "[[ .ProjectName ]] Logical Cluster Metrics",
+  "weekStart": ""
+}
+`
@@ -1,18 +1,27 @@
 package kcp
 
 import (
+	"fmt"
 	"path/filepath"
 
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+
+	"github.com/fgiloux/kcp-operator-sdk/plugins"
 )
 
 var _ machinery.Template = &APIExport{}
+var _ machinery.Inserter = &APIExport{}
 
 // APIExport scaffolds an apiexport.yaml for the manifests overlay folder.
+// The permission claims needed by the controllers are added to it.
 type APIExport struct {
 	machinery.TemplateMixin
 	machinery.ProjectNameMixin
 	machinery.DomainMixin
+	machinery.ResourceMixin
+
+	// Claims are the permission claims needed by the controllers of the project
+	Claims []plugins.Claim
 }
 
 // SetTemplateDefaults implements machinery.Template
@@ -27,15 +36,47 @@ func (f *APIExport) SetTemplateDefaults() error {
 	// needs to be replaced with /spec/template/spec/containers/0/volumeMounts/0
 	f.IfExistsAction = machinery.SkipFile
 
-	f.TemplateBody = apiexportTemplate
+	f.TemplateBody = fmt.Sprintf(apiexportTemplate,
+		machinery.NewMarkerFor(f.Path, permissionClaimsMarker),
+	)
 
 	return nil
 }
 
+const permissionClaimsMarker = "permissionclaims"
+
+// GetMarkers implements machinery.Inserter
+func (f *APIExport) GetMarkers() []machinery.Marker {
+	return []machinery.Marker{
+		machinery.NewMarkerFor(f.Path, permissionClaimsMarker),
+	}
+}
+
+const permissionClaimFragment = `  - group: %q
+    resource: %s
+`
+
+// GetCodeFragments implements machinery.Inserter
+func (f *APIExport) GetCodeFragments() machinery.CodeFragmentsMap {
+	fragments := make(machinery.CodeFragmentsMap, 1)
+	claims := make([]string, 0, len(f.Claims))
+	for _, claim := range f.Claims {
+		claims = append(claims, fmt.Sprintf(permissionClaimFragment, claim.Group, claim.Resource))
+	}
+	if len(claims) != 0 {
+		fragments[machinery.NewMarkerFor(f.Path, permissionClaimsMarker)] = claims
+	}
+	return fragments
+}
+
 const apiexportTemplate = `# Controller APIExport
 apiVersion: apis.kcp.dev/v1alpha1
 kind: APIExport
 metadata:
   name: {{ .ProjectName }}.{{ .Domain }}
 spec:
+  # The resources defined outside of the project, e.g. the core types, reached by the controllers in the workspaces
+  # of the tenants. The tenants accept the claims in their APIBindings.
+  permissionClaims:
+  %s
 `
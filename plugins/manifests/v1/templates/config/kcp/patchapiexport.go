@@ -1,19 +1,25 @@
 package kcp
 
 import (
+	"fmt"
 	"path/filepath"
 
 	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
 )
 
 var _ machinery.Template = &PatchAPIExport{}
+var _ machinery.Inserter = &PatchAPIExport{}
 
 // PatchAPIExport scaffolds a kustomizeconfig.yaml for the manifests overlay folder.
+// The APIResourceSchemas of the resources defined by the project are added to it.
 type PatchAPIExport struct {
 	machinery.TemplateMixin
 	machinery.ProjectNameMixin
 	machinery.DomainMixin
 	machinery.ResourceMixin
+
+	// External indicates whether the resource is defined outside of the project, e.g. a core type
+	External bool
 }
 
 // SetTemplateDefaults implements machinery.Template
@@ -28,11 +34,37 @@ func (f *PatchAPIExport) SetTemplateDefaults() error {
 	// needs to be replaced with /spec/template/spec/containers/0/volumeMounts/0
 	f.IfExistsAction = machinery.SkipFile
 
-	f.TemplateBody = patchAPIExportTemplate
+	f.TemplateBody = fmt.Sprintf(patchAPIExportTemplate,
+		machinery.NewMarkerFor(f.Path, latestResourceSchemasMarker),
+	)
 
 	return nil
 }
 
+const latestResourceSchemasMarker = "latestresourceschemas"
+
+// GetMarkers implements machinery.Inserter
+func (f *PatchAPIExport) GetMarkers() []machinery.Marker {
+	return []machinery.Marker{
+		machinery.NewMarkerFor(f.Path, latestResourceSchemasMarker),
+	}
+}
+
+// The APIResourceSchemas are named after the CRDs, prefixed by APIEXPORT_PREFIX in the Makefile.
+const latestResourceSchemaFragment = `  - PREFIX.%s.%s
+`
+
+// GetCodeFragments implements machinery.Inserter
+func (f *PatchAPIExport) GetCodeFragments() machinery.CodeFragmentsMap {
+	fragments := make(machinery.CodeFragmentsMap, 1)
+	if !f.External {
+		fragments[machinery.NewMarkerFor(f.Path, latestResourceSchemasMarker)] = []string{
+			fmt.Sprintf(latestResourceSchemaFragment, f.Resource.Plural, f.Resource.QualifiedGroup()),
+		}
+	}
+	return fragments
+}
+
 const patchAPIExportTemplate = `# Set the reference to the latest APIRresourceSchema
 ---
 apiVersion: apis.kcp.dev/v1alpha1
@@ -41,6 +73,5 @@ metadata:
   name: {{ .ProjectName }}.{{ .Domain }}
 spec:
   latestResourceSchemas:
-     - PREFIX.{{ .Resource.Plural }}.{{ .Domain }}
-
+  %s
 `
@@ -0,0 +1,42 @@
+package kcpleaderelection
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &Kustomization{}
+
+// Kustomization scaffolds a kustomization.yaml for the manifests of the workspace of the leader election.
+type Kustomization struct {
+	machinery.TemplateMixin
+	machinery.ProjectNameMixin
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *Kustomization) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("config", "kcp-leader-election", "kustomization.yaml")
+	}
+
+	f.IfExistsAction = machinery.SkipFile
+
+	f.TemplateBody = kustomizationTemplate
+
+	return nil
+}
+
+const kustomizationTemplate = `# These resources are the namespace and the permissions of the leader election lease
+# in the workspace passed to the controller with --leader-election-workspace, or set
+# in leaderElectionWorkspace of the component configuration. They are applied with:
+#   make deploy-leader-election LEADER_ELECTION_WORKSPACE=root:my-org:my-workspace
+namespace: {{ .ProjectName }}-system
+
+namePrefix: {{ .ProjectName }}-
+
+resources:
+- namespace.yaml
+- role.yaml
+- role_binding.yaml
+`
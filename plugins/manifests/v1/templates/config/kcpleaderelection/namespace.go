@@ -0,0 +1,42 @@
+package kcpleaderelection
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+var _ machinery.Template = &Namespace{}
+
+// Namespace scaffolds the namespace of the leader election lease.
+type Namespace struct {
+	machinery.TemplateMixin
+	machinery.ProjectNameMixin
+}
+
+// SetTemplateDefaults implements machinery.Template
+func (f *Namespace) SetTemplateDefaults() error {
+	if f.Path == "" {
+		f.Path = filepath.Join("config", "kcp-leader-election", "namespace.yaml")
+	}
+
+	f.IfExistsAction = machinery.SkipFile
+
+	f.TemplateBody = namespaceTemplate
+
+	return nil
+}
+
+const namespaceTemplate = `# The namespace of the leader election lease, it is renamed by the kustomization.
+apiVersion: v1
+kind: Namespace
+metadata:
+  labels:
+    app.kubernetes.io/name: namespace
+    app.kubernetes.io/instance: system
+    app.kubernetes.io/component: leader-election
+    app.kubernetes.io/created-by: {{ .ProjectName }}
+    app.kubernetes.io/part-of: {{ .ProjectName }}
+    app.kubernetes.io/managed-by: kustomize
+  name: system
+`
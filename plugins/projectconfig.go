@@ -0,0 +1,94 @@
+package plugins
+
+// ProjectConfigTemplate is the template of the ProjectConfig document, the component configuration of the manager of
+// a project scaffolded with --component-config, which is loaded into the ProjectConfig type scaffolded under
+// config/v1alpha1. It is rendered into the manager ConfigMap by the golang plugin and into the one of the kcp overlay
+// by the manifests plugin, so that both follow the type. The template expects the ProjectName, Domain, Repo, Mode,
+// APIExportName and Tracing fields.
+const ProjectConfigTemplate = `apiVersion: config.{{ .Domain }}/v1alpha1
+kind: ProjectConfig
+metadata:
+  labels:
+    app.kubernetes.io/name: projectconfig
+    app.kubernetes.io/instance: controller-manager-configuration
+    app.kubernetes.io/component: manager
+    app.kubernetes.io/created-by: {{ .ProjectName }}
+    app.kubernetes.io/part-of: {{ .ProjectName }}
+    app.kubernetes.io/managed-by: kustomize
+# mode selects whether the manager is cluster aware: auto when the server serves
+# the apis.kcp.dev group, kcp to require a kcp server or kubernetes.
+mode: {{ .Mode }}
+{{- if .APIExportName }}
+# apiExportName is the name of the APIExport whose virtual workspace the
+# manager watches.
+apiExportName: "{{ .APIExportName }}"
+{{- else }}
+# apiExportName is the name of the APIExport whose virtual workspace the
+# manager watches when connected to kcp. It defaults to the only APIExport of
+# the workspace of the kubeconfig.
+# apiExportName: {{ .ProjectName }}.{{ .Domain }}
+{{- end }}
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: 127.0.0.1:8080
+webhook:
+  port: 9443
+leaderElection:
+  leaderElect: true
+  resourceName: {{ hashFNV .Repo }}.{{ .Domain }}
+  # resourceNamespace is the namespace of the lease, it defaults to the namespace of the pod.
+  # resourceNamespace: {{ .ProjectName }}-system
+# leaderElectionWorkspace is the path of the workspace of the lease when connected
+# to kcp. It defaults to the workspace of the kubeconfig. The namespace and the
+# permissions of the lease are scaffolded in config/kcp-leader-election.
+# leaderElectionWorkspace: root:my-org:my-workspace
+# leaderElectionReleaseOnCancel defines if the leader should step down volume
+# when the Manager ends. This requires the binary to immediately end when the
+# Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+# speeds up voluntary leader transitions as the new leader don't have to wait
+# LeaseDuration time first.
+# In the default scaffold provided, the program ends immediately after
+# the manager stops, so would be fine to enable this option. However,
+# if you are doing or is intended to do any operation such as perform cleanups
+# after the manager stops then its usage might be unsafe.
+# leaderElectionReleaseOnCancel: true
+# clusterRateLimit configures the token buckets of the logical clusters: the
+# reconciliations of a logical cluster are admitted at the rate of qps per
+# controller, with bursts of up to burst.
+clusterRateLimit:
+  qps: 10
+  burst: 100
+# sharding partitions the logical clusters between the replicas rather than
+# electing a leader. The leases of the replicas are stored with the one of the
+# leader election.
+sharding:
+  enabled: false
+  # leaseDuration: 15s
+  # renewPeriod: 5s
+# dryRun turns the writes of the controllers into server-side dry-run
+# requests. The changes are logged and counted per logical cluster.
+dryRun: false
+# recording records the objects read and written by the controllers for the
+# logical clusters, with the data of the secrets redacted, to replay them offline.
+# recording:
+#   clusters:
+#   - root:my-org:my-workspace
+#   dir: /tmp/recordings
+#   maxEntries: 10000
+# tenants selects the logical clusters reconciled, by name or with a label
+# selector on their APIBindings. A tenant pauses the reconciliation of its
+# objects with the tenants.kcp.io/paused: "true" annotation on its APIBinding.
+# tenants:
+#   allow:
+#   - root:my-org:my-workspace
+#   deny: []
+#   selector: rollout=canary
+{{- if .Tracing }}
+# tracing configures the export of the traces to an OpenTelemetry collector.
+# Tracing is disabled when the endpoint is empty.
+tracing:
+  endpoint: ""
+  # insecure: true
+{{- end }}
+`
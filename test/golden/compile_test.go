@@ -0,0 +1,88 @@
+//go:build integration
+
+package golden
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+)
+
+// moduleCacheEnv is the environment variable pointing the scaffolded projects at a local module cache.
+// When it is set the go commands run offline against that cache, otherwise the environment is inherited.
+const moduleCacheEnv = "INTEGRATION_GOMODCACHE"
+
+// TestCompile scaffolds the projects of the golden file tests into temporary directories and checks
+// that they build and pass go vet and that their end-to-end tests compile. The clients of the cases creating APIs
+// with --generate-client are generated with make generate-client, which installs the code generators in LOCALBIN.
+// Run it with:
+//
+//	go test -tags integration ./test/golden/... -run TestCompile
+func TestCompile(t *testing.T) {
+	pkgDir, err := filepath.Abs(filepath.Join("..", "..", "pkg"))
+	if err != nil {
+		t.Fatalf("error locating the pkg module: %v", err)
+	}
+
+	env := os.Environ()
+	if cache := os.Getenv(moduleCacheEnv); cache != "" {
+		env = append(env, "GOMODCACHE="+cache, "GOPROXY=off", "GOFLAGS=-mod=mod")
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			scaffold(t, machinery.Filesystem{FS: afero.NewBasePathFs(afero.NewOsFs(), dir)}, c)
+
+			// The post-scaffold hooks are skipped by scaffold, run their equivalent here.
+			steps := [][]string{
+				{"go", "mod", "edit", "-replace", "github.com/fgiloux/kcp-operator-sdk/pkg=" + pkgDir},
+				{"go", "mod", "tidy"},
+				{"make", "generate"},
+			}
+			if c.generatesClients() {
+				steps = append(steps, []string{"make", "generate-client"})
+			}
+			steps = append(steps,
+				[]string{"go", "build", "./..."},
+				[]string{"go", "vet", "./..."},
+				[]string{"go", "test", "-run", "xxx", "./test/e2e/..."},
+			)
+			for _, args := range steps {
+				run(t, dir, env, args...)
+			}
+		})
+	}
+}
+
+// generatesClients tells whether an API of the case is created with --generate-client, whose post-scaffold hook
+// runs make generate-client.
+func (c scaffoldCase) generatesClients() bool {
+	for _, args := range c.apis {
+		for _, arg := range args {
+			if arg == "--generate-client" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func run(t *testing.T, dir string, env []string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
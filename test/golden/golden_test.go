@@ -0,0 +1,375 @@
+// Package golden checks the files scaffolded by the init and create api subcommands against golden files.
+//
+// The subcommands of the plugins bundled by the CLI are run against an in-memory filesystem for a matrix
+// of options. The pre-scaffold and post-scaffold hooks are skipped as they inspect the working directory
+// and run go and make. Run the tests with -update to regenerate the golden files after changing a template:
+//
+//	go test ./test/golden/... -update
+//
+// Golden files do not tell whether the scaffolded code compiles. TestCompile, behind the integration
+// build tag, scaffolds the same projects into temporary directories and builds them.
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/kubebuilder/v3/pkg/config"
+	yamlstore "sigs.k8s.io/kubebuilder/v3/pkg/config/store/yaml"
+	cfgv3 "sigs.k8s.io/kubebuilder/v3/pkg/config/v3"
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+	"sigs.k8s.io/kubebuilder/v3/pkg/plugin"
+
+	"github.com/fgiloux/kcp-operator-sdk/internal/cmd/cli"
+)
+
+var update = flag.Bool("update", false, "update the golden files with the scaffolded files")
+
+var currentYear = []byte(fmt.Sprintf("Copyright %d", time.Now().Year()))
+
+const (
+	domain = "example.com"
+	repo   = "github.com/example/memcached-operator"
+)
+
+// scaffoldCase describes a project to scaffold.
+type scaffoldCase struct {
+	name string
+	// initArgs are the flags passed to the init subcommand.
+	initArgs []string
+	// multiGroup enables multi-group support as "edit --multigroup" would.
+	multiGroup bool
+	// apis are the flags passed to each create api subcommand.
+	apis [][]string
+}
+
+var cases = []scaffoldCase{
+	{
+		name: "default",
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached"},
+		},
+	},
+	{
+		name: "multiple-apis",
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached"},
+			{"--group", "cache", "--version", "v1beta1", "--kind", "Redis"},
+		},
+	},
+	{
+		name:     "component-config",
+		initArgs: []string{"--component-config"},
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached"},
+		},
+	},
+	{
+		name:     "tracing",
+		initArgs: []string{"--tracing"},
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached"},
+		},
+	},
+	{
+		name:     "tracing-component-config",
+		initArgs: []string{"--tracing", "--component-config"},
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached"},
+		},
+	},
+	{
+		name:       "multigroup",
+		multiGroup: true,
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached"},
+			{"--group", "ship", "--version", "v1beta1", "--kind", "Frigate"},
+		},
+	},
+	{
+		name: "cluster-scoped",
+		apis: [][]string{
+			{"--group", "crew", "--version", "v1", "--kind", "Captain", "--namespaced=false"},
+		},
+	},
+	{
+		name: "core-group",
+		apis: [][]string{
+			{"--group", "core", "--version", "v1", "--kind", "ConfigMap", "--resource=false"},
+		},
+	},
+	{
+		name:       "finalizer",
+		multiGroup: true,
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached", "--with-finalizer"},
+			{"--group", "crew", "--version", "v1", "--kind", "Captain", "--namespaced=false", "--with-finalizer"},
+		},
+	},
+	{
+		name: "owns",
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached", "--owns=apps/v1/Deployment,core/v1/Service"},
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "MemcachedBackup", "--owns=cache/v1alpha1/Memcached"},
+		},
+	},
+	{
+		name: "references",
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached", "--with-finalizer", "--references=core/v1/Secret"},
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "MemcachedBackup", "--references=cache/v1alpha1/Memcached"},
+		},
+	},
+	{
+		name: "generate-client",
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached", "--generate-client"},
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Captain", "--namespaced=false", "--generate-client"},
+		},
+	},
+	{
+		name:       "tenant-lifecycle",
+		initArgs:   []string{"--tenant-lifecycle"},
+		multiGroup: true,
+		apis: [][]string{
+			{"--group", "cache", "--version", "v1alpha1", "--kind", "Memcached"},
+			{"--group", "ship", "--version", "v1beta1", "--kind", "Frigate"},
+		},
+	},
+}
+
+func TestScaffold(t *testing.T) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			fs := machinery.Filesystem{FS: afero.NewMemMapFs()}
+			scaffold(t, fs, c)
+
+			goldenDir := filepath.Join("testdata", c.name)
+			if *update {
+				writeGolden(t, fs, goldenDir)
+				return
+			}
+			compareGolden(t, fs, goldenDir)
+		})
+	}
+}
+
+// scaffold runs the init and create api subcommands of the bundled plugins against fs.
+func scaffold(t *testing.T, fs machinery.Filesystem, c scaffoldCase) {
+	t.Helper()
+
+	bundle := cli.GetGoV3Bundle()
+	store := yamlstore.New(fs)
+	if err := store.New(cfgv3.Version); err != nil {
+		t.Fatalf("error initializing project configuration: %v", err)
+	}
+	cfg := store.Config()
+	if err := cfg.SetPluginChain([]string{plugin.KeyFor(bundle)}); err != nil {
+		t.Fatalf("error setting the plugin chain: %v", err)
+	}
+
+	var initSubcommands []plugin.Subcommand
+	for _, p := range bundle.Plugins() {
+		if p, ok := p.(plugin.Init); ok {
+			initSubcommands = append(initSubcommands, p.GetInitSubcommand())
+		}
+	}
+	initArgs := append([]string{
+		"--domain", domain,
+		"--repo", repo,
+		"--project-name", "memcached-operator",
+		"--fetch-deps=false",
+		"--skip-go-version-check",
+	}, c.initArgs...)
+	runSubcommands(t, fs, cfg, nil, initSubcommands, initArgs)
+
+	if c.multiGroup {
+		if err := cfg.SetMultiGroup(); err != nil {
+			t.Fatalf("error enabling multi-group support: %v", err)
+		}
+	}
+
+	for _, apiArgs := range c.apis {
+		var apiSubcommands []plugin.Subcommand
+		for _, p := range bundle.Plugins() {
+			if p, ok := p.(plugin.CreateAPI); ok {
+				apiSubcommands = append(apiSubcommands, p.GetCreateAPISubcommand())
+			}
+		}
+		runSubcommands(t, fs, cfg, &resource.GVK{Domain: cfg.GetDomain()}, apiSubcommands,
+			append([]string{"--make=false", "--resource", "--controller"}, apiArgs...))
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("error saving the project configuration: %v", err)
+	}
+}
+
+// runSubcommands mimics the hooks of the kubebuilder CLI for the subcommands, skipping the pre-scaffold
+// and post-scaffold ones. When gvk is not nil a resource is created from the group, version and kind flags.
+func runSubcommands(t *testing.T, fs machinery.Filesystem, cfg config.Config, gvk *resource.GVK,
+	subcommands []plugin.Subcommand, args []string) {
+	t.Helper()
+
+	flags := pflag.NewFlagSet("scaffold", pflag.ContinueOnError)
+	if gvk != nil {
+		flags.StringVar(&gvk.Group, "group", "", "resource Group")
+		flags.StringVar(&gvk.Version, "version", "", "resource Version")
+		flags.StringVar(&gvk.Kind, "kind", "", "resource Kind")
+	}
+	for _, s := range subcommands {
+		if s, ok := s.(plugin.HasFlags); ok {
+			s.BindFlags(flags)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("error parsing flags %v: %v", args, err)
+	}
+
+	for _, s := range subcommands {
+		if s, ok := s.(plugin.RequiresConfig); ok {
+			if err := s.InjectConfig(cfg); err != nil {
+				t.Fatalf("error injecting the configuration: %v", err)
+			}
+		}
+	}
+
+	if gvk != nil {
+		res := &resource.Resource{
+			GVK:      *gvk,
+			Plural:   resource.RegularPlural(gvk.Kind),
+			API:      &resource.API{},
+			Webhooks: &resource.Webhooks{},
+		}
+		for _, s := range subcommands {
+			if s, ok := s.(plugin.RequiresResource); ok {
+				if err := s.InjectResource(res); err != nil {
+					t.Fatalf("error injecting the resource: %v", err)
+				}
+			}
+		}
+		if err := res.Validate(); err != nil {
+			t.Fatalf("invalid resource: %v", err)
+		}
+	}
+
+	for _, s := range subcommands {
+		if err := s.Scaffold(fs); err != nil {
+			t.Fatalf("error scaffolding: %v", err)
+		}
+	}
+}
+
+// scaffoldedFiles returns the content of the files in fs indexed by their slash separated path.
+func scaffoldedFiles(t *testing.T, fs machinery.Filesystem) map[string][]byte {
+	t.Helper()
+
+	files := map[string][]byte{}
+	if err := afero.Walk(fs.FS, ".", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := afero.ReadFile(fs.FS, path)
+		if err != nil {
+			return err
+		}
+		// The boilerplate is stamped with the current year.
+		files[filepath.ToSlash(path)] = bytes.ReplaceAll(content, currentYear, []byte("Copyright YEAR"))
+		return nil
+	}); err != nil {
+		t.Fatalf("error reading the scaffolded files: %v", err)
+	}
+	return files
+}
+
+func writeGolden(t *testing.T, fs machinery.Filesystem, goldenDir string) {
+	t.Helper()
+
+	if err := os.RemoveAll(goldenDir); err != nil {
+		t.Fatalf("error removing %s: %v", goldenDir, err)
+	}
+	for path, content := range scaffoldedFiles(t, fs) {
+		goldenPath := filepath.Join(goldenDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatalf("error creating the directory of %s: %v", goldenPath, err)
+		}
+		if err := os.WriteFile(goldenPath, content, 0644); err != nil {
+			t.Fatalf("error writing %s: %v", goldenPath, err)
+		}
+	}
+}
+
+func compareGolden(t *testing.T, fs machinery.Filesystem, goldenDir string) {
+	t.Helper()
+
+	files := scaffoldedFiles(t, fs)
+
+	golden := map[string]bool{}
+	if err := filepath.Walk(goldenDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(goldenDir, path)
+		if err != nil {
+			return err
+		}
+		golden[filepath.ToSlash(rel)] = true
+		return nil
+	}); err != nil {
+		t.Fatalf("error reading the golden files, run the tests with -update to create them: %v", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if !golden[path] {
+			t.Errorf("%s was scaffolded but has no golden file", path)
+			continue
+		}
+		expected, err := os.ReadFile(filepath.Join(goldenDir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Fatalf("error reading the golden file of %s: %v", path, err)
+		}
+		if !bytes.Equal(expected, files[path]) {
+			t.Errorf("%s does not match its golden file:\n%s", path, diff(string(expected), string(files[path])))
+		}
+	}
+	for path := range golden {
+		if _, ok := files[path]; !ok {
+			t.Errorf("%s has a golden file but was not scaffolded", path)
+		}
+	}
+}
+
+// diff returns the first line differing between expected and actual with some context.
+func diff(expected, actual string) string {
+	e, a := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	for i := 0; i < len(e) || i < len(a); i++ {
+		var el, al string
+		if i < len(e) {
+			el = e[i]
+		}
+		if i < len(a) {
+			al = a[i]
+		}
+		if el != al {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, el, al)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	// to ensure that exec-entrypoint and run can make use of them.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusterratelimit"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcpmanager"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/recording"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/events"
+
+	cachev1alpha1 "github.com/example/memcached-operator/api/v1alpha1"
+	configv1alpha1 "github.com/example/memcached-operator/config/v1alpha1"
+	"github.com/example/memcached-operator/controllers"
+	//+kubebuilder:scaffold:imports
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
+
+	utilruntime.Must(cachev1alpha1.AddToScheme(scheme))
+	//+kubebuilder:scaffold:scheme
+}
+
+func main() {
+	var configFile string
+	var apiExportName string
+	var metricsTopClusters int
+	flag.StringVar(&apiExportName, "api-export-name", "",
+		"The name of the APIExport. It overrides the apiExportName of the configuration file.")
+	flag.StringVar(&configFile, "config", "",
+		"The controller will load its initial configuration from this file. "+
+			"Omit this flag to use the default configuration values. "+
+			"Command-line flags override configuration from this file.")
+	flag.IntVar(&metricsTopClusters, "metrics-top-clusters", clustermetrics.DefaultTopClusters,
+		"The number of logical clusters with the most reconciliations exposed under their own label "+
+			"by the metrics of each controller. The other logical clusters are aggregated.")
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	ctx := ctrl.SetupSignalHandler()
+
+	var err error
+	ctrlConfig := configv1alpha1.ProjectConfig{}
+	options := ctrl.Options{Scheme: scheme}
+	if configFile != "" {
+		// The configuration is validated when it is loaded, see ProjectConfig.Complete.
+		options, err = options.AndFrom(ctrl.ConfigFile().AtPath(configFile).OfKind(&ctrlConfig))
+		if err != nil {
+			setupLog.Error(err, "unable to load the config file")
+			os.Exit(1)
+		}
+	}
+	if apiExportName == "" {
+		apiExportName = ctrlConfig.APIExportName
+	}
+	setupLog = setupLog.WithValues("api-export-name", apiExportName)
+
+	// The reconciliations are recorded per logical cluster by the controllers, see SetupWithManager.
+	if err := clustermetrics.Register(metrics.Registry, clustermetrics.Options{TopClusters: metricsTopClusters}); err != nil {
+		setupLog.Error(err, "unable to register the logical cluster metrics")
+		os.Exit(1)
+	}
+
+	// The reconciliations of the controllers are rate limited per logical cluster, see SetupWithManager.
+	clusterratelimit.SetDefaultOptions(clusterratelimit.Options{
+		QPS:   float64(ctrlConfig.ClusterRateLimit.QPS),
+		Burst: ctrlConfig.ClusterRateLimit.Burst,
+	})
+
+	// The logical clusters are partitioned between the replicas when sharding is enabled, see SetupWithManager.
+	var shardingOptions *sharding.Options
+	if ctrlConfig.Sharding.Enabled {
+		shardingOptions = &sharding.Options{
+			LeaseDuration: ctrlConfig.Sharding.LeaseDuration.Duration,
+			RenewPeriod:   ctrlConfig.Sharding.RenewPeriod.Duration,
+		}
+	}
+
+	// The manager is cluster aware and watches the virtual workspace of the APIExport when
+	// connected to kcp. A standard manager is created when connected to a Kubernetes cluster.
+	mgr, err := kcpmanager.NewManager(ctx, kcpmanager.Options{
+		APIExportName:           apiExportName,
+		Mode:                    ctrlConfig.Mode,
+		Manager:                 options,
+		LeaderElectionWorkspace: ctrlConfig.LeaderElectionWorkspace,
+		DryRun:                  ctrlConfig.DryRun,
+		Recording: recording.Options{
+			Clusters:   ctrlConfig.Recording.Clusters,
+			Dir:        ctrlConfig.Recording.Dir,
+			MaxEntries: ctrlConfig.Recording.MaxEntries,
+		},
+		Sharding: shardingOptions,
+		// The events of the logical clusters not selected are dropped by the controllers, see SetupWithManager.
+		Tenants: tenants.Options{
+			Allow:    ctrlConfig.Tenants.Allow,
+			Deny:     ctrlConfig.Tenants.Deny,
+			Selector: ctrlConfig.Tenants.Selector,
+		},
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	memcachedRecorder := events.NewRecorder(mgr.GetClient(), mgr.GetScheme(), "memcached-controller")
+	if err = mgr.Add(memcachedRecorder); err != nil {
+		setupLog.Error(err, "unable to add the event recorder", "controller", "Memcached")
+		os.Exit(1)
+	}
+	if err = (&controllers.MemcachedReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: memcachedRecorder,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Memcached")
+		os.Exit(1)
+	}
+	//+kubebuilder:scaffold:builder
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctx); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// +kubebuilder:rbac:groups="apis.kcp.dev",resources=apiexports,verbs=get;list;watch
@@ -0,0 +1,85 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcpmanager"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcptest"
+
+	configv1alpha1 "github.com/example/memcached-operator/config/v1alpha1"
+)
+
+// TestNewManager creates the manager with the scheme of the project against a fake kcp server,
+// which serves the APIExport of the controller, and against a fake Kubernetes API server.
+func TestNewManager(t *testing.T) {
+	tests := []struct {
+		name string
+		opts kcptest.Options
+		// virtualWorkspace is true if the manager is expected to connect to the virtual workspace of the APIExport.
+		virtualWorkspace bool
+	}{
+		{name: "kcp", virtualWorkspace: true},
+		{name: "kubernetes", opts: kcptest.Options{WithoutKCPAPIs: true}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := kcptest.NewServer(t, tt.opts)
+			s.AddAPIExports(s.NewAPIExport("memcached-operator"))
+
+			mgr, err := kcpmanager.NewManager(context.Background(), kcpmanager.Options{
+				RestConfig:    s.RestConfig(),
+				APIExportName: "memcached-operator",
+				Manager: ctrl.Options{
+					Scheme:             scheme,
+					MetricsBindAddress: "0",
+				},
+			})
+			if err != nil {
+				t.Fatalf("unable to create the manager: %v", err)
+			}
+
+			want := s.URL
+			if tt.virtualWorkspace {
+				want = s.VirtualWorkspaceURL("memcached-operator")
+			}
+			if host := mgr.GetConfig().Host; host != want {
+				t.Errorf("expected the manager to connect to %s, got %s", want, host)
+			}
+		})
+	}
+}
+
+// TestProjectConfig loads the component configuration of the manager ConfigMap, which is validated when it is
+// loaded, and verifies that an invalid configuration is rejected.
+func TestProjectConfig(t *testing.T) {
+	var ctrlConfig configv1alpha1.ProjectConfig
+	if _, err := (ctrl.Options{Scheme: scheme}).AndFrom(
+		ctrl.ConfigFile().AtPath("config/manager/controller_manager_config.yaml").OfKind(&ctrlConfig)); err != nil {
+		t.Fatalf("unable to load the configuration: %v", err)
+	}
+
+	ctrlConfig.Mode = "openshift"
+	if err := ctrlConfig.Validate(); err == nil {
+		t.Error("expected the configuration with an unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusteraware"
+
+	cachev1alpha1 "github.com/example/memcached-operator/apis/cache/v1alpha1"
+)
+
+var _ = Describe("Memcached controller", func() {
+	It("adds the finalizer and removes it after the cleanup", func() {
+		ctx := context.Background()
+		// The reconciler is wrapped as in SetupWithManager, the requests of the test environment have no logical cluster.
+		reconciler := clusteraware.NewReconciler(&MemcachedReconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: record.NewFakeRecorder(16),
+		})
+
+		memcached := &cachev1alpha1.Memcached{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-memcached",
+				Namespace: "default",
+			},
+		}
+		key := client.ObjectKeyFromObject(memcached)
+		req := ctrl.Request{NamespacedName: key}
+
+		By("reconciling the created Memcached")
+		Expect(k8sClient.Create(ctx, memcached)).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, memcached)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(memcached, MemcachedFinalizer)).To(BeTrue())
+
+		By("reconciling the deleted Memcached")
+		Expect(k8sClient.Delete(ctx, memcached)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, key, memcached)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("reconciling the Memcached once it is gone")
+		_, err = reconciler.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,192 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crew
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusteraware"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusterratelimit"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+
+	crewv1 "github.com/example/memcached-operator/apis/crew/v1"
+)
+
+// CaptainFinalizer is the finalizer added to the Captain objects, so that their deletion
+// waits for the cleanup of the controller.
+const CaptainFinalizer = "crew.example.com/finalizer"
+
+// CaptainReconciler reconciles a Captain object
+type CaptainReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Recorder records the events of the reconciled objects in their workspaces, where the tenants see them, e.g.
+	//	r.Recorder.Event(obj, corev1.EventTypeNormal, "Reconciled", "The spec is applied")
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=crew.example.com,resources=captains,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=crew.example.com,resources=captains/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=crew.example.com,resources=captains/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// TODO(user): Modify the Reconcile function to compare the state specified by
+// the Captain object against the actual cluster state, and then
+// perform operations to make the cluster state reflect the state specified by
+// the user.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.2/pkg/reconcile
+func (r *CaptainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	// The logger and the context are scoped to the logical cluster of the request, see SetupWithManager.
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Starting reconcile")
+
+	// The client scopes its requests to the logical cluster of ctx: always pass ctx, never a new context.
+	captain := &crewv1.Captain{}
+	if err := r.Get(ctx, req.NamespacedName, captain); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The object was deleted after its cleanup, there is nothing left to do.
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !captain.GetDeletionTimestamp().IsZero() {
+		if controllerutil.ContainsFinalizer(captain, CaptainFinalizer) {
+			logger.Info("Cleaning up before deletion")
+			if err := r.cleanup(ctx, captain); err != nil {
+				return ctrl.Result{}, err
+			}
+			controllerutil.RemoveFinalizer(captain, CaptainFinalizer)
+			if err := r.Update(ctx, captain); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(captain, CaptainFinalizer) {
+		controllerutil.AddFinalizer(captain, CaptainFinalizer)
+		if err := r.Update(ctx, captain); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Progressing is set when a new generation of the spec is observed, until it is applied.
+	ready := meta.FindStatusCondition(captain.Status.Conditions, crewv1.CaptainReady)
+	if ready == nil || ready.ObservedGeneration != captain.GetGeneration() {
+		if err := r.updateStatus(ctx, captain, metav1.Condition{
+			Type:    crewv1.CaptainProgressing,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Reconciling",
+			Message: "The spec is being applied",
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// TODO(user): your logic here. When it fails, set Ready to False with a reason telling the tenant what
+	// is wrong before returning the error, e.g.
+	//	_ = r.updateStatus(ctx, captain, metav1.Condition{Type: crewv1.CaptainReady,
+	//		Status: metav1.ConditionFalse, Reason: "Failed", Message: err.Error()})
+
+	if err := r.updateStatus(ctx, captain, metav1.Condition{
+		Type:    crewv1.CaptainReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Reconciled",
+		Message: "The spec is applied",
+	}, metav1.Condition{
+		Type:    crewv1.CaptainProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Reconciled",
+		Message: "The spec is applied",
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// updateStatus sets the conditions of captain for its current generation and patches its status.
+// The status is not patched when it is unchanged, so that the reconciliation does not trigger itself.
+func (r *CaptainReconciler) updateStatus(ctx context.Context, captain *crewv1.Captain, conditions ...metav1.Condition) error {
+	original := captain.DeepCopy()
+	for _, condition := range conditions {
+		condition.ObservedGeneration = captain.GetGeneration()
+		meta.SetStatusCondition(&captain.Status.Conditions, condition)
+	}
+	if equality.Semantic.DeepEqual(original.Status, captain.Status) {
+		return nil
+	}
+	return r.Status().Patch(ctx, captain, client.MergeFrom(original))
+}
+
+// cleanup is called before the deletion of captain, whose finalizer is removed when it succeeds.
+func (r *CaptainReconciler) cleanup(ctx context.Context, captain *crewv1.Captain) error {
+	// TODO(user): release the resources of captain outside of its workspace, e.g. in an external
+	// system. The objects of the workspace it owns are garbage collected.
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// The reconciler is wrapped by clusteraware.NewReconciler, which scopes the context and the logger
+// to the logical cluster of each request and recovers from panics, and by clustermetrics.NewReconciler,
+// which records the reconciliations per logical cluster.
+// The requests are admitted by a clusterratelimit.Limiter, which gives each logical cluster its own token bucket:
+// the requests of a logical cluster creating many objects are requeued and spread at the rate of its bucket.
+// The workqueue of the controller is replaced by the clusterratelimit.Queue of the limiter, which has a sub-queue
+// per logical cluster and hands out their requests in turn, so that the requests of the other logical clusters do
+// not wait behind the backlog of a logical cluster. Its depth per logical cluster is exposed by clustermetrics.
+// When sharding is enabled, the requests of the logical clusters owned by other replicas are skipped by
+// sharding.NewReconciler and the objects of the logical clusters acquired by the replica are requeued by
+// sharding.Source.
+// The requests of the logical clusters not selected by the tenants flags, or paused by their APIBinding, are
+// skipped by tenants.NewReconciler, including the requests queued before the logical cluster was paused and the
+// ones mapped from the changes of other objects. The events of their objects are dropped by tenants.Predicate and
+// the objects of the logical clusters that become selected are requeued by tenants.Source.
+func (r *CaptainReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	limiter := clusterratelimit.NewLimiter()
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&crewv1.Captain{}, builder.WithPredicates(tenants.Predicate())).
+		Watches(sharding.Source(mgr.GetCache(), &crewv1.CaptainList{}), &handler.EnqueueRequestForObject{}).
+		Watches(tenants.Source(mgr.GetCache(), &crewv1.CaptainList{}), &handler.EnqueueRequestForObject{}).
+		WithOptions(controller.Options{RateLimiter: limiter.RateLimiter()}).
+		Build(sharding.NewReconciler(tenants.NewReconciler(limiter.NewReconciler(clustermetrics.NewReconciler("captain", clusteraware.NewReconciler(r))))))
+	if err != nil {
+		return err
+	}
+	return limiter.SetQueue(c, "captain")
+}
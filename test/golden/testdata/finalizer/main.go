@@ -0,0 +1,214 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	// to ensure that exec-entrypoint and run can make use of them.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusterratelimit"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/events"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/kcpmanager"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/recording"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+
+	cachev1alpha1 "github.com/example/memcached-operator/apis/cache/v1alpha1"
+	crewv1 "github.com/example/memcached-operator/apis/crew/v1"
+	cachecontrollers "github.com/example/memcached-operator/controllers/cache"
+	crewcontrollers "github.com/example/memcached-operator/controllers/crew"
+	//+kubebuilder:scaffold:imports
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	utilruntime.Must(cachev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(crewv1.AddToScheme(scheme))
+	//+kubebuilder:scaffold:scheme
+}
+
+func main() {
+	var metricsAddr string
+	var enableLeaderElection bool
+	var probeAddr string
+	var apiExportName string
+	var metricsTopClusters int
+	flag.StringVar(&apiExportName, "api-export-name", "", "The name of the APIExport.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	var leaderElectionWorkspace string
+	var leaderElectionNamespace string
+	var leaderElectionID string
+	flag.StringVar(&leaderElectionWorkspace, "leader-election-workspace", "",
+		"The path of the workspace of the leader election lease when connected to kcp, e.g. root:org:ws. "+
+			"It defaults to the workspace of the kubeconfig.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"The namespace of the leader election lease. It defaults to the namespace of the pod.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "86f835c3.example.com",
+		"The name of the leader election lease.")
+	flag.IntVar(&metricsTopClusters, "metrics-top-clusters", clustermetrics.DefaultTopClusters,
+		"The number of logical clusters with the most reconciliations exposed under their own label "+
+			"by the metrics of each controller. The other logical clusters are aggregated.")
+	var clusterQPS float64
+	var clusterBurst int
+	flag.Float64Var(&clusterQPS, "cluster-qps", clusterratelimit.DefaultQPS,
+		"The rate of the reconciliations of a logical cluster per controller.")
+	flag.IntVar(&clusterBurst, "cluster-burst", clusterratelimit.DefaultBurst,
+		"The number of reconciliations of a logical cluster admitted at once per controller.")
+	var enableSharding bool
+	flag.BoolVar(&enableSharding, "enable-sharding", false,
+		"Partition the logical clusters between the replicas of the controller manager rather than electing a leader.")
+	var tenantsOptions tenants.Options
+	tenantsOptions.BindFlags(flag.CommandLine)
+	var dryRun bool
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Turn the writes of the controllers into server-side dry-run requests. "+
+			"The changes are logged and counted per logical cluster by the kcp_controller_dry_run_changes_total metric.")
+	var recordingOptions recording.Options
+	recordingOptions.BindFlags(flag.CommandLine)
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	setupLog = setupLog.WithValues("api-export-name", apiExportName)
+
+	ctx := ctrl.SetupSignalHandler()
+
+	options := ctrl.Options{
+		Scheme:                  scheme,
+		MetricsBindAddress:      metricsAddr,
+		Port:                    9443,
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: leaderElectionNamespace,
+		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
+		// when the Manager ends. This requires the binary to immediately end when the
+		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+		// speeds up voluntary leader transitions as the new leader don't have to wait
+		// LeaseDuration time first.
+		//
+		// In the default scaffold provided, the program ends immediately after
+		// the manager stops, so would be fine to enable this option. However,
+		// if you are doing or is intended to do any operation such as perform cleanups
+		// after the manager stops then its usage might be unsafe.
+		// LeaderElectionReleaseOnCancel: true,
+	}
+
+	// The reconciliations are recorded per logical cluster by the controllers, see SetupWithManager.
+	if err := clustermetrics.Register(metrics.Registry, clustermetrics.Options{TopClusters: metricsTopClusters}); err != nil {
+		setupLog.Error(err, "unable to register the logical cluster metrics")
+		os.Exit(1)
+	}
+
+	// The reconciliations of the controllers are rate limited per logical cluster, see SetupWithManager.
+	clusterratelimit.SetDefaultOptions(clusterratelimit.Options{QPS: clusterQPS, Burst: clusterBurst})
+
+	// The logical clusters are partitioned between the replicas when sharding is enabled, see SetupWithManager.
+	var shardingOptions *sharding.Options
+	if enableSharding {
+		shardingOptions = &sharding.Options{}
+	}
+
+	// The manager is cluster aware and watches the virtual workspace of the APIExport when
+	// connected to kcp. A standard manager is created when connected to a Kubernetes cluster.
+	mgr, err := kcpmanager.NewManager(ctx, kcpmanager.Options{
+		APIExportName:           apiExportName,
+		Manager:                 options,
+		LeaderElectionWorkspace: leaderElectionWorkspace,
+		DryRun:                  dryRun,
+		Recording:               recordingOptions,
+		Sharding:                shardingOptions,
+		// The events of the logical clusters not selected are dropped by the controllers, see SetupWithManager.
+		Tenants: tenantsOptions,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	cacheMemcachedRecorder := events.NewRecorder(mgr.GetClient(), mgr.GetScheme(), "memcached-controller")
+	if err = mgr.Add(cacheMemcachedRecorder); err != nil {
+		setupLog.Error(err, "unable to add the event recorder", "controller", "Memcached")
+		os.Exit(1)
+	}
+	if err = (&cachecontrollers.MemcachedReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: cacheMemcachedRecorder,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Memcached")
+		os.Exit(1)
+	}
+	crewCaptainRecorder := events.NewRecorder(mgr.GetClient(), mgr.GetScheme(), "captain-controller")
+	if err = mgr.Add(crewCaptainRecorder); err != nil {
+		setupLog.Error(err, "unable to add the event recorder", "controller", "Captain")
+		os.Exit(1)
+	}
+	if err = (&crewcontrollers.CaptainReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: crewCaptainRecorder,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Captain")
+		os.Exit(1)
+	}
+	//+kubebuilder:scaffold:builder
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctx); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// +kubebuilder:rbac:groups="apis.kcp.dev",resources=apiexports,verbs=get;list;watch
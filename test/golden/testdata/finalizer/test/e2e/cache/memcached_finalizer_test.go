@@ -0,0 +1,78 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	cachev1alpha1 "github.com/example/memcached-operator/apis/cache/v1alpha1"
+	controllers "github.com/example/memcached-operator/controllers/cache"
+)
+
+// TestMemcachedFinalizer verifies that the controller adds its finalizer to a Memcached and
+// removes it once the Memcached is deleted, letting the deletion complete.
+func TestMemcachedFinalizer(t *testing.T) {
+	t.Parallel()
+	workspaceCluster := parentWorkspace(t).Join(randomName())
+	c := createWorkspace(t, workspaceCluster)
+
+	memcached := &cachev1alpha1.Memcached{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      randomName(),
+			Namespace: "default",
+		},
+	}
+	key := client.ObjectKeyFromObject(memcached)
+	t.Logf("creating Memcached %s|%s", workspaceCluster, key)
+	if err := c.Create(context.TODO(), memcached); err != nil {
+		t.Fatalf("failed to create Memcached: %v", err)
+	}
+
+	t.Logf("waiting for the finalizer to be added to Memcached %s|%s", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		if err := c.Get(context.TODO(), key, memcached); err != nil {
+			return false, err
+		}
+		return controllerutil.ContainsFinalizer(memcached, controllers.MemcachedFinalizer), nil
+	}); err != nil {
+		t.Fatalf("finalizer never added to Memcached %s|%s: %v", workspaceCluster, key, err)
+	}
+
+	t.Logf("deleting Memcached %s|%s", workspaceCluster, key)
+	if err := c.Delete(context.TODO(), memcached); err != nil {
+		t.Fatalf("failed to delete Memcached: %v", err)
+	}
+
+	t.Logf("waiting for Memcached %s|%s to be gone", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		err = c.Get(context.TODO(), key, memcached)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		t.Fatalf("Memcached %s|%s never deleted: %v", workspaceCluster, key, err)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crew
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	crewv1 "github.com/example/memcached-operator/apis/crew/v1"
+	controllers "github.com/example/memcached-operator/controllers/crew"
+)
+
+// TestCaptainFinalizer verifies that the controller adds its finalizer to a Captain and
+// removes it once the Captain is deleted, letting the deletion complete.
+func TestCaptainFinalizer(t *testing.T) {
+	t.Parallel()
+	workspaceCluster := parentWorkspace(t).Join(randomName())
+	c := createWorkspace(t, workspaceCluster)
+
+	captain := &crewv1.Captain{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: randomName(),
+		},
+	}
+	key := client.ObjectKeyFromObject(captain)
+	t.Logf("creating Captain %s|%s", workspaceCluster, key)
+	if err := c.Create(context.TODO(), captain); err != nil {
+		t.Fatalf("failed to create Captain: %v", err)
+	}
+
+	t.Logf("waiting for the finalizer to be added to Captain %s|%s", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		if err := c.Get(context.TODO(), key, captain); err != nil {
+			return false, err
+		}
+		return controllerutil.ContainsFinalizer(captain, controllers.CaptainFinalizer), nil
+	}); err != nil {
+		t.Fatalf("finalizer never added to Captain %s|%s: %v", workspaceCluster, key, err)
+	}
+
+	t.Logf("deleting Captain %s|%s", workspaceCluster, key)
+	if err := c.Delete(context.TODO(), captain); err != nil {
+		t.Fatalf("failed to delete Captain: %v", err)
+	}
+
+	t.Logf("waiting for Captain %s|%s to be gone", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		err = c.Get(context.TODO(), key, captain)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		t.Fatalf("Captain %s|%s never deleted: %v", workspaceCluster, key, err)
+	}
+}
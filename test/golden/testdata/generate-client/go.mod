@@ -0,0 +1,15 @@
+
+module github.com/example/memcached-operator
+
+go 1.19
+
+require (
+	github.com/kcp-dev/apimachinery v0.0.0-20220922165458-607ac5e87531
+	github.com/kcp-dev/logicalcluster/v2 v2.0.0-alpha.3
+	k8s.io/api v0.24.3
+	k8s.io/apimachinery v0.24.3
+	k8s.io/client-go v0.24.3
+	sigs.k8s.io/controller-runtime v0.11.2
+)
+
+replace sigs.k8s.io/controller-runtime v0.11.2 => github.com/kcp-dev/controller-runtime v0.12.2-0.20221006162808-d4b60cec23b4
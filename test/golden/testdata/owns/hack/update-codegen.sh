@@ -0,0 +1,84 @@
+#!/usr/bin/env bash
+
+# Generates into client/ the clientsets, listers and informers of the API packages, e.g. api/v1alpha1, with types
+# marked with +genclient, see create api --generate-client:
+# - the single cluster clientset with client-gen, into client/clientset/versioned;
+# - the cluster aware clientset wrapping it, the listers and the informers with the code generators of kcp, at
+#   KCP_CODE_GENERATOR_VERSION, which generate them for LOGICALCLUSTER_VERSION and KCP_APIMACHINERY_VERSION.
+# The versions are set by make generate-client. The dependencies of go.mod are then updated for the generated code.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+CLIENT_GEN=${CLIENT_GEN:-client-gen}
+KCP_CODE_GENERATOR=${KCP_CODE_GENERATOR:-code-generator}
+BOILERPLATE=${BOILERPLATE:-hack/boilerplate.go.txt}
+: "${KCP_CODE_GENERATOR_VERSION:?must be set, see make generate-client}"
+: "${LOGICALCLUSTER_VERSION:?must be set, see make generate-client}"
+: "${KCP_APIMACHINERY_VERSION:?must be set, see make generate-client}"
+MODULE=$(go list -m)
+
+inputs=()
+for dir in $(grep -rlE --include='*_types.go' '^// ?\+genclient$' api apis 2>/dev/null | xargs -r -n1 dirname | sort -u); do
+  inputs+=("${dir}")
+done
+if [[ ${#inputs[@]} -eq 0 ]]; then
+  echo "No type is marked with +genclient, nothing to generate."
+  exit 0
+fi
+# The API packages are under api/ or, for multi-group projects, under apis/.
+root=${inputs[0]%%/*}
+paths=$(printf ',./%s' "${inputs[@]}")
+
+# check_version fails when go.mod requires another version of the module than the one the clients are generated for.
+check_version() {
+  local version
+  version=$(go list -m -f '{{ .Version }}' "$1")
+  if [[ "${version}" != "$2" ]]; then
+    echo "go.mod requires $1 ${version} but the code generators of kcp ${KCP_CODE_GENERATOR_VERSION} generate the clients for $2." >&2
+    echo "Require $1 $2 in go.mod, or set KCP_CODE_GENERATOR_VERSION, LOGICALCLUSTER_VERSION and KCP_APIMACHINERY_VERSION to the versions of a release of the code generators of kcp." >&2
+    exit 1
+  fi
+}
+check_version github.com/kcp-dev/logicalcluster/v2 "${LOGICALCLUSTER_VERSION}"
+check_version github.com/kcp-dev/apimachinery "${KCP_APIMACHINERY_VERSION}"
+
+rm -rf client
+
+# client-gen reads the API packages from <group>/<version> directories, where the group is the first label of the
+# name of the group, e.g. cache for cache.example.com, and takes api/<version> for the core group. The packages are
+# linked under client/apis for client-gen and the imports of the generated code are then rewritten to the packages.
+gvs=()
+for dir in "${inputs[@]}"; do
+  group=$(sed -nE 's|^// ?\+groupName=([^.]*).*|\1|p' "${dir}"/*.go | head -n 1)
+  version=${dir##*/}
+  mkdir -p "client/apis/${group}"
+  ln -s "../../../${dir}" "client/apis/${group}/${version}"
+  gvs+=("${group}/${version}")
+done
+
+"${CLIENT_GEN}" \
+  --go-header-file "${BOILERPLATE}" \
+  --clientset-name versioned \
+  --input-base "${MODULE}/client/apis" \
+  --input "$(IFS=,; echo "${gvs[*]}")" \
+  --output-package "${MODULE}/client/clientset" \
+  --output-base . \
+  --trim-path-prefix "${MODULE}"
+
+for i in "${!inputs[@]}"; do
+  find client/clientset -name '*.go' -exec \
+    sed -i.bak "s|\"${MODULE}/client/apis/${gvs[$i]}\"|\"${MODULE}/${inputs[$i]}\"|" {} +
+done
+find client/clientset -name '*.go.bak' -delete
+rm -rf client/apis
+
+"${KCP_CODE_GENERATOR}" \
+  "client:outputPackagePath=${MODULE}/client,apiPackagePath=${MODULE}/${root},singleClusterClientPackagePath=${MODULE}/client/clientset/versioned,headerFile=${BOILERPLATE}" \
+  "lister:apiPackagePath=${MODULE}/${root},headerFile=${BOILERPLATE}" \
+  "informer:outputPackagePath=${MODULE}/client,apiPackagePath=${MODULE}/${root},singleClusterClientPackagePath=${MODULE}/client/clientset/versioned,headerFile=${BOILERPLATE}" \
+  "paths={${paths#,}}" \
+  "output:dir=./client"
+
+go mod tidy
@@ -0,0 +1,238 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/claims"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusteraware"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clustermetrics"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/clusterratelimit"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/references"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/sharding"
+	"github.com/fgiloux/kcp-operator-sdk/pkg/tenants"
+
+	cachev1alpha1 "github.com/example/memcached-operator/api/v1alpha1"
+)
+
+// MemcachedFinalizer is the finalizer added to the Memcached objects, so that their deletion
+// waits for the cleanup of the controller.
+const MemcachedFinalizer = "cache.example.com/finalizer"
+
+// memcachedClaims are the resources reached in the workspaces of the tenants through the permission
+// claims of the APIExport, which the tenants must accept before the Memcached objects are reconciled.
+var memcachedClaims = []schema.GroupResource{
+	{Group: "", Resource: "secrets"},
+}
+
+// MemcachedReconciler reconciles a Memcached object
+type MemcachedReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Recorder records the events of the reconciled objects in their workspaces, where the tenants see them, e.g.
+	//	r.Recorder.Event(obj, corev1.EventTypeNormal, "Reconciled", "The spec is applied")
+	Recorder record.EventRecorder
+
+	// resolver reads the objects referenced by the Memcached objects, see SetupWithManager.
+	resolver *references.Resolver
+}
+
+//+kubebuilder:rbac:groups=cache.example.com,resources=memcacheds,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=cache.example.com,resources=memcacheds/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=cache.example.com,resources=memcacheds/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// TODO(user): Modify the Reconcile function to compare the state specified by
+// the Memcached object against the actual cluster state, and then
+// perform operations to make the cluster state reflect the state specified by
+// the user.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.2/pkg/reconcile
+func (r *MemcachedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	// The logger and the context are scoped to the logical cluster of the request, see SetupWithManager.
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Starting reconcile")
+
+	// The client scopes its requests to the logical cluster of ctx: always pass ctx, never a new context.
+	memcached := &cachev1alpha1.Memcached{}
+	if err := r.Get(ctx, req.NamespacedName, memcached); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The object was deleted after its cleanup, there is nothing left to do.
+			r.resolver.Forget(req)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !memcached.GetDeletionTimestamp().IsZero() {
+		if controllerutil.ContainsFinalizer(memcached, MemcachedFinalizer) {
+			logger.Info("Cleaning up before deletion")
+			if err := r.cleanup(ctx, memcached); err != nil {
+				return ctrl.Result{}, err
+			}
+			controllerutil.RemoveFinalizer(memcached, MemcachedFinalizer)
+			if err := r.Update(ctx, memcached); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(memcached, MemcachedFinalizer) {
+		controllerutil.AddFinalizer(memcached, MemcachedFinalizer)
+		if err := r.Update(ctx, memcached); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The Memcached waits until the tenant accepts the permission claims. Ready tells the tenant which claim
+	// to accept, and the claims are checked again after claims.RecheckInterval.
+	if err := claims.CheckCurrent(ctx, memcachedClaims...); err != nil {
+		if !claims.IsNotAccepted(err) {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Waiting for the permission claims to be accepted", "reason", err.Error())
+		if err := r.updateStatus(ctx, memcached, metav1.Condition{
+			Type:    cachev1alpha1.MemcachedReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  claims.NotAcceptedReason,
+			Message: err.Error(),
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: claims.RecheckInterval}, nil
+	}
+
+	// Progressing is set when a new generation of the spec is observed, until it is applied.
+	ready := meta.FindStatusCondition(memcached.Status.Conditions, cachev1alpha1.MemcachedReady)
+	if ready == nil || ready.ObservedGeneration != memcached.GetGeneration() {
+		if err := r.updateStatus(ctx, memcached, metav1.Condition{
+			Type:    cachev1alpha1.MemcachedProgressing,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Reconciling",
+			Message: "The spec is being applied",
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// TODO(user): your logic here. When it fails, set Ready to False with a reason telling the tenant what
+	// is wrong before returning the error, e.g.
+	//	_ = r.updateStatus(ctx, memcached, metav1.Condition{Type: cachev1alpha1.MemcachedReady,
+	//		Status: metav1.ConditionFalse, Reason: "Failed", Message: err.Error()})
+
+	// The objects referenced by the Memcached are read with r.resolver, in its workspace or in the workspace
+	// of the reference, relative to it. The resolver checks that the APIExport can reach them there and requeues the
+	// Memcached when they change. The reference is typically a references.Reference field of the spec, e.g.
+	//	secret := &corev1.Secret{}
+	//	if err := r.resolver.Get(ctx, req, references.Reference{Workspace: "..", Name: "shared"}, secret); err != nil {
+	//		return ctrl.Result{}, err
+	//	}
+
+	if err := r.updateStatus(ctx, memcached, metav1.Condition{
+		Type:    cachev1alpha1.MemcachedReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Reconciled",
+		Message: "The spec is applied",
+	}, metav1.Condition{
+		Type:    cachev1alpha1.MemcachedProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Reconciled",
+		Message: "The spec is applied",
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// updateStatus sets the conditions of memcached for its current generation and patches its status.
+// The status is not patched when it is unchanged, so that the reconciliation does not trigger itself.
+func (r *MemcachedReconciler) updateStatus(ctx context.Context, memcached *cachev1alpha1.Memcached, conditions ...metav1.Condition) error {
+	original := memcached.DeepCopy()
+	for _, condition := range conditions {
+		condition.ObservedGeneration = memcached.GetGeneration()
+		meta.SetStatusCondition(&memcached.Status.Conditions, condition)
+	}
+	if equality.Semantic.DeepEqual(original.Status, memcached.Status) {
+		return nil
+	}
+	return r.Status().Patch(ctx, memcached, client.MergeFrom(original))
+}
+
+// cleanup is called before the deletion of memcached, whose finalizer is removed when it succeeds.
+func (r *MemcachedReconciler) cleanup(ctx context.Context, memcached *cachev1alpha1.Memcached) error {
+	// TODO(user): release the resources of memcached outside of its workspace, e.g. in an external
+	// system. The objects of the workspace it owns are garbage collected.
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// The reconciler is wrapped by clusteraware.NewReconciler, which scopes the context and the logger
+// to the logical cluster of each request and recovers from panics, and by clustermetrics.NewReconciler,
+// which records the reconciliations per logical cluster.
+// The requests are admitted by a clusterratelimit.Limiter, which gives each logical cluster its own token bucket:
+// the requests of a logical cluster creating many objects are requeued and spread at the rate of its bucket.
+// The workqueue of the controller is replaced by the clusterratelimit.Queue of the limiter, which has a sub-queue
+// per logical cluster and hands out their requests in turn, so that the requests of the other logical clusters do
+// not wait behind the backlog of a logical cluster. Its depth per logical cluster is exposed by clustermetrics.
+// When sharding is enabled, the requests of the logical clusters owned by other replicas are skipped by
+// sharding.NewReconciler and the objects of the logical clusters acquired by the replica are requeued by
+// sharding.Source.
+// The requests of the logical clusters not selected by the tenants flags, or paused by their APIBinding, are
+// skipped by tenants.NewReconciler, including the requests queued before the logical cluster was paused and the
+// ones mapped from the changes of other objects. The events of their objects are dropped by tenants.Predicate and
+// the objects of the logical clusters that become selected are requeued by tenants.Source.
+// The changes of the referenced objects, possibly in other workspaces, are mapped to the objects referencing them
+// by the resolver.
+func (r *MemcachedReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.resolver = references.NewResolver(mgr.GetClient(), mgr.GetScheme(), mgr.GetRESTMapper())
+	limiter := clusterratelimit.NewLimiter()
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&cachev1alpha1.Memcached{}, builder.WithPredicates(tenants.Predicate())).
+		Watches(sharding.Source(mgr.GetCache(), &cachev1alpha1.MemcachedList{}), &handler.EnqueueRequestForObject{}).
+		Watches(tenants.Source(mgr.GetCache(), &cachev1alpha1.MemcachedList{}), &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, r.resolver.EnqueueReferrers()).
+		WithOptions(controller.Options{RateLimiter: limiter.RateLimiter()}).
+		Build(sharding.NewReconciler(tenants.NewReconciler(limiter.NewReconciler(clustermetrics.NewReconciler("memcached", clusteraware.NewReconciler(r))))))
+	if err != nil {
+		return err
+	}
+	return limiter.SetQueue(c, "memcached")
+}
@@ -0,0 +1,10 @@
+
+module github.com/example/memcached-operator
+
+go 1.19
+
+require (
+	sigs.k8s.io/controller-runtime v0.11.2
+)
+
+replace sigs.k8s.io/controller-runtime v0.11.2 => github.com/kcp-dev/controller-runtime v0.12.2-0.20221006162808-d4b60cec23b4
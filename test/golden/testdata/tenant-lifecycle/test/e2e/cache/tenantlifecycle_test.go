@@ -0,0 +1,74 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fgiloux/kcp-operator-sdk/pkg/lifecycle"
+
+	"github.com/example/memcached-operator/controllers"
+)
+
+// TestTenantLifecycle verifies that a tenant is onboarded by controllers.TenantLifecycle when it binds the APIExport
+// and that the objects created for it are deleted when it unbinds the APIExport.
+func TestTenantLifecycle(t *testing.T) {
+	t.Parallel()
+	workspaceCluster := parentWorkspace(t).Join(randomName())
+	c := createWorkspace(t, workspaceCluster)
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: controllers.TenantConfigMapName}
+	t.Logf("waiting for ConfigMap %s|%s to be provisioned", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		if err := c.Get(context.TODO(), key, cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return cm.Labels[lifecycle.ManagedByLabel] == controllers.TenantLifecycleOwner, nil
+	}); err != nil {
+		t.Fatalf("ConfigMap %s|%s never provisioned: %v", workspaceCluster, key, err)
+	}
+
+	apiName := "memcached-operator-memcached-operator.example.com"
+	t.Logf("deleting APIBinding %s|%s", workspaceCluster, apiName)
+	if err := c.Delete(context.TODO(), &apisv1alpha1.APIBinding{ObjectMeta: metav1.ObjectMeta{Name: apiName}}); err != nil {
+		t.Fatalf("failed to delete APIBinding: %v", err)
+	}
+
+	t.Logf("waiting for ConfigMap %s|%s to be deleted", workspaceCluster, key)
+	if err := wait.PollImmediate(100*time.Millisecond, wait.ForeverTestTimeout, func() (done bool, err error) {
+		err = c.Get(context.TODO(), key, cm)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		t.Fatalf("ConfigMap %s|%s never deleted: %v", workspaceCluster, key, err)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright YEAR.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+
+	configv1alpha1 "github.com/fgiloux/kcp-operator-sdk/pkg/config/v1alpha1"
+)
+
+//+kubebuilder:object:root=true
+
+// ProjectConfig is the component configuration of the manager. It extends the configuration of controller-runtime
+// with the kcp specific behaviours of the manager. It is validated when it is loaded, see Complete.
+type ProjectConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec returns the configurations for controllers
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// KCPConfig configures the kcp specific behaviours of the manager: mode, apiExportName, sharding and tenants,
+	// among others.
+	configv1alpha1.KCPConfig `json:",inline"`
+
+	// TODO(user): add the configuration of the controllers of the project.
+}
+
+// Validate returns the errors of the configuration.
+func (c *ProjectConfig) Validate() error {
+	errs := c.KCPConfig.Validate()
+	// TODO(user): validate the configuration of the controllers of the project.
+	return errs.ToAggregate()
+}
+
+// Complete validates the configuration and returns the one of controller-runtime. It is called when the
+// configuration is loaded.
+func (c *ProjectConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	if err := c.Validate(); err != nil {
+		return cfg.ControllerManagerConfigurationSpec{}, fmt.Errorf("invalid %s: %w", c.Kind, err)
+	}
+	return c.ControllerManagerConfigurationSpec, nil
+}
+
+func init() {
+	SchemeBuilder.Register(&ProjectConfig{})
+}